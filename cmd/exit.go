@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/viper"
+)
+
+// Process exit codes for each failure class
+const (
+	ExitFailure       = 1 // Any other failure
+	ExitValidation    = 2 // Invalid configuration or signing key; fix the configuration
+	ExitTokenEndpoint = 3 // PAIC rejected the request, e.g. invalid credentials
+	ExitNetwork       = 4 // PAIC could not be reached or answered 429 or 5xx; retrying may succeed
+)
+
+// Error formats for --error-format
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorCodes names each exit code in JSON error output
+var errorCodes = map[int]string{
+	ExitFailure:       "failure",
+	ExitValidation:    "validation",
+	ExitTokenEndpoint: "token_endpoint",
+	ExitNetwork:       "network",
+}
+
+// errorOutput is the error object printed with --error-format json
+type errorOutput struct {
+	Error   string   `json:"error"`
+	Code    string   `json:"code"`
+	Details []string `json:"details,omitempty"`
+}
+
+// ExitError is returned by Execute, carrying the exit code for the failure class
+type ExitError struct {
+	Err  error
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// withExitCode marks err with the exit code for its failure class
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Err: err, Code: code}
+}
+
+// exitCode classifies err using the typed errors of the token package.
+// Transient failures are checked first, as they are also token endpoint
+// failures when they happen during a token request. They are classified as
+// requests are retried, so certificate verification failures are not
+// transient while 429 and 5xx responses are.
+func exitCode(err error) int {
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case token.Transient(err):
+		return ExitNetwork
+	case errors.Is(err, token.ErrValidation), errors.Is(err, token.ErrKeyParse):
+		return ExitValidation
+	case errors.Is(err, token.ErrTokenEndpoint):
+		return ExitTokenEndpoint
+	default:
+		return ExitFailure
+	}
+}
+
+// silenceForJSONErrors stops cobra printing the error and usage text with
+// --error-format json, so that Execute prints a JSON error object instead
+func silenceForJSONErrors() {
+	if viper.GetString("error-format") == errorFormatJSON {
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+	}
+}
+
+// printJSONError writes the command failure to w as a JSON error object
+func printJSONError(w io.Writer, err error, code int) {
+	data, jsonErr := json.Marshal(errorOutput{
+		Error:   err.Error(),
+		Code:    errorCodes[code],
+		Details: token.ErrorDetails(err),
+	})
+	if jsonErr != nil {
+		fmt.Fprintln(w, "Error:", err)
+		return
+	}
+	fmt.Fprintln(w, string(data))
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/aaronwang/pctl/pkg/token"
+)
+
+func TestExitCode(t *testing.T) {
+	// Errors as the token package returns them from a token request
+	requestErr := func(err error) error {
+		return fmt.Errorf("%w: %w", token.ErrTokenEndpoint, &url.Error{Op: "Post", URL: "https://paic.example.com/am/oauth2/access_token", Err: err})
+	}
+	endpointErr := func(status int) error {
+		return fmt.Errorf("token generation failed: %w", &token.TokenEndpointError{StatusCode: status, Body: "{}"})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "explicit code", err: withExitCode(ExitValidation, errors.New("bad flag")), want: ExitValidation},
+		{name: "connection refused", err: requestErr(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), want: ExitNetwork},
+		{name: "timeout", err: requestErr(context.DeadlineExceeded), want: ExitNetwork},
+		{name: "unknown certificate authority", err: requestErr(x509.UnknownAuthorityError{}), want: ExitTokenEndpoint},
+		{name: "certificate hostname mismatch", err: requestErr(x509.HostnameError{Host: "paic.example.com", Certificate: &x509.Certificate{}}), want: ExitTokenEndpoint},
+		{name: "too many requests", err: endpointErr(429), want: ExitNetwork},
+		{name: "server error", err: endpointErr(503), want: ExitNetwork},
+		{name: "invalid credentials", err: endpointErr(401), want: ExitTokenEndpoint},
+		{name: "clock skew", err: &token.ClockSkewError{Err: &token.TokenEndpointError{StatusCode: 400}}, want: ExitTokenEndpoint},
+		{name: "validation", err: fmt.Errorf("%w: missing platform", token.ErrValidation), want: ExitValidation},
+		{name: "key parse", err: fmt.Errorf("%w: bad key", token.ErrKeyParse), want: ExitValidation},
+		{name: "other", err: errors.New("failed to write output file"), want: ExitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("Expected exit code %d, got %d for %v", tt.want, got, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// isTerminal reports whether the file is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptLine writes the prompt to stderr and reads a line from stdin
+func promptLine(prompt string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return line, nil
+}
+
+// promptPassword writes the prompt to stderr and reads a line from the
+// terminal on stdin without echoing it
+func promptPassword(prompt string) (string, error) {
+	if err := setEcho(os.Stdin, false); err != nil {
+		return "", fmt.Errorf("failed to disable terminal echo: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	defer func() {
+		setEcho(os.Stdin, true)
+		fmt.Fprintln(os.Stderr)
+	}()
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPassword reads a password piped to stdin from r, dropping the trailing newline
+func readPassword(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("no password on stdin")
+	}
+	return password, nil
+}
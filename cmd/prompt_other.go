@@ -0,0 +1,19 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setEcho turns echoing of typed characters on the terminal on or off with stty
+func setEcho(f *os.File, on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = f
+	return cmd.Run()
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aaronwang/pctl/pkg/token"
+)
+
+func TestReadPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "trailing newline", input: "secret\n", want: "secret"},
+		{name: "trailing CRLF", input: "secret\r\n", want: "secret"},
+		{name: "no newline", input: "secret", want: "secret"},
+		{name: "spaces kept", input: " pass word \n", want: " pass word "},
+		{name: "empty", input: "", wantErr: "no password on stdin"},
+		{name: "only newline", input: "\n", wantErr: "no password on stdin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPassword(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected password %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyPasswordStdin(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType token.TokenType
+		input     string
+		want      string
+		wantErr   string
+	}{
+		{name: "replaces configured password", tokenType: token.TokenTypeUser, input: "from-stdin\n", want: "from-stdin"},
+		{name: "empty stdin", tokenType: token.TokenTypeUser, input: "", wantErr: "no password on stdin"},
+		{name: "not a user token", tokenType: token.TokenTypeCustom, input: "from-stdin\n", wantErr: "only supported for user tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &token.TokenConfig{Type: tt.tokenType, Username: "testuser", Password: "from-config"}
+			err := applyPasswordStdin(config, strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if config.Password != "from-config" {
+					t.Errorf("Expected the configured password to be kept on error, got %q", config.Password)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.Password != tt.want {
+				t.Errorf("Expected password %q, got %q", tt.want, config.Password)
+			}
+		})
+	}
+}
@@ -2,15 +2,22 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/aaronwang/pctl/internal/logger"
+	"github.com/aaronwang/pctl/internal/version"
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile   string
+	verbose   bool
+	quiet     bool
+	trace     bool
+	logFormat string
+	errFormat string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -20,25 +27,78 @@ var rootCmd = &cobra.Command{
 	Long: `PCTL (PAIC Control) is a comprehensive CLI platform for managing, testing, 
 and automating Ping Identity Advanced Identity Cloud (PAIC) operations.
 
-Built with Go for performance, reliability, and easy deployment.`,
-	Version: "0.1.0",
+Built with Go for performance, reliability, and easy deployment.
+
+Exit codes:
+  0  success
+  1  any other failure
+  2  invalid configuration or signing key
+  3  PAIC rejected the request, e.g. invalid credentials
+  4  PAIC could not be reached or answered 429 or 5xx; retrying may succeed`,
+	Version: version.Version,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// A failure is returned as an *ExitError carrying the exit code for its class.
 func Execute() error {
-	return rootCmd.Execute()
+	if err := rootCmd.Execute(); err != nil {
+		code := exitCode(err)
+		// Cobra prints text errors itself unless silenced for JSON errors
+		if rootCmd.SilenceErrors {
+			printJSONError(os.Stderr, err, code)
+		}
+		return &ExitError{Err: err, Code: code}
+	}
+	return nil
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		silenceForJSONErrors()
+		return err
+	})
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.pctl.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "print only the command result on stdout and suppress diagnostics")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.PersistentFlags().BoolVar(&trace, "trace", false, "print DNS, connect, TLS and first byte timings of requests to PAIC on stderr")
+	rootCmd.MarkFlagsMutuallyExclusive("trace", "quiet")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logger.FormatText, "format of verbose diagnostics on stderr (text, json)")
+	rootCmd.PersistentFlags().StringVar(&errFormat, "error-format", errorFormatText, "format of the error printed on stderr when a command fails (text, json)")
 
 	// Bind flags to viper
 	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+	viper.BindPFlag("trace", rootCmd.PersistentFlags().Lookup("trace"))
+	viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
+	viper.BindPFlag("error-format", rootCmd.PersistentFlags().Lookup("error-format"))
+}
+
+// newLogger creates the logger for diagnostics on stderr. Debug records are
+// only written in verbose mode so stdout stays reserved for command output.
+func newLogger() (*slog.Logger, error) {
+	return newLevelLogger(slog.LevelWarn)
+}
+
+// newLevelLogger creates the logger for diagnostics on stderr at the given
+// level, or at debug level in verbose mode. Request timings are logged at
+// info level, so tracing lowers the level to info. Diagnostics are discarded
+// in quiet mode.
+func newLevelLogger(level slog.Level) (*slog.Logger, error) {
+	if viper.GetBool("quiet") {
+		return logger.Discard(), nil
+	}
+	if viper.GetBool("trace") && level > slog.LevelInfo {
+		level = slog.LevelInfo
+	}
+	if viper.GetBool("verbose") {
+		level = slog.LevelDebug
+	}
+	return logger.New(os.Stderr, viper.GetString("log-format"), level)
 }
 
 // initConfig reads in config file and ENV variables.
@@ -68,4 +128,9 @@ func initConfig() {
 			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 		}
 	}
-}
\ No newline at end of file
+
+	if format := viper.GetString("error-format"); format != errorFormatText && format != errorFormatJSON {
+		cobra.CheckErr(fmt.Errorf("invalid error format %q: must be %s or %s", format, errorFormatText, errorFormatJSON))
+	}
+	silenceForJSONErrors()
+}
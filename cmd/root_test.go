@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newHome creates a temporary home directory whose ~/.pctl/config.yaml holds
+// defaults, and returns its path
+func newHome(t *testing.T, defaults string) string {
+	t.Helper()
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".pctl"), 0700); err != nil {
+		t.Fatalf("Failed to create pctl directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".pctl", "config.yaml"), []byte(defaults), 0600); err != nil {
+		t.Fatalf("Failed to write defaults: %v", err)
+	}
+	return home
+}
+
+// executeCommand runs pctl with args in the home directory, returning what
+// was written to stdout and stderr. Flags are reset first, as the commands
+// are package globals.
+func executeCommand(t *testing.T, home string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	t.Setenv("HOME", home)
+	resetFlags(rootCmd)
+
+	stdoutFile := captureFile(t, &os.Stdout)
+	stderrFile := captureFile(t, &os.Stderr)
+	rootCmd.SetArgs(args)
+	err = Execute()
+
+	return readCapture(t, stdoutFile), readCapture(t, stderrFile), err
+}
+
+// resetFlags restores every flag of cmd and its subcommands to its default
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// captureFile replaces *target, such as os.Stdout, with a temporary file
+// until the test ends
+func captureFile(t *testing.T, target **os.File) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "output")
+	if err != nil {
+		t.Fatalf("Failed to create capture file: %v", err)
+	}
+	original := *target
+	*target = f
+	t.Cleanup(func() {
+		*target = original
+		f.Close()
+	})
+	return f
+}
+
+// readCapture returns everything written to a capture file
+func readCapture(t *testing.T, f *os.File) string {
+	t.Helper()
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("Failed to read capture file: %v", err)
+	}
+	return string(data)
+}
+
+// newTokenServer returns a PAIC stub issuing test-access-token for every
+// token request and accepting revocations
+func newTokenServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/token/revoke") {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeTokenConfig writes a client credentials token configuration for the
+// server, followed by extra YAML lines, and returns its path
+func writeTokenConfig(t *testing.T, server *httptest.Server, extra ...string) string {
+	t.Helper()
+	lines := append([]string{
+		"type: custom",
+		"baseUrl: " + server.URL,
+		"allow_insecure_url: true",
+		"clientId: test-client",
+		"clientSecret: test-secret",
+	}, extra...)
+	path := filepath.Join(t.TempDir(), "token.yaml")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token config: %v", err)
+	}
+	return path
+}
+
+func TestQuiet(t *testing.T) {
+	server := newTokenServer(t)
+	// Conflicting lifetimes are warned about on stderr
+	config := writeTokenConfig(t, server, "exp_seconds: 900", "expiresIn: 30m")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "token warns on stderr",
+			args:       []string{"token", "-c", config, "-o", "raw", "--no-cache"},
+			wantStdout: "test-access-token\n",
+			wantStderr: "exp_seconds and expiresIn conflict",
+		},
+		{
+			name:       "quiet token",
+			args:       []string{"token", "-c", config, "-o", "raw", "--no-cache", "--quiet"},
+			wantStdout: "test-access-token\n",
+		},
+		{
+			name:       "revoke",
+			args:       []string{"token", "revoke", "-c", config, "some-token"},
+			wantStdout: "Token revoked successfully\n",
+		},
+		{
+			name: "quiet revoke",
+			args: []string{"token", "revoke", "-c", config, "some-token", "-q"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := executeCommand(t, newHome(t, ""), tt.args...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v\nstderr: %s", err, stderr)
+			}
+			if stdout != tt.wantStdout {
+				t.Errorf("Expected stdout %q, got %q", tt.wantStdout, stdout)
+			}
+			if tt.wantStderr == "" && stderr != "" {
+				t.Errorf("Expected nothing on stderr, got %q", stderr)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.wantStderr, stderr)
+			}
+		})
+	}
+}
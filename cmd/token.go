@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -9,9 +16,52 @@ import (
 )
 
 var (
-	tokenConfigFile string
-	tokenOutput     string
-	tokenType       string
+	tokenConfigFiles []string
+	tokenOutput      string
+	tokenType        string
+	tokenNoCache     bool
+	tokenCacheBuf    time.Duration
+	tokenExportPfx   string
+	tokenOutFile     string
+	tokenTimeout     time.Duration
+	tokenRetries     int
+	tokenRetryWait   time.Duration
+	tokenInsecure    bool
+	tokenOTP         string
+	tokenScopes      []string
+	tokenPlatform    string
+	tokenBaseURL     string
+	tokenSAID        string
+	tokenUserAgent   string
+	tokenRealm       string
+	tokenFields      []string
+	tokenFingerprint bool
+	tokenJWTLifetime time.Duration
+	tokenTimeFmt     string
+	tokenReqScope    bool
+	tokenHeaders     []string
+	tokenClockSkew   time.Duration
+	tokenDecode      bool
+	tokenResources   []string
+	tokenNoBrowser   bool
+	tokenSubject     string
+	tokenActor       string
+	tokenAssertOnly  bool
+	tokenIncludeJTI  bool
+	tokenClaims      []string
+	tokenCall        string
+	tokenCallData    string
+	tokenUsername    string
+	tokenPassStdin   bool
+	tokenMinValid    time.Duration
+	tokenStrictKey   bool
+	tokenAudience    string
+	tokenStrictAud   bool
+	tokenTemplate    string
+	tokenEndpoint    string
+	tokenStrictExp   bool
+	tokenSigningAlg  string
+	tokenShowToken   bool
 )
 
 // tokenCmd represents the token command
@@ -25,16 +75,134 @@ var tokenCmd = &cobra.Command{
 
 Examples:
   pctl token -c config.yaml
+  pctl token -c config.json
+  pctl token -c base.yaml -c account.yaml
   pctl token --type service-account --output json
-  pctl token --config token-config.yaml --verbose`,
-	RunE: runToken,
+  TOKEN=$(pctl token -c config.yaml -o raw --quiet)
+  eval "$(pctl token -c config.yaml -o export)"
+  pctl token --config token-config.yaml --verbose
+  pctl token -c config.yaml --no-cache
+  pctl token -c config.yaml --min-validity 10m
+  pctl token -c config.yaml --platform https://openam-staging.forgeblocks.com
+  pctl token -c config.yaml --scope fr:am:* --scope fr:idm:*
+  pctl token -c user.yaml --type user --otp "$OTP"
+  pctl token -c user.yaml --type user --username alice
+  printf '%s' "$PASSWORD" | pctl token -c user.yaml --type user --password-stdin
+  pctl token -c app.yaml --type authorization-code --no-browser
+  pctl token -c app.yaml --type device-code
+  pctl token -c exchange.yaml --type token-exchange --subject-token "$USER_TOKEN" --actor-token "$SERVICE_TOKEN"
+  pctl token -c config.yaml -o json --out-file token.json
+  pctl token -c config.yaml -o json,raw --out-file 'token.{ext}'
+  pctl token -c config.yaml -o json --fields access_token,expires_at
+  pctl token -c config.yaml --format-template '{{.AccessToken}} expires {{.ExpiresAt}}'
+  pctl token -c config.yaml --decode-after-generate
+  pctl token -c config.yaml --show-token
+  pctl token validate -c config.yaml --strict-key
+  ASSERTION=$(pctl token -c config.yaml --assertion-only -o raw)
+  pctl token -c config.yaml --assertion-claim department=engineering --assertion-claim level=3
+  pctl token -c config.yaml --call 'GET https://openam-example.forgeblocks.com/openidm/info/login'
+  pctl token -c config.yaml --call 'POST https://api.example.com/users' --data @user.json`,
+	PersistentPreRunE: prepareTokenCommand,
+	RunE:              runToken,
+}
+
+// prepareTokenCommand applies the defaults from the pctl config file and
+// validates the output flags before a token command runs
+func prepareTokenCommand(cmd *cobra.Command, args []string) error {
+	if err := applyTokenDefaults(cmd); err != nil {
+		return err
+	}
+	return validateTokenOutputFlags(cmd, args)
+}
+
+// applyTokenDefaults uses token.config and token.output from
+// ~/.pctl/config.yaml when -c and -o are not given. Relative token config
+// paths resolve against ~/.pctl.
+func applyTokenDefaults(cmd *cobra.Command) error {
+	defaults, err := loadTokenDefaults()
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("config") {
+		tokenConfigFiles = nil
+		for _, path := range defaults.GetStringSlice("token.config") {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(defaults.ConfigFileUsed()), path)
+			}
+			tokenConfigFiles = append(tokenConfigFiles, path)
+		}
+	}
+	if len(tokenConfigFiles) == 0 {
+		return fmt.Errorf(`required flag(s) "config" not set; pass -c or set token.config in ~/.pctl/config.yaml`)
+	}
+
+	applyTokenOutputDefault(cmd, defaults)
+	return nil
+}
+
+// applyTokenOutputDefault uses token.output from the defaults when -o is not given
+func applyTokenOutputDefault(cmd *cobra.Command, defaults *viper.Viper) {
+	if !cmd.Flags().Changed("output") {
+		if output := defaults.GetString("token.output"); output != "" {
+			tokenOutput = output
+		}
+	}
+}
+
+// loadTokenDefaults reads ~/.pctl/config.yaml, when it exists, into its own
+// viper instance. Only token.config and token.output are taken from it, so
+// the file cannot change any other token setting.
+func loadTokenDefaults() (*viper.Viper, error) {
+	defaults := viper.New()
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaults, nil
+	}
+
+	path := filepath.Join(home, ".pctl", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return defaults, nil
+	}
+	defaults.SetConfigFile(path)
+	if err := defaults.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return defaults, nil
+}
+
+// validateTokenOutputFlags rejects unknown output and time formats before any request is made.
+// Several comma-separated output formats are only accepted when generating a token
+// with an --out-file template containing {ext}, as stdout takes a single format.
+func validateTokenOutputFlags(cmd *cobra.Command, args []string) error {
+	formats, err := token.ParseOutputFormats(tokenOutput)
+	if err != nil {
+		return err
+	}
+	if len(formats) > 1 {
+		if viper.GetString("token.format-template") != "" {
+			return fmt.Errorf("--format-template cannot be combined with multiple output formats")
+		}
+		if cmd.Parent() != rootCmd {
+			return fmt.Errorf("multiple output formats are only supported when generating a token")
+		}
+		if !strings.Contains(viper.GetString("token.out-file"), token.OutputExtPlaceholder) {
+			return fmt.Errorf("multiple output formats require an --out-file template containing %s", token.OutputExtPlaceholder)
+		}
+	}
+	// Report template syntax errors before requesting a token
+	if text := viper.GetString("token.format-template"); text != "" {
+		if _, err := token.ParseFormatTemplate(text); err != nil {
+			return err
+		}
+	}
+	return token.ValidateTimeFormat(token.TimeFormat(tokenTimeFmt))
 }
 
 func runToken(cmd *cobra.Command, args []string) error {
 	// Load token configuration
-	tokenConfig, err := token.LoadConfig(tokenConfigFile)
+	tokenConfig, err := loadTokenConfig(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to load token config: %w", err)
+		return err
 	}
 
 	// Override token type from CLI flag if different  
@@ -44,25 +212,208 @@ func runToken(cmd *cobra.Command, args []string) error {
 			tokenConfig.Type = "user"
 		case "custom":
 			tokenConfig.Type = "custom" 
+		case "authorization-code":
+			tokenConfig.Type = "authorization-code"
+		case "token-exchange":
+			tokenConfig.Type = "token-exchange"
+		case "device-code":
+			tokenConfig.Type = "device-code"
+		}
+	}
+
+	// Add the access token fingerprint to the result metadata when requested
+	if viper.GetBool("token.fingerprint") {
+		tokenConfig.Fingerprint = true
+	}
+
+	// Override the JWT assertion lifetime from CLI flag if set
+	if cmd.Flags().Changed("jwt-lifetime") {
+		tokenConfig.AssertionExpSeconds = int(math.Ceil(viper.GetDuration("token.jwt-lifetime").Seconds()))
+	}
+
+	// Override the assertion audience, or token exchange target audience, from CLI flag if set
+	if cmd.Flags().Changed("audience") {
+		tokenConfig.Audience = viper.GetString("token.audience")
+	}
+
+	// Reject a service account audience that does not match the token endpoint when requested
+	if viper.GetBool("token.strict-audience") {
+		tokenConfig.StrictAudience = true
+	}
+
+	// Return the signed service account assertion instead of exchanging it when requested
+	if viper.GetBool("token.assertion-only") {
+		tokenConfig.AssertionOnly = true
+	}
+
+	// Merge assertion claims from CLI flags over configured customClaims
+	for _, claim := range tokenClaims {
+		name, value, err := token.ParseClaim(claim)
+		if err != nil {
+			return err
+		}
+		if tokenConfig.CustomClaims == nil {
+			tokenConfig.CustomClaims = make(map[string]interface{})
+		}
+		tokenConfig.CustomClaims[name] = value
+	}
+
+	// Include or omit the assertion jti claim from CLI flag if set
+	if cmd.Flags().Changed("include-jti") {
+		includeJTI := viper.GetBool("token.include-jti")
+		tokenConfig.IncludeJTI = &includeJTI
+	}
+
+	// Override the assertion clock skew offset from CLI flag if set
+	if cmd.Flags().Changed("clock-skew") {
+		tokenConfig.ClockSkewSeconds = int(viper.GetDuration("token.clock-skew").Round(time.Second).Seconds())
+	}
+
+	// Override the token exchange subject and actor tokens from CLI flags if set
+	if cmd.Flags().Changed("subject-token") {
+		tokenConfig.SubjectToken = viper.GetString("token.subject-token")
+	}
+	if cmd.Flags().Changed("actor-token") {
+		tokenConfig.ActorToken = viper.GetString("token.actor-token")
+	}
+
+	// Override the user from CLI flag if set
+	if cmd.Flags().Changed("username") {
+		tokenConfig.Username = viper.GetString("token.username")
+	}
+
+	// Read the user password from stdin when requested, or prompt for a
+	// password missing from the configuration when interactive
+	if viper.GetBool("token.password-stdin") {
+		if err := applyPasswordStdin(tokenConfig, os.Stdin); err != nil {
+			return err
+		}
+	} else if tokenConfig.Type == "user" && tokenConfig.Password == "" && tokenConfig.Username != "" && isTerminal(os.Stdin) {
+		password, err := promptPassword(fmt.Sprintf("Password for %s", tokenConfig.Username))
+		if err != nil {
+			return fmt.Errorf("failed to read password (set password or pass --password-stdin): %w", err)
 		}
+		tokenConfig.Password = password
+	}
+
+	// Reject unknown output fields before requesting a token
+	fields := viper.GetStringSlice("token.fields")
+	if err := token.ValidateFields(fields); err != nil {
+		return err
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	// Output formats were validated before the command ran
+	formats, err := token.ParseOutputFormats(tokenOutput)
+	if err != nil {
+		return err
 	}
 
 	// Create token client options
 	options := token.GeneratorOptions{
 		Config:       *tokenConfig,
-		OutputFormat: token.OutputFormat(tokenOutput),
+		OutputFormat: formats[0],
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
 		Verbose:      viper.GetBool("verbose"),
+		ExportPrefix: viper.GetString("token.export-prefix"),
+		Logger:       log,
+		Fields:       fields,
+		Decode:       viper.GetBool("token.decode-after-generate"),
+		ShowToken:    viper.GetBool("token.show-token"),
+		MinValidity:  viper.GetDuration("token.min-validity"),
+
+		FormatTemplate: viper.GetString("token.format-template"),
+	}
+
+	// Print the authorization-code login URL instead of opening a browser when requested
+	if viper.GetBool("token.no-browser") {
+		options.Config.NoBrowser = true
+	}
+	options.OpenBrowser = openBrowser
+
+	// Use the supplied one-time password, or prompt for one when interactive
+	if otp := viper.GetString("token.otp"); otp != "" {
+		options.Config.OTP = otp
+	} else if isTerminal(os.Stdin) {
+		options.OTPPrompt = promptLine
+	}
+
+	// Reuse cached tokens unless disabled
+	if !viper.GetBool("token.no-cache") {
+		cache, err := token.NewFileCache("", viper.GetDuration("token.cache-buffer"))
+		if err != nil {
+			return err
+		}
+		options.Cache = cache
 	}
 
-	// Create token client and generate token
+	// Create token client
 	client := token.NewClient(options)
+
+	// Send an API request with the token instead of printing it when requested
+	if tokenCall != "" {
+		return runTokenCall(client)
+	}
+
+	// Generate token
 	result, err := client.Generate()
 	if err != nil {
 		return fmt.Errorf("token generation failed: %w", err)
 	}
 
-	// Format and output the result
-	output, err := client.FormatOutput(result)
+	// Format and output the result once per format
+	outFile := viper.GetString("token.out-file")
+	for _, format := range formats {
+		formatOptions := options
+		formatOptions.OutputFormat = format
+		output, err := token.NewClient(formatOptions).FormatOutput(result)
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+
+		// Write to file atomically when requested, otherwise stdout
+		if outFile == "" {
+			fmt.Print(output)
+			continue
+		}
+		if err := token.WriteFileAtomic(token.OutputPath(outFile, format), []byte(output), 0600); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+	return nil
+}
+
+// runTokenCall generates a token, sends the --call API request with it and
+// prints the response status and body
+func runTokenCall(client *token.Client) error {
+	var body []byte
+	if tokenCallData != "" {
+		body = []byte(tokenCallData)
+		// As with curl, @file reads the body from a file
+		if path, ok := strings.CutPrefix(tokenCallData, "@"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read request body: %w", err)
+			}
+			body = data
+		}
+	}
+
+	method, apiURL, err := token.ParseCall(tokenCall, body != nil)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	result, err := client.Call(context.Background(), method, apiURL, body)
+	if err != nil {
+		return err
+	}
+
+	output, err := client.FormatCallResult(result)
 	if err != nil {
 		return fmt.Errorf("failed to format output: %w", err)
 	}
@@ -71,19 +422,246 @@ func runToken(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// applyPasswordStdin replaces the configured password of a user token
+// configuration with the password read from stdin
+func applyPasswordStdin(config *token.TokenConfig, stdin io.Reader) error {
+	if config.Type != token.TokenTypeUser {
+		return fmt.Errorf("--password-stdin is only supported for user tokens")
+	}
+	password, err := readPassword(stdin)
+	if err != nil {
+		return err
+	}
+	config.Password = password
+	return nil
+}
+
+// loadTokenConfig loads the token configuration and applies the CLI overrides
+// shared by the token command and its subcommands
+func loadTokenConfig(cmd *cobra.Command) (*token.TokenConfig, error) {
+	return loadTokenConfigFiles(cmd, tokenConfigFiles)
+}
+
+// loadTokenConfigFiles loads and merges the configuration files and applies the CLI overrides
+func loadTokenConfigFiles(cmd *cobra.Command, configFiles []string) (*token.TokenConfig, error) {
+	tokenConfig, err := token.LoadConfig(configFiles...)
+	if err != nil {
+		return nil, withExitCode(ExitValidation, fmt.Errorf("failed to load token config: %w", err))
+	}
+
+	if viper.GetBool("verbose") {
+		log, err := newLogger()
+		if err != nil {
+			return nil, err
+		}
+		for _, warning := range tokenConfig.Warnings {
+			log.Warn(warning)
+		}
+	}
+
+	// Override the platform from CLI flags if set. As in LoadConfig, platform
+	// also sets baseUrl, and an explicit base URL takes precedence.
+	if cmd.Flags().Changed("platform") {
+		tokenConfig.Platform = viper.GetString("token.platform")
+		tokenConfig.BaseURL = tokenConfig.Platform
+	}
+	if cmd.Flags().Changed("base-url") {
+		tokenConfig.BaseURL = viper.GetString("token.base-url")
+	}
+
+	// Override the service account from CLI flag if set, before validation
+	if saID := viper.GetString("token.service-account-id"); saID != "" {
+		tokenConfig.ServiceAccountID = saID
+	}
+
+	// Override HTTP timeout from CLI flag if set
+	if cmd.Flags().Changed("timeout") {
+		tokenConfig.TimeoutSeconds = int(math.Ceil(viper.GetDuration("token.timeout").Seconds()))
+	}
+
+	// Override retry behavior from CLI flags if set
+	if cmd.Flags().Changed("retries") {
+		tokenConfig.Retries = viper.GetInt("token.retries")
+		if tokenConfig.Retries == 0 {
+			tokenConfig.Retries = -1 // Zero retries disables retrying
+		}
+	}
+	if cmd.Flags().Changed("retry-max-wait") {
+		tokenConfig.RetryMaxWaitSeconds = int(math.Ceil(viper.GetDuration("token.retry-max-wait").Seconds()))
+	}
+
+	// Override scopes from CLI flags, each of which may hold several space-delimited scopes
+	if cmd.Flags().Changed("scope") {
+		tokenConfig.Scopes = nil
+		for _, scope := range tokenScopes {
+			tokenConfig.Scopes = append(tokenConfig.Scopes, strings.Fields(scope)...)
+		}
+		tokenConfig.Scope = strings.Join(tokenConfig.Scopes, " ")
+	}
+
+	// Override resource indicators from CLI flags if set
+	if cmd.Flags().Changed("resource") {
+		tokenConfig.Resource = tokenResources
+	}
+
+	// Reject user and custom token requests without a scope when requested
+	if viper.GetBool("token.require-scope") {
+		tokenConfig.RequireScope = true
+	}
+
+	// Add request headers from CLI flags, overriding configured headers of the same name
+	if cmd.Flags().Changed("header") {
+		for _, header := range tokenHeaders {
+			name, value, ok := strings.Cut(header, "=")
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("invalid header %q: expected key=value", header)
+			}
+			if tokenConfig.Headers == nil {
+				tokenConfig.Headers = make(map[string]string)
+			}
+			tokenConfig.Headers[strings.TrimSpace(name)] = value
+		}
+	}
+
+	// Trace request timings when requested
+	if viper.GetBool("trace") {
+		tokenConfig.Trace = true
+	}
+
+	// Override the User-Agent from CLI flag if set
+	if cmd.Flags().Changed("user-agent") {
+		tokenConfig.UserAgent = viper.GetString("token.user-agent")
+	}
+
+	// Override the OAuth 2.0 realm from CLI flag if set
+	if cmd.Flags().Changed("realm") {
+		tokenConfig.Realm = viper.GetString("token.realm")
+	}
+
+	// Override the token endpoint URL from CLI flag if set
+	if cmd.Flags().Changed("token-endpoint") {
+		tokenConfig.TokenEndpoint = viper.GetString("token.token-endpoint")
+	}
+
+	// Check the RSA JWK components for consistency when requested
+	if viper.GetBool("token.strict-key") {
+		tokenConfig.StrictKey = true
+	}
+
+	// Override the assertion signing algorithm from CLI flag if set
+	if cmd.Flags().Changed("signing-alg") {
+		tokenConfig.SigningAlg = viper.GetString("token.signing-alg")
+	}
+
+	// Reject conflicting exp_seconds and expiresIn when requested
+	if viper.GetBool("token.strict-expiry") {
+		tokenConfig.StrictExpiry = true
+	}
+
+	// Permit a plain http platform URL, e.g. for local test servers
+	if viper.GetBool("token.allow-insecure-url") {
+		tokenConfig.AllowInsecureURL = true
+	}
+
+	return tokenConfig, nil
+}
+
 func init() {
 	rootCmd.AddCommand(tokenCmd)
 
-	// Token-specific flags
-	tokenCmd.Flags().StringVarP(&tokenConfigFile, "config", "c", "", "token configuration file (required)")
-	tokenCmd.Flags().StringVarP(&tokenOutput, "output", "o", "text", "output format (text, json, yaml)")
-	tokenCmd.Flags().StringVarP(&tokenType, "type", "t", "service-account", "token type (service-account, user, custom)")
+	// Flags shared with token subcommands
+	tokenCmd.PersistentFlags().StringArrayVarP(&tokenConfigFiles, "config", "c", nil, "token configuration file (required unless token.config is set in ~/.pctl/config.yaml; repeat to merge, later files override earlier ones)")
+	tokenCmd.PersistentFlags().StringVarP(&tokenOutput, "output", "o", "text", "output format (text, json, yaml, raw, export, jsonl); a comma list writes each to --out-file with {ext}")
+	tokenCmd.PersistentFlags().BoolVar(&tokenShowToken, "show-token", false, "print tokens in full in text output instead of masking them to their first and last few characters")
+	tokenCmd.PersistentFlags().StringVar(&tokenTimeFmt, "time-format", string(token.TimeFormatHuman), "timestamp format in text output (human, rfc3339, unix)")
+	tokenCmd.PersistentFlags().StringVar(&tokenPlatform, "platform", "", "PAIC platform URL, overriding the configuration")
+	tokenCmd.PersistentFlags().StringVar(&tokenBaseURL, "base-url", "", "PAIC base URL, overriding the configuration")
+	tokenCmd.PersistentFlags().StringVar(&tokenRealm, "realm", "", "OAuth 2.0 realm path such as root/alpha, overriding the configuration")
+	tokenCmd.PersistentFlags().StringVar(&tokenEndpoint, "token-endpoint", "", "token endpoint URL used verbatim instead of the one derived from the platform URL, overriding the configuration")
+	tokenCmd.PersistentFlags().DurationVar(&tokenTimeout, "timeout", token.DefaultHTTPTimeout, "HTTP timeout for requests to PAIC")
+	tokenCmd.PersistentFlags().IntVar(&tokenRetries, "retries", token.DefaultRetries, "retries for transient PAIC request failures (0 disables)")
+	tokenCmd.PersistentFlags().DurationVar(&tokenRetryWait, "retry-max-wait", token.DefaultRetryMaxWait, "maximum wait between retries")
+	tokenCmd.PersistentFlags().StringArrayVar(&tokenScopes, "scope", nil, "scope to request, replacing configured scopes (repeatable)")
+	tokenCmd.PersistentFlags().StringArrayVar(&tokenResources, "resource", nil, "RFC 8707 resource indicator to request, replacing configured resources (repeatable)")
+	tokenCmd.PersistentFlags().BoolVar(&tokenReqScope, "require-scope", false, "fail user and custom token requests that have no scope")
+	tokenCmd.PersistentFlags().BoolVar(&tokenInsecure, "allow-insecure-url", false, "allow a plain http platform URL")
+	tokenCmd.PersistentFlags().BoolVar(&tokenStrictExp, "strict-expiry", false, "fail when exp_seconds and expiresIn are set to different lifetimes, instead of warning")
+	tokenCmd.PersistentFlags().StringVar(&tokenSigningAlg, "signing-alg", "", "JWS algorithm for service account and private_key_jwt assertions (RS256, RS384, RS512, PS256, PS384, PS512 for RSA keys; ES256, ES384, ES512 for EC keys), overriding the configuration")
+	tokenCmd.PersistentFlags().BoolVar(&tokenStrictKey, "strict-key", false, "check that the RSA JWK modulus and private exponent match its primes p and q")
+	tokenCmd.PersistentFlags().StringArrayVar(&tokenHeaders, "header", nil, "additional request header as key=value (repeatable)")
+	tokenCmd.PersistentFlags().StringVar(&tokenUserAgent, "user-agent", "", "User-Agent for requests to PAIC (default pctl/<version>)")
 
-	// Mark config as required
-	tokenCmd.MarkFlagRequired("config")
+	// Token-specific flags
+	tokenCmd.Flags().StringVarP(&tokenType, "type", "t", "service-account", "token type (service-account, user, custom, authorization-code, token-exchange, device-code)")
+	tokenCmd.Flags().StringVar(&tokenSAID, "service-account-id", "", "service account ID, overriding the configuration")
+	tokenCmd.Flags().DurationVar(&tokenJWTLifetime, "jwt-lifetime", token.DefaultAssertionExp, "service account JWT assertion lifetime, independent of the access token lifetime")
+	tokenCmd.Flags().DurationVar(&tokenClockSkew, "clock-skew", 0, "offset added to the JWT assertion time claims, positive when the local clock is behind")
+	tokenCmd.Flags().StringArrayVar(&tokenClaims, "assertion-claim", nil, "service account assertion claim as key=value, merged over customClaims; true, false and numbers are typed, quote the value to keep a string (repeatable)")
+	tokenCmd.Flags().StringVar(&tokenAudience, "audience", "", "service account assertion audience, or token-exchange target audience, overriding the configuration")
+	tokenCmd.Flags().BoolVar(&tokenStrictAud, "strict-audience", false, "fail when the service account audience does not match the token endpoint, instead of warning")
+	tokenCmd.Flags().BoolVar(&tokenIncludeJTI, "include-jti", true, "add a jti claim to the service account JWT assertion; --include-jti=false omits it")
+	tokenCmd.Flags().BoolVar(&tokenAssertOnly, "assertion-only", false, "output the signed service account JWT assertion without exchanging it for an access token")
+	tokenCmd.Flags().StringVar(&tokenOutFile, "out-file", "", "write output to this file (mode 0600) instead of stdout")
+	tokenCmd.Flags().StringSliceVar(&tokenFields, "fields", nil, "comma-separated result fields to include in json or yaml output")
+	tokenCmd.Flags().StringVar(&tokenTemplate, "format-template", "", "render the result through a Go text/template, e.g. '{{.AccessToken}} expires {{.ExpiresAt}}', instead of --output")
+	tokenCmd.MarkFlagsMutuallyExclusive("format-template", "fields")
+	tokenCmd.Flags().StringVar(&tokenExportPfx, "export-prefix", token.DefaultExportPrefix, "environment variable prefix for export output")
+	tokenCmd.Flags().StringVar(&tokenUsername, "username", "", "user token username, overriding the configuration")
+	tokenCmd.Flags().BoolVar(&tokenPassStdin, "password-stdin", false, "read the user token password from stdin; without it, a missing password is prompted for on a terminal")
+	tokenCmd.Flags().StringVar(&tokenOTP, "otp", "", "one-time password for multi-factor user authentication, answered in the auth_tree authentication tree or the realm default")
+	tokenCmd.Flags().BoolVar(&tokenFingerprint, "fingerprint", false, "include the access token SHA-256 in the result metadata")
+	tokenCmd.Flags().BoolVar(&tokenDecode, "decode-after-generate", false, "add the decoded access token claims to text, json or yaml output")
+	tokenCmd.Flags().BoolVar(&tokenNoBrowser, "no-browser", false, "print the authorization-code login URL instead of opening a browser")
+	tokenCmd.Flags().StringVar(&tokenSubject, "subject-token", "", "token-exchange subject token, overriding the configuration")
+	tokenCmd.Flags().StringVar(&tokenActor, "actor-token", "", "token-exchange actor token, overriding the configuration")
+	tokenCmd.Flags().StringVar(&tokenCall, "call", "", "send an API request, given as 'METHOD URL', with the token as a bearer Authorization header and print the response")
+	tokenCmd.Flags().StringVar(&tokenCallData, "data", "", "request body for --call; @file reads it from a file")
+	tokenCmd.MarkFlagsMutuallyExclusive("call", "assertion-only")
+	tokenCmd.MarkFlagsMutuallyExclusive("call", "out-file")
+	tokenCmd.MarkFlagsMutuallyExclusive("call", "format-template")
+	tokenCmd.Flags().BoolVar(&tokenNoCache, "no-cache", false, "always generate a new token instead of reusing a cached one")
+	tokenCmd.Flags().DurationVar(&tokenCacheBuf, "cache-buffer", token.DefaultCacheBuffer, "regenerate cached tokens this close to expiry")
+	tokenCmd.Flags().DurationVar(&tokenMinValid, "min-validity", 0, "fail unless the token remains valid for at least this long, regenerating cached tokens that do not")
 
 	// Bind flags to viper
-	viper.BindPFlag("token.config", tokenCmd.Flags().Lookup("config"))
-	viper.BindPFlag("token.output", tokenCmd.Flags().Lookup("output"))
+	viper.BindPFlag("token.config", tokenCmd.PersistentFlags().Lookup("config"))
+	viper.BindPFlag("token.output", tokenCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("token.time-format", tokenCmd.PersistentFlags().Lookup("time-format"))
 	viper.BindPFlag("token.type", tokenCmd.Flags().Lookup("type"))
+	viper.BindPFlag("token.platform", tokenCmd.PersistentFlags().Lookup("platform"))
+	viper.BindPFlag("token.base-url", tokenCmd.PersistentFlags().Lookup("base-url"))
+	viper.BindPFlag("token.realm", tokenCmd.PersistentFlags().Lookup("realm"))
+	viper.BindPFlag("token.token-endpoint", tokenCmd.PersistentFlags().Lookup("token-endpoint"))
+	viper.BindPFlag("token.timeout", tokenCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("token.retries", tokenCmd.PersistentFlags().Lookup("retries"))
+	viper.BindPFlag("token.retry-max-wait", tokenCmd.PersistentFlags().Lookup("retry-max-wait"))
+	viper.BindPFlag("token.require-scope", tokenCmd.PersistentFlags().Lookup("require-scope"))
+	viper.BindPFlag("token.allow-insecure-url", tokenCmd.PersistentFlags().Lookup("allow-insecure-url"))
+	viper.BindPFlag("token.strict-key", tokenCmd.PersistentFlags().Lookup("strict-key"))
+	viper.BindPFlag("token.signing-alg", tokenCmd.PersistentFlags().Lookup("signing-alg"))
+	viper.BindPFlag("token.strict-expiry", tokenCmd.PersistentFlags().Lookup("strict-expiry"))
+	viper.BindPFlag("token.user-agent", tokenCmd.PersistentFlags().Lookup("user-agent"))
+	viper.BindPFlag("token.show-token", tokenCmd.PersistentFlags().Lookup("show-token"))
+	viper.BindPFlag("token.service-account-id", tokenCmd.Flags().Lookup("service-account-id"))
+	viper.BindPFlag("token.jwt-lifetime", tokenCmd.Flags().Lookup("jwt-lifetime"))
+	viper.BindPFlag("token.clock-skew", tokenCmd.Flags().Lookup("clock-skew"))
+	viper.BindPFlag("token.audience", tokenCmd.Flags().Lookup("audience"))
+	viper.BindPFlag("token.strict-audience", tokenCmd.Flags().Lookup("strict-audience"))
+	viper.BindPFlag("token.include-jti", tokenCmd.Flags().Lookup("include-jti"))
+	viper.BindPFlag("token.assertion-only", tokenCmd.Flags().Lookup("assertion-only"))
+	viper.BindPFlag("token.out-file", tokenCmd.Flags().Lookup("out-file"))
+	viper.BindPFlag("token.export-prefix", tokenCmd.Flags().Lookup("export-prefix"))
+	viper.BindPFlag("token.fields", tokenCmd.Flags().Lookup("fields"))
+	viper.BindPFlag("token.format-template", tokenCmd.Flags().Lookup("format-template"))
+	viper.BindPFlag("token.username", tokenCmd.Flags().Lookup("username"))
+	viper.BindPFlag("token.password-stdin", tokenCmd.Flags().Lookup("password-stdin"))
+	viper.BindPFlag("token.otp", tokenCmd.Flags().Lookup("otp"))
+	viper.BindPFlag("token.fingerprint", tokenCmd.Flags().Lookup("fingerprint"))
+	viper.BindPFlag("token.decode-after-generate", tokenCmd.Flags().Lookup("decode-after-generate"))
+	viper.BindPFlag("token.no-browser", tokenCmd.Flags().Lookup("no-browser"))
+	viper.BindPFlag("token.subject-token", tokenCmd.Flags().Lookup("subject-token"))
+	viper.BindPFlag("token.actor-token", tokenCmd.Flags().Lookup("actor-token"))
+	viper.BindPFlag("token.no-cache", tokenCmd.Flags().Lookup("no-cache"))
+	viper.BindPFlag("token.cache-buffer", tokenCmd.Flags().Lookup("cache-buffer"))
+	viper.BindPFlag("token.min-validity", tokenCmd.Flags().Lookup("min-validity"))
 }
\ No newline at end of file
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	batchListFile    string
+	batchConcurrency int
+)
+
+// tokenBatchCmd represents the token batch command
+var tokenBatchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Generate tokens for many configurations concurrently",
+	Long: `Generate a token for each configuration file named in a list file, several
+at a time. The list file holds one configuration path per line, relative to
+the list file; blank lines and lines starting with # are ignored. Each listed
+file is merged over the -c configuration, so shared settings such as the
+platform URL can live in one place.
+
+Results are printed in list order, except with -o jsonl, which prints one
+JSON object per line as each token is generated. The command fails if any
+token could not be generated.
+
+Examples:
+  pctl token batch -c platform.yaml --list accounts.txt
+  pctl token batch -c platform.yaml --list accounts.txt --concurrency 8 -o json
+  pctl token batch -c platform.yaml --list accounts.txt -o jsonl | jq -r .token.access_token`,
+	Args: cobra.NoArgs,
+	RunE: runTokenBatch,
+}
+
+func runTokenBatch(cmd *cobra.Command, args []string) error {
+	names, configs, err := loadConfigList(cmd, viper.GetString("token.batch.list"))
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	options := token.GeneratorOptions{
+		OutputFormat: token.OutputFormat(tokenOutput),
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+		ShowToken:    viper.GetBool("token.show-token"),
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+	}
+	client := token.NewClient(options)
+	concurrency := viper.GetInt("token.batch.concurrency")
+
+	var results []token.BatchResult
+	if options.OutputFormat == token.OutputFormatJSONL {
+		// Print each result as soon as it completes
+		var formatErr error
+		results = token.GenerateBatchFunc(context.Background(), options, configs, concurrency, func(i int, result token.BatchResult) {
+			output, err := client.FormatBatch(names[i:i+1], []token.BatchResult{result})
+			if err != nil {
+				formatErr = err
+				return
+			}
+			fmt.Print(output)
+		})
+		if formatErr != nil {
+			return fmt.Errorf("failed to format output: %w", formatErr)
+		}
+	} else {
+		results = token.GenerateBatch(context.Background(), options, configs, concurrency)
+
+		// Format and output the results
+		output, err := client.FormatBatch(names, results)
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+		fmt.Print(output)
+	}
+
+	var failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("token generation failed for %d of %d configurations", failed, len(results))
+	}
+	return nil
+}
+
+// loadConfigList loads each configuration named in the list file merged over
+// the -c configuration, returning the names and configurations in list order
+func loadConfigList(cmd *cobra.Command, listFile string) ([]string, []token.TokenConfig, error) {
+	names, err := readConfigList(listFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configs := make([]token.TokenConfig, len(names))
+	for i, name := range names {
+		tokenConfig, err := loadTokenConfigFiles(cmd, append(append([]string(nil), tokenConfigFiles...), name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		configs[i] = *tokenConfig
+	}
+	return names, configs, nil
+}
+
+// readConfigList reads the configuration paths from a list file, resolving
+// relative paths against the list file's directory
+func readConfigList(listFile string) ([]string, error) {
+	file, err := os.Open(listFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config list: %w", err)
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(listFile), line)
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config list: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("config list %s names no configurations", listFile)
+	}
+	return names, nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenBatchCmd)
+
+	tokenBatchCmd.Flags().StringVar(&batchListFile, "list", "", "file listing one token configuration path per line")
+	tokenBatchCmd.Flags().IntVar(&batchConcurrency, "concurrency", token.DefaultBatchConcurrency, "maximum number of tokens to generate at once")
+	tokenBatchCmd.MarkFlagRequired("list")
+
+	viper.BindPFlag("token.batch.list", tokenBatchCmd.Flags().Lookup("list"))
+	viper.BindPFlag("token.batch.concurrency", tokenBatchCmd.Flags().Lookup("concurrency"))
+}
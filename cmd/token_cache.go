@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheListBuffer time.Duration
+
+// tokenCacheCmd represents the token cache command
+var tokenCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and purge the token cache",
+	Long: `Inspect and purge the tokens cached in ~/.pctl/cache by pctl token, unless
+run with --no-cache, and by pctl token refresh-cache.`,
+	PersistentPreRunE: prepareTokenCacheCommand,
+}
+
+// tokenCacheListCmd represents the token cache list command
+var tokenCacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached tokens without the tokens themselves",
+	Long: `List each cache entry by its key, with the token type, account (service
+account ID, username or client ID), platform and scope it was issued for,
+when it expires and whether it is still valid. The tokens themselves are
+never shown. A token is valid until it is within --cache-buffer of expiry,
+as for pctl token --cache.
+
+Entries cached by older versions of pctl may lack the type, platform or
+scope.
+
+Examples:
+  pctl token cache list
+  pctl token cache list -o json`,
+	Args: cobra.NoArgs,
+	RunE: runTokenCacheList,
+}
+
+// tokenCacheClearCmd represents the token cache clear command
+var tokenCacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenCacheClear,
+}
+
+// prepareTokenCacheCommand validates the output flags; unlike other token
+// commands, the cache commands need no token configuration
+func prepareTokenCacheCommand(cmd *cobra.Command, args []string) error {
+	defaults, err := loadTokenDefaults()
+	if err != nil {
+		return err
+	}
+	applyTokenOutputDefault(cmd, defaults)
+	return validateTokenOutputFlags(cmd, args)
+}
+
+func runTokenCacheList(cmd *cobra.Command, args []string) error {
+	cache, err := token.NewFileCache("", viper.GetDuration("token.cache.list.cache-buffer"))
+	if err != nil {
+		return err
+	}
+
+	entries, err := cache.List()
+	if err != nil {
+		return err
+	}
+
+	options := token.GeneratorOptions{
+		OutputFormat: token.OutputFormat(tokenOutput),
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+	}
+	output, err := token.NewClient(options).FormatCacheList(entries)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	fmt.Print(output)
+	return nil
+}
+
+func runTokenCacheClear(cmd *cobra.Command, args []string) error {
+	cache, err := token.NewFileCache("", 0)
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d cached tokens\n", removed)
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenCacheCmd)
+	tokenCacheCmd.AddCommand(tokenCacheListCmd)
+	tokenCacheCmd.AddCommand(tokenCacheClearCmd)
+
+	tokenCacheListCmd.Flags().DurationVar(&cacheListBuffer, "cache-buffer", token.DefaultCacheBuffer, "report cached tokens this close to expiry as invalid")
+
+	viper.BindPFlag("token.cache.list.cache-buffer", tokenCacheListCmd.Flags().Lookup("cache-buffer"))
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokenIntrospectCmd represents the token introspect command
+var tokenIntrospectCmd = &cobra.Command{
+	Use:   "introspect <token>",
+	Short: "Check whether a token is active using PAIC introspection",
+	Long: `Check whether an access token is still active against the PAIC
+introspection endpoint, authenticating with the client credentials
+(clientId, clientSecret) from the token configuration.
+
+Examples:
+  pctl token introspect -c config.yaml "$TOKEN"
+  pctl token introspect -c config.yaml "$TOKEN" -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTokenIntrospect,
+}
+
+func runTokenIntrospect(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:       *tokenConfig,
+		OutputFormat: token.OutputFormat(tokenOutput),
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+	})
+
+	result, err := client.Introspect(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("token introspection failed: %w", err)
+	}
+
+	// Format and output the result
+	output, err := client.FormatIntrospection(result)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+
+	fmt.Print(output)
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenIntrospectCmd)
+}
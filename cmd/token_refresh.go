@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokenRefreshCmd represents the token refresh command
+var tokenRefreshCmd = &cobra.Command{
+	Use:   "refresh [refresh-token]",
+	Short: "Exchange a refresh token for a new access token",
+	Long: `Exchange a refresh token for a new access token using the OAuth 2.0
+refresh token grant, without re-sending user credentials. The refresh
+token is taken from the argument or refreshToken in the token
+configuration. Any rotated refresh token is included in the output.
+
+Examples:
+  pctl token refresh -c config.yaml "$REFRESH_TOKEN"
+  pctl token refresh -c config.yaml -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTokenRefresh,
+}
+
+func runTokenRefresh(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var refreshToken string
+	if len(args) == 1 {
+		refreshToken = args[0]
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:       *tokenConfig,
+		OutputFormat: token.OutputFormat(tokenOutput),
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+		ShowToken:    viper.GetBool("token.show-token"),
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+	})
+
+	result, err := client.Refresh(context.Background(), refreshToken)
+	if err != nil {
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	// Format and output the result
+	output, err := client.FormatOutput(result)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+
+	fmt.Print(output)
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRefreshCmd)
+}
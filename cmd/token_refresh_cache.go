@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	refreshCacheList        string
+	refreshCacheConcurrency int
+	refreshCacheBuffer      time.Duration
+	refreshCacheMinValid    time.Duration
+)
+
+// tokenRefreshCacheCmd represents the token refresh-cache command
+var tokenRefreshCacheCmd = &cobra.Command{
+	Use:   "refresh-cache",
+	Short: "Generate and cache tokens for many configurations ahead of use",
+	Long: `Pre-warm the token cache for each configuration file named in a list file,
+so that later token commands reuse the cached tokens instead of waiting on
+PAIC. The list file has the same format as for pctl token batch, and each
+listed file is merged over the -c configuration.
+
+Cached tokens that are still valid are kept; use --min-validity to
+regenerate those expiring sooner. Whether each token was cached is reported
+in list order, without the tokens themselves. The command fails if any
+token could not be cached.
+
+Examples:
+  pctl token refresh-cache -c platform.yaml --list accounts.txt
+  pctl token refresh-cache -c platform.yaml --list accounts.txt --min-validity 8h -o json`,
+	Args: cobra.NoArgs,
+	RunE: runTokenRefreshCache,
+}
+
+func runTokenRefreshCache(cmd *cobra.Command, args []string) error {
+	names, configs, err := loadConfigList(cmd, viper.GetString("token.refresh-cache.list"))
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	cache, err := token.NewFileCache("", viper.GetDuration("token.refresh-cache.cache-buffer"))
+	if err != nil {
+		return err
+	}
+
+	options := token.GeneratorOptions{
+		OutputFormat: token.OutputFormat(tokenOutput),
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+		Cache:        cache,
+		MinValidity:  viper.GetDuration("token.refresh-cache.min-validity"),
+	}
+	results := token.GenerateBatch(context.Background(), options, configs, viper.GetInt("token.refresh-cache.concurrency"))
+
+	// Report the results without the tokens
+	output, err := token.NewClient(options).FormatCacheRefresh(names, results)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	fmt.Print(output)
+
+	var failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("token caching failed for %d of %d configurations", failed, len(results))
+	}
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRefreshCacheCmd)
+
+	tokenRefreshCacheCmd.Flags().StringVar(&refreshCacheList, "list", "", "file listing one token configuration path per line")
+	tokenRefreshCacheCmd.Flags().IntVar(&refreshCacheConcurrency, "concurrency", token.DefaultBatchConcurrency, "maximum number of tokens to generate at once")
+	tokenRefreshCacheCmd.Flags().DurationVar(&refreshCacheBuffer, "cache-buffer", token.DefaultCacheBuffer, "regenerate cached tokens this close to expiry")
+	tokenRefreshCacheCmd.Flags().DurationVar(&refreshCacheMinValid, "min-validity", 0, "regenerate cached tokens valid for less than this long, and fail for tokens issued with a shorter lifetime")
+	tokenRefreshCacheCmd.MarkFlagRequired("list")
+
+	viper.BindPFlag("token.refresh-cache.list", tokenRefreshCacheCmd.Flags().Lookup("list"))
+	viper.BindPFlag("token.refresh-cache.concurrency", tokenRefreshCacheCmd.Flags().Lookup("concurrency"))
+	viper.BindPFlag("token.refresh-cache.cache-buffer", tokenRefreshCacheCmd.Flags().Lookup("cache-buffer"))
+	viper.BindPFlag("token.refresh-cache.min-validity", tokenRefreshCacheCmd.Flags().Lookup("min-validity"))
+}
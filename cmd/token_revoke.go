@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var tokenRevokeRefresh string
+
+// tokenRevokeCmd represents the token revoke command
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke [token]",
+	Short: "Revoke an access or refresh token",
+	Long: `Revoke an issued access token or refresh token at the PAIC revocation
+endpoint, authenticating with the client credentials (clientId,
+clientSecret) from the token configuration.
+
+Examples:
+  pctl token revoke -c config.yaml "$ACCESS_TOKEN"
+  pctl token revoke -c config.yaml --refresh-token "$REFRESH_TOKEN"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTokenRevoke,
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	// Determine the token to revoke
+	var tok, hint string
+	switch {
+	case len(args) == 1 && tokenRevokeRefresh != "":
+		return fmt.Errorf("specify either a token argument or --refresh-token, not both")
+	case len(args) == 1:
+		tok, hint = args[0], token.TokenTypeHintAccessToken
+	case tokenRevokeRefresh != "":
+		tok, hint = tokenRevokeRefresh, token.TokenTypeHintRefreshToken
+	default:
+		return fmt.Errorf("a token argument or --refresh-token is required")
+	}
+
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:  *tokenConfig,
+		Verbose: viper.GetBool("verbose"),
+		Logger:  log,
+	})
+
+	if err := client.Revoke(context.Background(), tok, hint); err != nil {
+		return fmt.Errorf("token revocation failed: %w", err)
+	}
+
+	if !viper.GetBool("quiet") {
+		fmt.Println("Token revoked successfully")
+	}
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenRevokeCmd.Flags().StringVar(&tokenRevokeRefresh, "refresh-token", "", "refresh token to revoke")
+}
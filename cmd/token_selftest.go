@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokenSelftestCmd represents the token selftest command
+var tokenSelftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Generate a token and confirm it is usable, step by step",
+	Long: `Check a token configuration end to end for troubleshooting. Each step is
+reported as passed, failed or skipped:
+
+  config          the configuration is valid
+  key parse       the JWK or PEM signing key parses
+  assertion sign  a service account assertion can be signed
+  token exchange  PAIC issues a new token; the token cache is not used
+  token active    token introspection, or userinfo when no client secret
+                  is configured, accepts the token
+
+Steps after the first failure are skipped, and the command fails if any
+step failed.
+
+Examples:
+  pctl token selftest -c config.yaml
+  pctl token selftest -c config.yaml -o json`,
+	Args: cobra.NoArgs,
+	RunE: runTokenSelftest,
+}
+
+func runTokenSelftest(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:       *tokenConfig,
+		OutputFormat: token.OutputFormat(tokenOutput),
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+	})
+
+	// Format and output the result, in color on a terminal
+	result := client.SelfTest(context.Background())
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	output, err := client.FormatSelfTest(result, color)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	fmt.Print(output)
+
+	if !result.Passed {
+		// The failed step is already reported, so skip the usage text
+		cmd.SilenceUsage = true
+		return fmt.Errorf("self-test failed")
+	}
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenSelftestCmd)
+}
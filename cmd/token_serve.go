@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	serveOutFile       string
+	serveRefreshBefore time.Duration
+)
+
+// tokenServeCmd represents the token serve command
+var tokenServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Keep a fresh token in a file, regenerating it before expiry",
+	Long: `Generate a token, write it atomically to the output file, and regenerate
+it shortly before it expires, until interrupted with SIGINT or SIGTERM.
+The file holds the raw access token unless another output format is
+requested with -o.
+
+Examples:
+  pctl token serve -c config.yaml --out-file token.txt
+  pctl token serve -c config.yaml --out-file token.json -o json --refresh-before 5m`,
+	Args: cobra.NoArgs,
+	RunE: runTokenServe,
+}
+
+func runTokenServe(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Log each refresh, not just warnings
+	log, err := newLevelLogger(slog.LevelInfo)
+	if err != nil {
+		return err
+	}
+
+	// A token file holds the bare token unless a format is requested
+	outputFormat := token.OutputFormatRaw
+	if cmd.Flags().Changed("output") {
+		outputFormat = token.OutputFormat(tokenOutput)
+	}
+
+	// Reuse connections to PAIC across refreshes
+	httpClient, err := token.NewHTTPClient(*tokenConfig)
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:       *tokenConfig,
+		OutputFormat: outputFormat,
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+		ShowToken:    true, // The file is read by programs, not shown on a terminal
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+		HTTPClient:   httpClient,
+	})
+
+	// Stop cleanly on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	outFile := viper.GetString("token.serve.out-file")
+	err = client.Watch(ctx, viper.GetDuration("token.serve.refresh-before"), func(result *token.TokenResult) error {
+		output, err := client.FormatOutput(result)
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+		if err := token.WriteFileAtomic(outFile, []byte(output), 0600); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("token generation failed: %w", err)
+	}
+
+	log.Info("stopped")
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenServeCmd)
+
+	tokenServeCmd.Flags().StringVar(&serveOutFile, "out-file", "", "file to keep the token in (mode 0600)")
+	tokenServeCmd.Flags().DurationVar(&serveRefreshBefore, "refresh-before", token.DefaultRefreshBefore, "regenerate the token this long before it expires")
+	tokenServeCmd.MarkFlagRequired("out-file")
+
+	viper.BindPFlag("token.serve.out-file", tokenServeCmd.Flags().Lookup("out-file"))
+	viper.BindPFlag("token.serve.refresh-before", tokenServeCmd.Flags().Lookup("refresh-before"))
+}
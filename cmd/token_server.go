@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var serverAddr string
+
+// tokenServerCmd represents the token server command
+var tokenServerCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Serve the current access token over local HTTP",
+	Long: `Serve the current access token as JSON at GET /token, in the style of a
+cloud metadata server, so local processes can share one token without
+embedding credentials. The token is generated on the first request and
+regenerated as it nears expiry.
+
+The server listens on localhost by default; binding to other interfaces
+exposes the token to anyone who can reach the port.
+
+Examples:
+  pctl token server -c config.yaml
+  pctl token server -c config.yaml --addr 127.0.0.1:9090
+  curl -s http://127.0.0.1:8080/token`,
+	Args: cobra.NoArgs,
+	RunE: runTokenServer,
+}
+
+func runTokenServer(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	log, err := newLevelLogger(slog.LevelInfo)
+	if err != nil {
+		return err
+	}
+
+	// Reuse connections to PAIC across token requests
+	httpClient, err := token.NewHTTPClient(*tokenConfig)
+	if err != nil {
+		return err
+	}
+
+	client := token.NewCachingClient(token.NewClient(token.GeneratorOptions{
+		Config:     *tokenConfig,
+		Verbose:    viper.GetBool("verbose"),
+		Logger:     log,
+		HTTPClient: httpClient,
+	}), 0)
+
+	server := &http.Server{
+		Addr:              viper.GetString("token.server.addr"),
+		Handler:           token.NewTokenHandler(client),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Stop cleanly on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Info("serving token", "url", "http://"+server.Addr+token.TokenPath)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("token server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("token server shutdown failed: %w", err)
+	}
+
+	log.Info("stopped")
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenServerCmd)
+
+	tokenServerCmd.Flags().StringVar(&serverAddr, "addr", "127.0.0.1:8080", "address to listen on")
+
+	viper.BindPFlag("token.server.addr", tokenServerCmd.Flags().Lookup("addr"))
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenDefaults(t *testing.T) {
+	server := newTokenServer(t)
+	config := writeTokenConfig(t, server)
+
+	tests := []struct {
+		name       string
+		defaults   string
+		args       []string
+		wantStdout string
+		wantErr    string
+	}{
+		{
+			name:       "config and output defaults",
+			defaults:   "token:\n  config: " + config + "\n  output: raw\n",
+			args:       []string{"token", "--no-cache"},
+			wantStdout: "test-access-token\n",
+		},
+		{
+			name:       "relative config default",
+			defaults:   "token:\n  config: token.yaml\n  output: raw\n",
+			args:       []string{"token", "--no-cache"},
+			wantStdout: "test-access-token\n",
+		},
+		{
+			name:       "config flag wins",
+			defaults:   "token:\n  config: /nonexistent/token.yaml\n  output: raw\n",
+			args:       []string{"token", "--no-cache", "-c", config},
+			wantStdout: "test-access-token\n",
+		},
+		{
+			name:       "output flag wins",
+			defaults:   "token:\n  config: " + config + "\n  output: raw\n",
+			args:       []string{"token", "--no-cache", "-o", "json"},
+			wantStdout: `"access_token": "test-access-token"`,
+		},
+		{
+			name:       "other token keys ignored",
+			defaults:   "token:\n  config: " + config + "\n  output: raw\n  out-file: " + filepath.Join(t.TempDir(), "token.txt") + "\n",
+			args:       []string{"token", "--no-cache"},
+			wantStdout: "test-access-token\n",
+		},
+		{
+			name:    "no config",
+			args:    []string{"token", "--no-cache"},
+			wantErr: `required flag(s) "config" not set`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := newHome(t, tt.defaults)
+			// Relative token config paths resolve against ~/.pctl
+			data, err := os.ReadFile(config)
+			if err != nil {
+				t.Fatalf("Failed to read token config: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(home, ".pctl", "token.yaml"), data, 0600); err != nil {
+				t.Fatalf("Failed to write token config: %v", err)
+			}
+
+			stdout, stderr, err := executeCommand(t, home, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v\nstderr: %s", err, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("Expected stdout to contain %q, got %q", tt.wantStdout, stdout)
+			}
+		})
+	}
+}
+
+func TestLoadTokenConfigFilesOverrides(t *testing.T) {
+	server := newTokenServer(t)
+	config := writeTokenConfig(t, server, "scope: fr:am:*")
+
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, config *token.TokenConfig)
+	}{
+		{
+			name: "configured scope",
+			check: func(t *testing.T, config *token.TokenConfig) {
+				if config.Scope != "fr:am:*" || !slices.Equal(config.Scopes, []string{"fr:am:*"}) {
+					t.Errorf("Expected the configured scope, got %q / %q", config.Scope, config.Scopes)
+				}
+			},
+		},
+		{
+			name: "scope flags replace scope and scopes",
+			args: []string{"--scope", "fr:idm:* fr:iga:*", "--scope", "openid"},
+			check: func(t *testing.T, config *token.TokenConfig) {
+				want := []string{"fr:idm:*", "fr:iga:*", "openid"}
+				if config.Scope != strings.Join(want, " ") || !slices.Equal(config.Scopes, want) {
+					t.Errorf("Expected scopes %q, got %q / %q", want, config.Scope, config.Scopes)
+				}
+			},
+		},
+		{
+			name: "platform flag sets platform and base URL",
+			args: []string{"--platform", "https://staging.example.com"},
+			check: func(t *testing.T, config *token.TokenConfig) {
+				if config.Platform != "https://staging.example.com" || config.BaseURL != "https://staging.example.com" {
+					t.Errorf("Expected the staging platform and base URL, got %q / %q", config.Platform, config.BaseURL)
+				}
+			},
+		},
+		{
+			name: "base URL flag only sets base URL",
+			args: []string{"--base-url", "https://prod.example.com"},
+			check: func(t *testing.T, config *token.TokenConfig) {
+				if config.Platform != "" || config.BaseURL != "https://prod.example.com" {
+					t.Errorf("Expected only the base URL to be overridden, got %q / %q", config.Platform, config.BaseURL)
+				}
+			},
+		},
+		{
+			name: "base URL flag takes precedence over platform flag",
+			args: []string{"--platform", "https://staging.example.com", "--base-url", "https://prod.example.com"},
+			check: func(t *testing.T, config *token.TokenConfig) {
+				if config.Platform != "https://staging.example.com" || config.BaseURL != "https://prod.example.com" {
+					t.Errorf("Expected the staging platform and prod base URL, got %q / %q", config.Platform, config.BaseURL)
+				}
+			},
+		},
+		{
+			name: "service account ID flag",
+			args: []string{"--service-account-id", "other-account"},
+			check: func(t *testing.T, config *token.TokenConfig) {
+				if config.ServiceAccountID != "other-account" {
+					t.Errorf("Expected service account ID 'other-account', got %q", config.ServiceAccountID)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(rootCmd)
+			if err := tokenCmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("Failed to parse flags: %v", err)
+			}
+
+			loaded, err := loadTokenConfigFiles(tokenCmd, []string{config})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.check(t, loaded)
+		})
+	}
+}
+
+func TestServiceAccountIDBeforeValidation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	// A service account configuration without service_account_id, returning
+	// the signed assertion so no token request is made
+	lines := []string{
+		"type: service-account",
+		"platform: https://paic.example.com",
+		"keyId: test-key",
+		"assertion_only: true",
+		"privateKey: |",
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(keyPEM)), "\n") {
+		lines = append(lines, "  "+line)
+	}
+	config := filepath.Join(t.TempDir(), "sa.yaml")
+	if err := os.WriteFile(config, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token config: %v", err)
+	}
+
+	if _, _, err := executeCommand(t, newHome(t, ""), "token", "-c", config, "-o", "raw", "--no-cache"); err == nil || !strings.Contains(err.Error(), "service_account_id is required") {
+		t.Fatalf("Expected missing service_account_id error, got %v", err)
+	}
+
+	stdout, stderr, err := executeCommand(t, newHome(t, ""), "token", "-c", config, "-o", "raw", "--no-cache", "--service-account-id", "flag-account")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\nstderr: %s", err, stderr)
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(strings.TrimSpace(stdout), claims); err != nil {
+		t.Fatalf("Expected a JWT assertion on stdout, got %q: %v", stdout, err)
+	}
+	if claims["sub"] != "flag-account" || claims["iss"] != "flag-account" {
+		t.Errorf("Expected the assertion to be for flag-account, got %v", claims)
+	}
+}
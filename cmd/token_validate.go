@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokenValidateCmd represents the token validate command
+var tokenValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a token configuration without generating a token",
+	Long: `Check a token configuration without making any network calls. The
+configuration is validated and the JWK or PEM signing key is parsed, and
+every problem found is reported. A key fetched from jwks_url is not checked.
+
+The command fails with exit code 2 if any problem was found, so it can be
+used as a CI pre-flight check.
+
+Examples:
+  pctl token validate -c config.yaml
+  pctl token validate -c base.yaml -c account.yaml -o json`,
+	Args: cobra.NoArgs,
+	RunE: runTokenValidate,
+}
+
+func runTokenValidate(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:       *tokenConfig,
+		OutputFormat: token.OutputFormat(tokenOutput),
+		Verbose:      viper.GetBool("verbose"),
+	})
+
+	// Format and output the result
+	result := token.Check(tokenConfig)
+	output, err := client.FormatCheck(result)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	fmt.Print(output)
+
+	if !result.Valid {
+		// The problems are already listed, so skip the usage text
+		cmd.SilenceUsage = true
+		return withExitCode(ExitValidation, fmt.Errorf("configuration has %d problem(s)", len(result.Problems)))
+	}
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenValidateCmd)
+}
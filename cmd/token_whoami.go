@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaronwang/pctl/pkg/token"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokenWhoamiCmd represents the token whoami command
+var tokenWhoamiCmd = &cobra.Command{
+	Use:   "whoami [token]",
+	Short: "Show the identity a token represents using PAIC userinfo",
+	Long: `Show the claims about the identity an access token represents from the
+PAIC userinfo endpoint. Without a token argument, a new token is
+generated from the token configuration first.
+
+Examples:
+  pctl token whoami -c user.yaml
+  pctl token whoami -c config.yaml "$TOKEN" -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTokenWhoami,
+}
+
+func runTokenWhoami(cmd *cobra.Command, args []string) error {
+	// Load token configuration
+	tokenConfig, err := loadTokenConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var accessToken string
+	if len(args) == 1 {
+		accessToken = args[0]
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+
+	client := token.NewClient(token.GeneratorOptions{
+		Config:       *tokenConfig,
+		OutputFormat: token.OutputFormat(tokenOutput),
+		TimeFormat:   token.TimeFormat(tokenTimeFmt),
+		Verbose:      viper.GetBool("verbose"),
+		Logger:       log,
+	})
+
+	info, err := client.UserInfo(context.Background(), accessToken)
+	if err != nil {
+		return fmt.Errorf("userinfo lookup failed: %w", err)
+	}
+
+	// Format and output the result
+	output, err := client.FormatUserInfo(info)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+
+	fmt.Print(output)
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenWhoamiCmd)
+}
@@ -10,7 +10,7 @@ import (
 
 // ExampleInternalTokenUsage demonstrates how other PCTL commands would use token generation internally
 func ExampleInternalTokenUsage() {
-	fmt.Println("=== PCTL Internal Token API Usage Example ===\n")
+	fmt.Print("=== PCTL Internal Token API Usage Example ===\n\n")
 	
 	// 1. Load token configuration (as ELK command would do)
 	fmt.Println("1. Loading token configuration from file...")
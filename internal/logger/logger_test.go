@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		expectError bool
+	}{
+		{name: "text", format: FormatText},
+		{name: "default", format: ""},
+		{name: "json", format: FormatJSON},
+		{name: "invalid", format: "xml", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log, err := New(&buf, tt.format, slog.LevelDebug)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			log.Debug("token request", "status", 200)
+			if !strings.Contains(buf.String(), "token request") {
+				t.Errorf("Expected record in output, got %q", buf.String())
+			}
+			if tt.format == FormatJSON && !json.Valid(buf.Bytes()) {
+				t.Errorf("Expected JSON output, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestNewLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, FormatText, slog.LevelWarn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	log.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected debug record to be dropped, got %q", buf.String())
+	}
+}
@@ -0,0 +1,234 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// defaultSessionCookieName is the AM session cookie used when session_cookie_name is not set
+	defaultSessionCookieName = "iPlanetDirectoryPro"
+	// maxAuthTreeSteps bounds the callback round trips before authentication is abandoned
+	maxAuthTreeSteps = 10
+)
+
+// AuthCallback is a callback requesting input in a PAIC authentication tree response
+type AuthCallback struct {
+	Type   string              `json:"type"`
+	Output []AuthCallbackValue `json:"output,omitempty"`
+	Input  []AuthCallbackValue `json:"input,omitempty"`
+}
+
+// AuthCallbackValue is a named callback input or output value
+type AuthCallbackValue struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// authTreeResponse is a response from the PAIC authenticate endpoint: either
+// callbacks to answer and resubmit, or the session token once the tree completes
+type authTreeResponse struct {
+	AuthID    string         `json:"authId,omitempty"`
+	Callbacks []AuthCallback `json:"callbacks,omitempty"`
+	TokenID   string         `json:"tokenId,omitempty"`
+}
+
+// authenticateURL returns the PAIC authenticate endpoint URL for the configured
+// realm, matching the realm of the authorize and token endpoints
+func authenticateURL(config TokenConfig) string {
+	return platformURL(config) + "/am/json" + realmPath(config) + "/authenticate"
+}
+
+// authTreeURL returns the authenticate endpoint URL that starts the
+// configured authentication tree, or the realm's default tree when unset
+func authTreeURL(config TokenConfig) string {
+	if config.AuthTree == "" {
+		return authenticateURL(config)
+	}
+	query := url.Values{
+		"authIndexType":  {"service"},
+		"authIndexValue": {config.AuthTree},
+	}
+	return authenticateURL(config) + "?" + query.Encode()
+}
+
+// prompt returns the prompt shown for the callback
+func (c *AuthCallback) prompt() string {
+	for _, output := range c.Output {
+		if output.Name == "prompt" {
+			return fmt.Sprint(output.Value)
+		}
+	}
+	return ""
+}
+
+// isOTPPrompt reports whether the callback prompt asks for a one-time password
+func isOTPPrompt(prompt string) bool {
+	prompt = strings.ToLower(prompt)
+	for _, keyword := range []string{"one time", "one-time", "otp", "code", "verification"} {
+		if strings.Contains(prompt, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// completeAuthTree starts the authentication tree and answers its callbacks
+// until PAIC issues a session token, and returns that token
+func (g *UserTokenGenerator) completeAuthTree(ctx context.Context, log *slog.Logger) (string, error) {
+	authURL := authTreeURL(g.Config)
+	header := http.Header{
+		"Accept-Api-Version": {"resource=2.1, protocol=1.0"},
+	}
+
+	var body []byte
+	passwordSent := false
+	for step := 0; step < maxAuthTreeSteps; step++ {
+		log.Debug("requesting authentication tree step", "url", authURL, "step", step)
+		resp, respBody, err := post(ctx, g.Config, authURL, "application/json", body, header, log)
+		if err != nil {
+			return "", fmt.Errorf("authentication request failed: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		var challenge authTreeResponse
+		if err := json.Unmarshal(respBody, &challenge); err != nil {
+			return "", fmt.Errorf("failed to parse authentication response: %w", err)
+		}
+		if challenge.TokenID != "" {
+			return challenge.TokenID, nil
+		}
+		if len(challenge.Callbacks) == 0 {
+			return "", fmt.Errorf("authentication response has neither callbacks nor a session token")
+		}
+
+		if passwordSent, err = g.answerCallbacks(challenge.Callbacks, passwordSent); err != nil {
+			return "", err
+		}
+		if body, err = json.Marshal(challenge); err != nil {
+			return "", fmt.Errorf("failed to encode authentication callbacks: %w", err)
+		}
+	}
+
+	return "", fmt.Errorf("authentication did not complete after %d steps", maxAuthTreeSteps)
+}
+
+// answerCallbacks fills in the callback inputs. NameCallbacks take the
+// username and PasswordCallbacks the password until it has been sent; after
+// that, a PasswordCallback prompting for a one-time password takes the OTP,
+// as does a TextInputCallback prompting for one. Callbacks without inputs,
+// such as TextOutputCallback, are left as they are. It reports whether the
+// password has been sent once these callbacks are submitted.
+func (g *UserTokenGenerator) answerCallbacks(callbacks []AuthCallback, passwordSent bool) (bool, error) {
+	answeredPassword := false
+	for i := range callbacks {
+		callback := &callbacks[i]
+		if len(callback.Input) == 0 {
+			continue
+		}
+		prompt := callback.prompt()
+
+		var value string
+		switch callback.Type {
+		case "NameCallback":
+			value = g.Config.Username
+		case "PasswordCallback":
+			if !(passwordSent || answeredPassword) || !isOTPPrompt(prompt) {
+				value = g.Config.Password
+				answeredPassword = true
+				break
+			}
+			otp, err := g.oneTimePassword(prompt)
+			if err != nil {
+				return passwordSent, err
+			}
+			value = otp
+		case "TextInputCallback":
+			if !isOTPPrompt(prompt) {
+				return passwordSent, fmt.Errorf("unsupported authentication callback %s: %q", callback.Type, prompt)
+			}
+			otp, err := g.oneTimePassword(prompt)
+			if err != nil {
+				return passwordSent, err
+			}
+			value = otp
+		default:
+			return passwordSent, fmt.Errorf("unsupported authentication callback %s: %q", callback.Type, prompt)
+		}
+
+		for j := range callback.Input {
+			callback.Input[j].Value = value
+		}
+	}
+	return passwordSent || answeredPassword, nil
+}
+
+// oneTimePassword returns the configured one-time password, prompting for it when none is set
+func (g *UserTokenGenerator) oneTimePassword(prompt string) (string, error) {
+	if g.Config.OTP != "" {
+		return g.Config.OTP, nil
+	}
+	if g.OTPPrompt == nil {
+		return "", fmt.Errorf("one-time password required: %q (provide otp)", prompt)
+	}
+
+	otp, err := g.OTPPrompt(prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to read one-time password: %w", err)
+	}
+	return strings.TrimSpace(otp), nil
+}
+
+// authorizationCode obtains an authorization code for the session token from the PAIC authorize endpoint
+func (g *UserTokenGenerator) authorizationCode(ctx context.Context, tokenID string, log *slog.Logger) (string, error) {
+	if g.Config.RedirectURI == "" {
+		return "", fmt.Errorf("redirect_uri is required to complete authentication tree login")
+	}
+
+	cookieName := g.Config.SessionCookieName
+	if cookieName == "" {
+		cookieName = defaultSessionCookieName
+	}
+
+	authorizeURL := oauth2EndpointURL(g.Config, "authorize")
+	data := url.Values{
+		"response_type": {"code"},
+		"client_id":     {g.Config.ClientID},
+		"redirect_uri":  {g.Config.RedirectURI},
+		"scope":         {requestedScope(g.Config)},
+		"decision":      {"allow"},
+		"csrf":          {tokenID},
+	}
+
+	log.Debug("requesting authorization code", "url", authorizeURL)
+	resp, body, err := post(ctx, g.Config, authorizeURL, "application/x-www-form-urlencoded", []byte(data.Encode()), http.Header{
+		"Cookie": {(&http.Cookie{Name: cookieName, Value: tokenID}).String()},
+	}, log)
+	if err != nil {
+		return "", fmt.Errorf("authorization request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusFound && resp.StatusCode != http.StatusSeeOther {
+		return "", fmt.Errorf("authorization request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("authorization response has no redirect location: %w", err)
+	}
+	query := location.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		return "", fmt.Errorf("authorization denied: %s: %s", errorCode, query.Get("error_description"))
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("authorization response has no code")
+	}
+	return code, nil
+}
@@ -0,0 +1,244 @@
+package token
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMFAServer returns a PAIC stub whose authentication tree asks for the
+// username and password, then a one-time password, before issuing tokens via
+// the authorization code grant. Every endpoint is served under realmPath, such
+// as /realms/root/alpha, and the tree must be started with the tree name.
+func newMFAServer(t *testing.T, realmPath, tree string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/am/json" + realmPath + "/authenticate":
+			if got := r.URL.Query().Get("authIndexValue"); got != tree {
+				t.Errorf("Expected tree %q, got %q", tree, got)
+			}
+			var submitted authTreeResponse
+			json.NewDecoder(r.Body).Decode(&submitted)
+			switch {
+			case submitted.AuthID == "":
+				json.NewEncoder(w).Encode(authTreeResponse{
+					AuthID: "credentials-step",
+					Callbacks: []AuthCallback{
+						{Type: "NameCallback", Output: []AuthCallbackValue{{Name: "prompt", Value: "User Name"}}, Input: []AuthCallbackValue{{Name: "IDToken1", Value: ""}}},
+						{Type: "PasswordCallback", Output: []AuthCallbackValue{{Name: "prompt", Value: "Password"}}, Input: []AuthCallbackValue{{Name: "IDToken2", Value: ""}}},
+					},
+				})
+			case submitted.AuthID == "credentials-step" && submitted.Callbacks[0].Input[0].Value == "testuser" && submitted.Callbacks[1].Input[0].Value == "testpass":
+				json.NewEncoder(w).Encode(authTreeResponse{
+					AuthID: "otp-step",
+					Callbacks: []AuthCallback{{
+						Type:   "PasswordCallback",
+						Output: []AuthCallbackValue{{Name: "prompt", Value: "One Time Password"}},
+						Input:  []AuthCallbackValue{{Name: "IDToken1", Value: ""}},
+					}},
+				})
+			case submitted.AuthID == "otp-step" && submitted.Callbacks[0].Input[0].Value == "123456":
+				json.NewEncoder(w).Encode(authTreeResponse{TokenID: "sso-token"})
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"code":401,"reason":"Unauthorized","message":"Login failure"}`))
+			}
+
+		case "/am/oauth2" + realmPath + "/authorize":
+			r.ParseForm()
+			cookie, err := r.Cookie("iPlanetDirectoryPro")
+			if err != nil || cookie.Value != "sso-token" || r.PostForm.Get("csrf") != "sso-token" {
+				t.Errorf("Expected session token in cookie and csrf, got %v / %s", cookie, r.PostForm.Get("csrf"))
+			}
+			http.Redirect(w, r, r.PostForm.Get("redirect_uri")+"?code=auth-code", http.StatusFound)
+
+		case "/am/oauth2" + realmPath + "/access_token":
+			r.ParseForm()
+			if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != "auth-code" || r.PostForm.Get("redirect_uri") != "https://app.example.com/callback" {
+				t.Errorf("Unexpected token request: %v", r.PostForm)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "mfa-access-token",
+				"token_type":   "Bearer",
+				"expires_in":   3599,
+			})
+
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUserTokenGenerateMFA(t *testing.T) {
+	tests := []struct {
+		name      string
+		otp       string
+		realm     string
+		tree      string
+		password  string
+		otpPrompt func(string) (string, error)
+		wantErr   string
+	}{
+		{name: "configured OTP", otp: "123456"},
+		{name: "realm", otp: "123456", realm: "root/alpha"},
+		{name: "configured OTP and tree", otp: "123456", tree: "Login2FA"},
+		{
+			name: "prompted OTP",
+			tree: "Login2FA",
+			otpPrompt: func(prompt string) (string, error) {
+				if prompt != "One Time Password" {
+					t.Errorf("Expected callback prompt, got %q", prompt)
+				}
+				return "123456\n", nil
+			},
+		},
+		{name: "missing OTP", tree: "Login2FA", wantErr: "one-time password required"},
+		{name: "wrong OTP", otp: "000000", wantErr: "authentication failed with status 401"},
+		{name: "wrong password", otp: "123456", password: "wrongpass", wantErr: "authentication failed with status 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			realmPath := ""
+			if tt.realm != "" {
+				realmPath = "/realms/" + tt.realm
+			}
+			password := tt.password
+			if password == "" {
+				password = "testpass"
+			}
+			server := newMFAServer(t, realmPath, tt.tree)
+			generator := &UserTokenGenerator{
+				Config: TokenConfig{
+					Type:        TokenTypeUser,
+					Platform:    server.URL,
+					Username:    "testuser",
+					Password:    password,
+					ClientID:    "test-client",
+					RedirectURI: "https://app.example.com/callback",
+					OTP:         tt.otp,
+					AuthTree:    tt.tree,
+					Realm:       tt.realm,
+				},
+				OTPPrompt: tt.otpPrompt,
+			}
+
+			result, err := generator.Generate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.AccessToken != "mfa-access-token" {
+				t.Errorf("Expected access token 'mfa-access-token', got %s", result.AccessToken)
+			}
+			if result.Metadata["grant_type"] != "authorization_code" {
+				t.Errorf("Expected grant_type metadata 'authorization_code', got %v", result.Metadata["grant_type"])
+			}
+		})
+	}
+}
+
+func TestAnswerCallbacks(t *testing.T) {
+	callback := func(callbackType, prompt string) AuthCallback {
+		return AuthCallback{
+			Type:   callbackType,
+			Output: []AuthCallbackValue{{Name: "prompt", Value: prompt}},
+			Input:  []AuthCallbackValue{{Name: "IDToken1"}},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		callbacks        []AuthCallback
+		passwordSent     bool
+		want             []interface{}
+		wantPasswordSent bool
+		wantErr          string
+	}{
+		{
+			name:             "username and password",
+			callbacks:        []AuthCallback{callback("NameCallback", "User Name"), callback("PasswordCallback", "Password")},
+			want:             []interface{}{"testuser", "testpass"},
+			wantPasswordSent: true,
+		},
+		{
+			name:             "code prompts in the credentials step",
+			callbacks:        []AuthCallback{callback("NameCallback", "Postal code"), callback("PasswordCallback", "Recovery code")},
+			want:             []interface{}{"testuser", "testpass"},
+			wantPasswordSent: true,
+		},
+		{
+			name:             "password and OTP in one step",
+			callbacks:        []AuthCallback{callback("PasswordCallback", "Password"), callback("PasswordCallback", "One Time Password")},
+			want:             []interface{}{"testpass", "123456"},
+			wantPasswordSent: true,
+		},
+		{
+			name:             "OTP after the password",
+			callbacks:        []AuthCallback{callback("PasswordCallback", "One Time Password")},
+			passwordSent:     true,
+			want:             []interface{}{"123456"},
+			wantPasswordSent: true,
+		},
+		{
+			name:      "text input OTP",
+			callbacks: []AuthCallback{callback("TextInputCallback", "Enter verification code")},
+			want:      []interface{}{"123456"},
+		},
+		{
+			name:      "text output skipped",
+			callbacks: []AuthCallback{{Type: "TextOutputCallback", Output: []AuthCallbackValue{{Name: "message", Value: "Check your phone"}}}, callback("NameCallback", "User Name")},
+			want:      []interface{}{nil, "testuser"},
+		},
+		{
+			name:      "unsupported callback",
+			callbacks: []AuthCallback{callback("ChoiceCallback", "Pick one")},
+			wantErr:   "unsupported authentication callback",
+		},
+		{
+			name:      "text input without OTP prompt",
+			callbacks: []AuthCallback{callback("TextInputCallback", "Favourite colour")},
+			wantErr:   "unsupported authentication callback",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &UserTokenGenerator{Config: TokenConfig{Username: "testuser", Password: "testpass", OTP: "123456"}}
+
+			passwordSent, err := generator.answerCallbacks(tt.callbacks, tt.passwordSent)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, expected := range tt.want {
+				var got interface{}
+				if len(tt.callbacks[i].Input) > 0 {
+					got = tt.callbacks[i].Input[0].Value
+				}
+				if got != expected {
+					t.Errorf("Expected callback %d input %v, got %v", i, expected, got)
+				}
+			}
+			if passwordSent != tt.wantPasswordSent {
+				t.Errorf("Expected password sent %v, got %v", tt.wantPasswordSent, passwordSent)
+			}
+		})
+	}
+}
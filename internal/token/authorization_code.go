@@ -0,0 +1,246 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+const (
+	// defaultCallbackPath is the redirect URI path used when redirect_uri is not set
+	defaultCallbackPath = "/callback"
+	// authorizationCodeTimeout bounds how long the callback listener waits for the user to log in
+	authorizationCodeTimeout = 5 * time.Minute
+)
+
+// AuthorizationCodeGenerator handles interactive user token generation using
+// the OAuth 2.0 authorization code grant with PKCE
+type AuthorizationCodeGenerator struct {
+	Config  TokenConfig
+	Verbose bool
+	Logger  *slog.Logger // Optional; defaults to debug output on stderr when Verbose
+
+	// OpenBrowser opens the authorization URL for the user. When nil, or when
+	// Config.NoBrowser is set, the URL is written to Out instead.
+	OpenBrowser func(authorizationURL string) error
+	Out         io.Writer // Optional; defaults to stderr
+}
+
+// callbackResult is the outcome of the authorization redirect to the local listener
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// Generate generates a user token using the OAuth 2.0 authorization code grant with PKCE
+func (g *AuthorizationCodeGenerator) Generate() (*TokenResult, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext generates a user token using the OAuth 2.0 authorization code grant with PKCE,
+// waiting for the user to log in until ctx is done
+func (g *AuthorizationCodeGenerator) GenerateContext(ctx context.Context) (*TokenResult, error) {
+	log := g.log()
+	log.Debug("generating authorization code token", "client_id", g.Config.ClientID)
+
+	// RFC 7636: the verifier stays local, the S256 challenge goes in the authorization request
+	verifier, err := randomURLString(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PKCE verifier: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	state, err := randomURLString(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	// Listen for the authorization redirect on the loopback interface
+	listener, redirectURI, err := listenForCallback(g.Config.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+	results := make(chan callbackResult, 1)
+	server := &http.Server{Handler: callbackHandler(redirectURI.Path, state, results)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorizationURL := oauth2EndpointURL(g.Config, "authorize") + "?" + url.Values{
+		"response_type":         {"code"},
+		"client_id":             {g.Config.ClientID},
+		"redirect_uri":          {redirectURI.String()},
+		"scope":                 {requestedScope(g.Config)},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode()
+
+	log.Debug("waiting for authorization", "redirect_uri", redirectURI.String())
+	if err := g.openAuthorizationURL(authorizationURL); err != nil {
+		return nil, err
+	}
+
+	// Wait for the user to complete login in the browser
+	ctx, cancel := context.WithTimeout(ctx, authorizationCodeTimeout)
+	defer cancel()
+	var code string
+	select {
+	case result := <-results:
+		if result.err != nil {
+			return nil, result.err
+		}
+		code = result.code
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out waiting for authorization: %w", ctx.Err())
+	}
+
+	// Exchange the authorization code and verifier for tokens
+	data := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI.String()},
+		"code_verifier": {verifier},
+	}
+	header := addClientCredentials(data, g.Config)
+
+	tokenResponse, err := requestToken(ctx, g.Config, tokenEndpointURL(g.Config), data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code for token: %w", err)
+	}
+
+	// Build result
+	result := newTokenResult(g.Config, tokenResponse, map[string]interface{}{
+		"client_id":  g.Config.ClientID,
+		"grant_type": "authorization_code",
+	})
+
+	log.Debug("authorization code token generated", "expires_at", result.ExpiresAt)
+
+	return result, nil
+}
+
+// openAuthorizationURL opens the authorization URL in the browser, or prints
+// it when no browser is available or browser launching is disabled
+func (g *AuthorizationCodeGenerator) openAuthorizationURL(authorizationURL string) error {
+	if g.OpenBrowser != nil && !g.Config.NoBrowser {
+		err := g.OpenBrowser(authorizationURL)
+		if err == nil {
+			return nil
+		}
+		g.log().Warn("failed to open browser; open the URL manually", "error", err)
+	}
+
+	out := g.Out
+	if out == nil {
+		out = os.Stderr
+	}
+	if _, err := fmt.Fprintf(out, "Open this URL in a browser to log in:\n\n%s\n\n", authorizationURL); err != nil {
+		return fmt.Errorf("failed to print authorization URL: %w", err)
+	}
+	return nil
+}
+
+// listenForCallback listens on the redirect URI's loopback address, or on a
+// random loopback port when no redirect URI is configured
+func listenForCallback(redirectURI string) (net.Listener, *url.URL, error) {
+	if redirectURI == "" {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start callback listener: %w", err)
+		}
+		return listener, &url.URL{Scheme: "http", Host: listener.Addr().String(), Path: defaultCallbackPath}, nil
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid redirect_uri %q: %w", redirectURI, err)
+	}
+	if !IsLoopbackRedirectURI(u) {
+		return nil, nil, fmt.Errorf("invalid redirect_uri %q: must be an http loopback URL with a port", redirectURI)
+	}
+	listener, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start callback listener: %w", err)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return listener, u, nil
+}
+
+// IsLoopbackRedirectURI reports whether u is an http URL on a loopback host
+// with an explicit port, which pctl can listen on for the authorization redirect
+func IsLoopbackRedirectURI(u *url.URL) bool {
+	if u.Scheme != "http" || u.Port() == "" {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// callbackHandler receives the authorization redirect, checks the state and
+// reports the authorization code or error once
+func callbackHandler(path, state string, results chan<- callbackResult) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		var result callbackResult
+		switch {
+		case query.Get("state") != state:
+			result.err = errors.New("authorization response has an unexpected state")
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s: %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("code") == "":
+			result.err = errors.New("authorization response has no code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(w, "Login failed: "+result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login complete. You can close this window.")
+		}
+
+		select {
+		case results <- result:
+		default:
+			// A result was already reported; ignore repeated redirects
+		}
+	})
+}
+
+// randomURLString returns n random bytes encoded as unpadded base64url
+func randomURLString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// log returns the configured logger, falling back to the default for the verbosity
+func (g *AuthorizationCodeGenerator) log() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return logger.Default(g.Verbose)
+}
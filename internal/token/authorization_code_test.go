@@ -0,0 +1,166 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newAuthorizationServer returns a fake PAIC that approves every authorization
+// request and checks the PKCE verifier on the code exchange
+func newAuthorizationServer(t *testing.T, denied bool) *httptest.Server {
+	t.Helper()
+	var challenge string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/am/oauth2/authorize":
+			query := r.URL.Query()
+			if got := query.Get("code_challenge_method"); got != "S256" {
+				t.Errorf("Expected code_challenge_method S256, got %q", got)
+			}
+			challenge = query.Get("code_challenge")
+			redirect, err := url.Parse(query.Get("redirect_uri"))
+			if err != nil {
+				t.Fatalf("Invalid redirect_uri: %v", err)
+			}
+			params := url.Values{"state": {query.Get("state")}}
+			if denied {
+				params.Set("error", "access_denied")
+				params.Set("error_description", "user cancelled")
+			} else {
+				params.Set("code", "test-code")
+			}
+			redirect.RawQuery = params.Encode()
+			http.Redirect(w, r, redirect.String(), http.StatusFound)
+		case "/am/oauth2/access_token":
+			if err := r.ParseForm(); err != nil {
+				t.Fatalf("Failed to parse form: %v", err)
+			}
+			if got := r.PostForm.Get("code"); got != "test-code" {
+				t.Errorf("Expected code 'test-code', got %q", got)
+			}
+			verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if got := base64.RawURLEncoding.EncodeToString(verifier[:]); got != challenge {
+				t.Errorf("Expected code_verifier to match challenge %q, got %q", challenge, got)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "auth-code-token",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// followAuthorization plays the browser: it follows the authorization URL
+// through the redirect to the callback listener
+func followAuthorization(t *testing.T) func(string) error {
+	return func(authorizationURL string) error {
+		go func() {
+			resp, err := http.Get(authorizationURL)
+			if err != nil {
+				t.Errorf("Authorization request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+}
+
+func TestAuthorizationCodeGenerate(t *testing.T) {
+	server := newAuthorizationServer(t, false)
+
+	generator := &AuthorizationCodeGenerator{
+		Config: TokenConfig{
+			Type:     TokenTypeAuthorizationCode,
+			Platform: server.URL,
+			ClientID: "test-client",
+			Scope:    "openid",
+		},
+		OpenBrowser: followAuthorization(t),
+	}
+
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.AccessToken != "auth-code-token" {
+		t.Errorf("Expected access token 'auth-code-token', got %s", result.AccessToken)
+	}
+	if result.Metadata["grant_type"] != "authorization_code" {
+		t.Errorf("Expected grant_type metadata 'authorization_code', got %v", result.Metadata["grant_type"])
+	}
+}
+
+func TestAuthorizationCodeDenied(t *testing.T) {
+	server := newAuthorizationServer(t, true)
+
+	generator := &AuthorizationCodeGenerator{
+		Config:      TokenConfig{Platform: server.URL, ClientID: "test-client"},
+		OpenBrowser: followAuthorization(t),
+	}
+
+	_, err := generator.Generate()
+	if err == nil || !strings.Contains(err.Error(), "authorization denied: access_denied") {
+		t.Errorf("Expected authorization denied error, got %v", err)
+	}
+}
+
+func TestAuthorizationCodeNoBrowser(t *testing.T) {
+	var out bytes.Buffer
+	generator := &AuthorizationCodeGenerator{
+		Config: TokenConfig{Platform: "https://test.forgerock.com", ClientID: "test-client", NoBrowser: true},
+		OpenBrowser: func(string) error {
+			t.Error("Expected browser not to be opened")
+			return nil
+		},
+		Out: &out,
+	}
+
+	// The login is never completed, so the generator gives up when ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := generator.GenerateContext(ctx); err == nil {
+		t.Error("Expected error when the login is not completed")
+	}
+	if !strings.Contains(out.String(), "https://test.forgerock.com/am/oauth2/authorize?") {
+		t.Errorf("Expected authorization URL to be printed, got %q", out.String())
+	}
+}
+
+func TestIsLoopbackRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"http://127.0.0.1:8085/callback", true},
+		{"http://localhost:8085/", true},
+		{"http://[::1]:8085/callback", true},
+		{"http://127.0.0.1/callback", false},
+		{"https://127.0.0.1:8085/callback", false},
+		{"http://example.com:8085/callback", false},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.uri)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.uri, err)
+		}
+		if got := IsLoopbackRedirectURI(u); got != tt.want {
+			t.Errorf("IsLoopbackRedirectURI(%q) = %v, want %v", tt.uri, got, tt.want)
+		}
+	}
+}
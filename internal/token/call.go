@@ -0,0 +1,80 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// CallResult holds the response to an API request authorized with an access token
+type CallResult struct {
+	StatusCode int         `json:"status_code" yaml:"status_code"`
+	Status     string      `json:"status" yaml:"status"`
+	Header     http.Header `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       string      `json:"body" yaml:"body"`
+}
+
+// idempotentMethods may be retried without repeating a side effect
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// Call sends the request to the API URL with the access token as a bearer
+// Authorization header. A body that is valid JSON is sent as application/json,
+// any other body as form data. Only idempotent methods are retried.
+func Call(ctx context.Context, config TokenConfig, method, apiURL string, body []byte, accessToken string, log *slog.Logger) (*CallResult, error) {
+	log = logger.OrDiscard(log)
+	log.Debug("making API request", "method", method, "url", apiURL)
+
+	config = callConfig(config)
+
+	if !idempotentMethods[method] {
+		config.Retries = -1 // Retrying could repeat the side effect
+	}
+
+	header := http.Header{
+		"Authorization": {"Bearer " + accessToken},
+	}
+	if len(body) > 0 {
+		if json.Valid(body) {
+			header.Set("Content-Type", "application/json")
+		} else {
+			header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	resp, respBody, err := send(ctx, config, method, apiURL, body, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+
+	log.Debug("API response received", "status", resp.StatusCode)
+
+	return &CallResult{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       string(respBody),
+	}, nil
+}
+
+// callConfig keeps only the TLS verification, timeout, retry and trace
+// settings for an API request. The headers, client certificate, proxy and
+// User-Agent configured for PAIC are not sent to the API, which may be any host.
+func callConfig(config TokenConfig) TokenConfig {
+	return TokenConfig{
+		VerifySSL:           config.VerifySSL,
+		TimeoutSeconds:      config.TimeoutSeconds,
+		Retries:             config.Retries,
+		RetryMaxWaitSeconds: config.RetryMaxWaitSeconds,
+		Trace:               config.Trace,
+	}
+}
@@ -0,0 +1,55 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clientAssertionType is the RFC 7523 client_assertion_type for JWT client authentication
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// addClientAssertion adds a private_key_jwt client authentication assertion,
+// signed with the configured JWK, PEM or JWKS key, to the form data
+func addClientAssertion(ctx context.Context, data url.Values, config TokenConfig, audience string, log *slog.Logger) error {
+	generator := &ServiceAccountGenerator{Config: config, Logger: log}
+	privateKey, signingMethod, err := generator.signingKey(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeyParse, err)
+	}
+
+	jtiBytes := make([]byte, 16)
+	if _, err := rand.Read(jtiBytes); err != nil {
+		return fmt.Errorf("failed to generate JWT ID: %w", err)
+	}
+
+	// RFC 7523 section 3: the client is both issuer and subject, and the
+	// token endpoint is the audience
+	now := time.Now().Add(config.ClockSkew())
+	assertion := jwt.NewWithClaims(signingMethod, jwt.MapClaims{
+		"iss": config.ClientID,
+		"sub": config.ClientID,
+		"aud": audience,
+		"iat": now.Unix(),
+		"exp": now.Add(min(config.AssertionExp(), config.MaxAssertionExp())).Unix(),
+		"jti": base64.RawURLEncoding.EncodeToString(jtiBytes),
+	})
+	if config.KeyID != "" {
+		assertion.Header["kid"] = config.KeyID
+	}
+
+	signed, err := assertion.SignedString(privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign client assertion: %w", err)
+	}
+
+	data.Set("client_assertion_type", clientAssertionType)
+	data.Set("client_assertion", signed)
+	return nil
+}
@@ -1,49 +1,73 @@
 package token
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"log/slog"
+	"net/url"
+
+	"github.com/aaronwang/pctl/internal/logger"
 )
 
 // CustomTokenGenerator handles custom token generation
 type CustomTokenGenerator struct {
 	Config  TokenConfig
 	Verbose bool
+	Logger  *slog.Logger // Optional; defaults to debug output on stderr when Verbose
 }
 
-// Generate generates a custom token with specified claims
+// Generate generates a custom token using the OAuth 2.0 client credentials grant
 func (g *CustomTokenGenerator) Generate() (*TokenResult, error) {
-	if g.Verbose {
-		fmt.Printf("Generating custom token for client: %s\n", g.Config.ClientID)
-	}
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext generates a custom token using the OAuth 2.0 client credentials grant, aborting the request when ctx is done
+func (g *CustomTokenGenerator) GenerateContext(ctx context.Context) (*TokenResult, error) {
+	log := g.log()
+	log.Debug("generating custom token", "client_id", g.Config.ClientID)
 
-	// TODO: Implement actual custom token generation
-	// This would involve:
-	// 1. Making an OAuth 2.0 client credentials request to PAIC
-	// 2. Including custom claims in the request
-	// 3. Parsing the response and returning the token
-
-	// For now, return a mock token for testing
-	now := time.Now()
-	expiresIn := int64(g.Config.ExpiresIn.Seconds())
-	
-	result := &TokenResult{
-		AccessToken: "mock_custom_token_" + g.Config.ClientID,
-		TokenType:   "Bearer",
-		ExpiresIn:   expiresIn,
-		ExpiresAt:   now.Add(g.Config.ExpiresIn),
-		Scope:       "custom_scope",
-		Metadata: map[string]interface{}{
-			"client_id":      g.Config.ClientID,
-			"generated_at":   now.Unix(),
-			"grant_type":     "client_credentials",
-			"custom_claims":  g.Config.CustomClaims,
-		},
+	// Build token endpoint URL
+	tokenURL := tokenEndpointURL(g.Config)
+
+	// Prepare form data, sending custom claims as additional parameters
+	data := url.Values{}
+	for name, value := range g.Config.CustomClaims {
+		data.Set(name, fmt.Sprint(value))
+	}
+	data.Set("grant_type", "client_credentials")
+	header := addClientCredentials(data, g.Config)
+	if g.Config.ClientAuthMethod() == ClientAuthPrivateKeyJWT {
+		if err := addClientAssertion(ctx, data, g.Config, tokenURL, log); err != nil {
+			return nil, fmt.Errorf("failed to create client assertion: %w", err)
+		}
 	}
+	data.Set("scope", requestedScope(g.Config))
+	addResources(data, g.Config)
+
+	log.Debug("making token request", "url", tokenURL, "grant_type", "client_credentials", "scope", requestedScope(g.Config))
 
-	if g.Verbose {
-		fmt.Printf("Custom token generated successfully, expires at: %s\n", result.ExpiresAt.Format(time.RFC3339))
+	// Exchange client credentials for access token
+	tokenResponse, err := requestToken(ctx, g.Config, tokenURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange client credentials for token: %w", err)
 	}
 
+	// Build result
+	result := newTokenResult(g.Config, tokenResponse, map[string]interface{}{
+		"client_id":     g.Config.ClientID,
+		"grant_type":    "client_credentials",
+		"custom_claims": g.Config.CustomClaims,
+	})
+
+	log.Debug("custom token generated", "expires_at", result.ExpiresAt)
+
 	return result, nil
-}
\ No newline at end of file
+}
+
+// log returns the configured logger, falling back to the default for the verbosity
+func (g *CustomTokenGenerator) log() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return logger.Default(g.Verbose)
+}
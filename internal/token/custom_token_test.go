@@ -0,0 +1,303 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCustomTokenGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			t.Errorf("Expected grant_type 'client_credentials', got %s", r.PostForm.Get("grant_type"))
+		}
+		if r.PostForm.Get("client_id") != "test-client" || r.PostForm.Get("client_secret") != "test-secret" {
+			t.Errorf("Unexpected client credentials: %s/%s", r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"))
+		}
+		if r.PostForm.Get("tenant") != "alpha" {
+			t.Errorf("Expected custom claim 'tenant' to be sent, got %q", r.PostForm.Get("tenant"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "custom-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   600,
+			"scope":        "fr:idm:*",
+		})
+	}))
+	defer server.Close()
+
+	generator := &CustomTokenGenerator{
+		Config: TokenConfig{
+			Type:         TokenTypeCustom,
+			BaseURL:      server.URL,
+			ClientID:     "test-client",
+			ClientSecret: "test-secret",
+			Scope:        "fr:idm:*",
+			CustomClaims: map[string]interface{}{"tenant": "alpha"},
+		},
+	}
+
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.AccessToken != "custom-access-token" {
+		t.Errorf("Expected access token 'custom-access-token', got %s", result.AccessToken)
+	}
+	if result.ExpiresIn != 600 {
+		t.Errorf("Expected expires_in 600, got %d", result.ExpiresIn)
+	}
+}
+
+func TestCustomTokenGenerateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+
+	generator := &CustomTokenGenerator{
+		Config: TokenConfig{
+			Type:         TokenTypeCustom,
+			BaseURL:      server.URL,
+			ClientID:     "test-client",
+			ClientSecret: "wrong",
+		},
+	}
+
+	_, err := generator.Generate()
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Expected error to include server body, got: %v", err)
+	}
+}
+
+func TestCustomTokenClientAuthMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		authMethod string
+		clientID   string
+		wantBasic  bool
+	}{
+		{name: "defaults to client_secret_post", clientID: "test-client"},
+		{name: "client_secret_post", authMethod: ClientAuthSecretPost, clientID: "test-client"},
+		{name: "client_secret_basic", authMethod: ClientAuthSecretBasic, clientID: "test-client", wantBasic: true},
+		{name: "client_secret_basic encodes credentials", authMethod: ClientAuthSecretBasic, clientID: "test client:1", wantBasic: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("Failed to parse form: %v", err)
+				}
+				clientID, clientSecret, basic := r.BasicAuth()
+				if basic != tt.wantBasic {
+					t.Errorf("Expected basic auth %t, got %t", tt.wantBasic, basic)
+				}
+				if basic {
+					// Basic credentials are form-encoded before base64
+					clientID, _ = url.QueryUnescape(clientID)
+					if r.PostForm.Has("client_secret") {
+						t.Error("Expected client_secret to be omitted from the form body")
+					}
+				} else {
+					clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+				}
+				if clientID != tt.clientID || clientSecret != "test-secret" {
+					t.Errorf("Unexpected client credentials: %s/%s", clientID, clientSecret)
+				}
+
+				json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "custom-access-token", "token_type": "Bearer"})
+			}))
+			defer server.Close()
+
+			generator := &CustomTokenGenerator{
+				Config: TokenConfig{
+					Type:                    TokenTypeCustom,
+					BaseURL:                 server.URL,
+					ClientID:                tt.clientID,
+					ClientSecret:            "test-secret",
+					TokenEndpointAuthMethod: tt.authMethod,
+				},
+			}
+			if _, err := generator.Generate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCustomTokenFingerprint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "custom-access-token", "token_type": "Bearer"})
+	}))
+	defer server.Close()
+
+	for _, fingerprint := range []bool{false, true} {
+		generator := &CustomTokenGenerator{
+			Config: TokenConfig{
+				Type:         TokenTypeCustom,
+				BaseURL:      server.URL,
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+				Fingerprint:  fingerprint,
+			},
+		}
+
+		result, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		got, ok := result.Metadata["fingerprint"]
+		if ok != fingerprint {
+			t.Errorf("Expected fingerprint metadata present %t, got %v", fingerprint, result.Metadata)
+		}
+		// sha256("custom-access-token")
+		if fingerprint && got != "272d6a08116a88edc633f2ad7412a276ef01942b6102be85f67c2fddcdb426ac" {
+			t.Errorf("Unexpected fingerprint %v", got)
+		}
+	}
+}
+
+func TestCustomTokenPrivateKeyJWT(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	var tokenURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		if r.PostForm.Has("client_secret") {
+			t.Errorf("Expected no client_secret, got %q", r.PostForm.Get("client_secret"))
+		}
+		if got := r.PostForm.Get("client_id"); got != "test-client" {
+			t.Errorf("Expected client_id 'test-client', got %q", got)
+		}
+		if got := r.PostForm.Get("client_assertion_type"); got != clientAssertionType {
+			t.Errorf("Expected client_assertion_type %q, got %q", clientAssertionType, got)
+		}
+
+		claims := jwt.MapClaims{}
+		assertion, err := jwt.ParseWithClaims(r.PostForm.Get("client_assertion"), claims, func(*jwt.Token) (interface{}, error) {
+			return &privateKey.PublicKey, nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to verify client assertion: %v", err)
+		}
+		if assertion.Header["kid"] != "client-key" {
+			t.Errorf("Expected kid 'client-key', got %v", assertion.Header["kid"])
+		}
+		if claims["iss"] != "test-client" || claims["sub"] != "test-client" {
+			t.Errorf("Expected iss and sub 'test-client', got %v and %v", claims["iss"], claims["sub"])
+		}
+		if claims["aud"] != tokenURL {
+			t.Errorf("Expected aud %q, got %v", tokenURL, claims["aud"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"custom-access-token","token_type":"Bearer","expires_in":600}`))
+	}))
+	defer server.Close()
+
+	config := TokenConfig{
+		Type:                    TokenTypeCustom,
+		BaseURL:                 server.URL,
+		ClientID:                "test-client",
+		ClientSecret:            "unused-secret",
+		TokenEndpointAuthMethod: ClientAuthPrivateKeyJWT,
+		PrivateKey:              string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+		KeyID:                   "client-key",
+	}
+	tokenURL = tokenEndpointURL(config)
+
+	result, err := (&CustomTokenGenerator{Config: config}).Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.AccessToken != "custom-access-token" {
+		t.Errorf("Expected access token 'custom-access-token', got %s", result.AccessToken)
+	}
+
+	// A key that cannot be parsed fails before any request is made
+	config.PrivateKey = "not a key"
+	_, err = (&CustomTokenGenerator{Config: config}).Generate()
+	if !errors.Is(err, ErrKeyParse) {
+		t.Errorf("Expected ErrKeyParse, got %v", err)
+	}
+}
+
+func TestCustomTokenResource(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []string
+	}{
+		{name: "no resource", resources: nil},
+		{name: "single resource", resources: []string{"https://api.example.com"}},
+		{name: "multiple resources", resources: []string{"https://api.example.com", "https://other.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("Failed to parse form: %v", err)
+				}
+				got := r.PostForm["resource"]
+				if len(got) != len(tt.resources) {
+					t.Fatalf("Expected resources %v, got %v", tt.resources, got)
+				}
+				for i := range got {
+					if got[i] != tt.resources[i] {
+						t.Errorf("Expected resources %v, got %v", tt.resources, got)
+					}
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "custom-access-token", "token_type": "Bearer"})
+			}))
+			defer server.Close()
+
+			generator := &CustomTokenGenerator{
+				Config: TokenConfig{
+					Type:         TokenTypeCustom,
+					BaseURL:      server.URL,
+					ClientID:     "test-client",
+					ClientSecret: "test-secret",
+					Resource:     tt.resources,
+				},
+			}
+			if _, err := generator.Generate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package token
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DecodedToken holds the header and claims of a JWT access token, decoded
+// without verifying the signature
+type DecodedToken struct {
+	Header map[string]interface{} `json:"header,omitempty" yaml:"header,omitempty"`
+	Claims map[string]interface{} `json:"claims,omitempty" yaml:"claims,omitempty"`
+	Note   string                 `json:"note,omitempty" yaml:"note,omitempty"` // Why the token was not decoded, e.g. it is opaque
+}
+
+// DecodeJWT decodes the header and claims of a JWT without verifying it.
+// Opaque tokens are not an error; the result notes that they were skipped.
+func DecodeJWT(tokenString string) *DecodedToken {
+	claims := jwt.MapClaims{}
+	parsed, _, err := jwt.NewParser(jwt.WithJSONNumber()).ParseUnverified(tokenString, claims)
+	if err != nil {
+		return &DecodedToken{Note: "access token is not a JWT; decoding skipped"}
+	}
+	return &DecodedToken{Header: parsed.Header, Claims: claims}
+}
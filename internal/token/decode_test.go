@@ -0,0 +1,38 @@
+package token
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestDecodeJWT(t *testing.T) {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "test-subject",
+		"scope": "fr:idm:*",
+		"exp":   1700000000,
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	decoded := DecodeJWT(signed)
+	if decoded.Note != "" {
+		t.Fatalf("Expected JWT to be decoded, got note %q", decoded.Note)
+	}
+	if decoded.Header["alg"] != "HS256" {
+		t.Errorf("Expected alg HS256, got %v", decoded.Header["alg"])
+	}
+	if decoded.Claims["sub"] != "test-subject" {
+		t.Errorf("Expected sub 'test-subject', got %v", decoded.Claims["sub"])
+	}
+	if exp, ok := decoded.Claims["exp"].(json.Number); !ok || exp.String() != "1700000000" {
+		t.Errorf("Expected exp 1700000000 as a JSON number, got %#v", decoded.Claims["exp"])
+	}
+
+	opaque := DecodeJWT("opaque-access-token")
+	if opaque.Note == "" || opaque.Claims != nil {
+		t.Errorf("Expected opaque token to be skipped with a note, got %+v", opaque)
+	}
+}
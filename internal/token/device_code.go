@@ -0,0 +1,220 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// deviceCodeGrantType is the RFC 8628 device authorization grant type
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// deviceCodeDefaultInterval is the polling interval used when the device
+	// authorization response has none, as RFC 8628 section 3.2 specifies
+	deviceCodeDefaultInterval = 5 * time.Second
+	// deviceCodeSlowDown is added to the polling interval on each slow_down error
+	deviceCodeSlowDown = 5 * time.Second
+)
+
+// DeviceCodeGenerator handles user token generation on devices without a
+// browser using the OAuth 2.0 device authorization grant (RFC 8628)
+type DeviceCodeGenerator struct {
+	Config  TokenConfig
+	Verbose bool
+	Logger  *slog.Logger // Optional; defaults to debug output on stderr when Verbose
+	Out     io.Writer    // Optional; where the verification URL and user code are printed, defaults to stderr
+}
+
+// deviceAuthorizationResponse is the PAIC response to a device authorization request
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURL         string `json:"verification_url"` // Name used by drafts of RFC 8628
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// Generate generates a user token using the OAuth 2.0 device authorization grant
+func (g *DeviceCodeGenerator) Generate() (*TokenResult, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext generates a user token using the OAuth 2.0 device authorization grant,
+// polling the token endpoint until the user authorizes the device, the device code
+// expires or ctx is done
+func (g *DeviceCodeGenerator) GenerateContext(ctx context.Context) (*TokenResult, error) {
+	log := g.log()
+	log.Debug("generating device code token", "client_id", g.Config.ClientID)
+
+	authorization, err := g.requestDeviceCode(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.printUserCode(authorization); err != nil {
+		return nil, err
+	}
+
+	// Poll until the device code expires
+	if authorization.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(authorization.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	tokenResponse, err := g.pollForToken(ctx, authorization, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build result
+	result := newTokenResult(g.Config, tokenResponse, map[string]interface{}{
+		"client_id":  g.Config.ClientID,
+		"grant_type": deviceCodeGrantType,
+	})
+
+	log.Debug("device code token generated", "expires_at", result.ExpiresAt)
+
+	return result, nil
+}
+
+// requestDeviceCode requests a device code and user code from the PAIC device authorization endpoint
+func (g *DeviceCodeGenerator) requestDeviceCode(ctx context.Context, log *slog.Logger) (*deviceAuthorizationResponse, error) {
+	deviceURL := oauth2EndpointURL(g.Config, "device/code")
+
+	data := url.Values{}
+	if scope := requestedScope(g.Config); scope != "" {
+		data.Set("scope", scope)
+	}
+	header := addClientCredentials(data, g.Config)
+
+	log.Debug("making device authorization request", "url", deviceURL)
+
+	resp, body, err := postForm(ctx, g.Config, deviceURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device authorization request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var authorization deviceAuthorizationResponse
+	if err := json.Unmarshal(body, &authorization); err != nil {
+		return nil, fmt.Errorf("failed to parse device authorization response: %w", err)
+	}
+	if authorization.VerificationURI == "" {
+		authorization.VerificationURI = authorization.VerificationURL
+	}
+	if authorization.DeviceCode == "" || authorization.UserCode == "" || authorization.VerificationURI == "" {
+		return nil, fmt.Errorf("device authorization response is missing device_code, user_code or verification_uri")
+	}
+	return &authorization, nil
+}
+
+// printUserCode tells the user where to enter the user code
+func (g *DeviceCodeGenerator) printUserCode(authorization *deviceAuthorizationResponse) error {
+	out := g.Out
+	if out == nil {
+		out = os.Stderr
+	}
+
+	var err error
+	if authorization.VerificationURIComplete != "" {
+		_, err = fmt.Fprintf(out, "To log in, open this URL on any device:\n\n%s\n\nor open %s and enter the code %s\n\n",
+			authorization.VerificationURIComplete, authorization.VerificationURI, authorization.UserCode)
+	} else {
+		_, err = fmt.Fprintf(out, "To log in, open this URL on any device:\n\n%s\n\nand enter the code %s\n\n",
+			authorization.VerificationURI, authorization.UserCode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to print verification URL: %w", err)
+	}
+	return nil
+}
+
+// pollForToken polls the token endpoint with the device code at the interval
+// PAIC asks for until the user authorizes or denies the device
+func (g *DeviceCodeGenerator) pollForToken(ctx context.Context, authorization *deviceAuthorizationResponse, log *slog.Logger) (*PaicTokenResponse, error) {
+	tokenURL := tokenEndpointURL(g.Config)
+	interval := deviceCodeDefaultInterval
+	if authorization.Interval > 0 {
+		interval = time.Duration(authorization.Interval) * time.Second
+	}
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("device code expired before the device was authorized")
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		data := url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {authorization.DeviceCode},
+		}
+		header := addClientCredentials(data, g.Config)
+
+		tokenResponse, err := requestToken(ctx, g.Config, tokenURL, data, header, log)
+		if err == nil {
+			return tokenResponse, nil
+		}
+		// The device code may expire while the request is in flight
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("device code expired before the device was authorized")
+		}
+
+		// RFC 8628 section 3.5: keep polling while authorization is pending
+		switch deviceCodeError(err) {
+		case "authorization_pending":
+			log.Debug("waiting for device authorization", "interval", interval)
+		case "slow_down":
+			interval += deviceCodeSlowDown
+			log.Debug("slowing down device code polling", "interval", interval)
+		case "access_denied":
+			return nil, fmt.Errorf("device authorization denied: %w", err)
+		case "expired_token":
+			return nil, fmt.Errorf("device code expired before the device was authorized: %w", err)
+		default:
+			return nil, fmt.Errorf("failed to exchange device code for token: %w", err)
+		}
+	}
+}
+
+// deviceCodeError returns the OAuth 2.0 error code of a token endpoint
+// rejection, or "" when err is not one
+func deviceCodeError(err error) string {
+	var endpointErr *TokenEndpointError
+	if !errors.As(err, &endpointErr) {
+		return ""
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal([]byte(endpointErr.Body), &body) != nil {
+		return ""
+	}
+	return body.Error
+}
+
+// log returns the configured logger, falling back to the default for the verbosity
+func (g *DeviceCodeGenerator) log() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return logger.Default(g.Verbose)
+}
@@ -0,0 +1,136 @@
+package token
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeviceCodeGenerator(t *testing.T) {
+	deviceCodeDefaultInterval = time.Millisecond
+	deviceCodeSlowDown = time.Millisecond
+	defer func() {
+		deviceCodeDefaultInterval = 5 * time.Second
+		deviceCodeSlowDown = 5 * time.Second
+	}()
+
+	tests := []struct {
+		name      string
+		responses []string // OAuth error codes returned before the token, "" for the token
+		wantPolls int32
+		wantErr   string
+	}{
+		{name: "authorized immediately", responses: []string{""}, wantPolls: 1},
+		{name: "pending then authorized", responses: []string{"authorization_pending", "authorization_pending", ""}, wantPolls: 3},
+		{name: "slow down then authorized", responses: []string{"slow_down", ""}, wantPolls: 2},
+		{name: "denied", responses: []string{"authorization_pending", "access_denied"}, wantPolls: 2, wantErr: "device authorization denied"},
+		{name: "expired", responses: []string{"expired_token"}, wantPolls: 1, wantErr: "device code expired"},
+		{name: "other error", responses: []string{"invalid_client"}, wantPolls: 1, wantErr: "failed to exchange device code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var polls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("Failed to parse form: %v", err)
+				}
+				if r.PostForm.Get("client_id") != "device-client" {
+					t.Errorf("Expected client_id 'device-client', got %s", r.PostForm.Get("client_id"))
+				}
+				w.Header().Set("Content-Type", "application/json")
+
+				switch r.URL.Path {
+				case "/am/oauth2/device/code":
+					if r.PostForm.Get("scope") != "openid profile" {
+						t.Errorf("Expected scope 'openid profile', got %s", r.PostForm.Get("scope"))
+					}
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"device_code":      "device-123",
+						"user_code":        "ABCD-EFGH",
+						"verification_uri": "https://openam.example.com/device",
+						"expires_in":       60,
+					})
+				case "/am/oauth2/access_token":
+					if r.PostForm.Get("grant_type") != deviceCodeGrantType || r.PostForm.Get("device_code") != "device-123" {
+						t.Errorf("Unexpected token request %v", r.PostForm)
+					}
+					poll := atomic.AddInt32(&polls, 1)
+					if code := tt.responses[poll-1]; code != "" {
+						w.WriteHeader(http.StatusBadRequest)
+						json.NewEncoder(w).Encode(map[string]string{"error": code})
+						return
+					}
+					w.Write([]byte(`{"access_token":"device-token","token_type":"Bearer","expires_in":3600}`))
+				default:
+					t.Errorf("Unexpected request path %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			var out bytes.Buffer
+			generator := &DeviceCodeGenerator{
+				Config: TokenConfig{
+					Platform: server.URL,
+					ClientID: "device-client",
+					Scopes:   []string{"openid", "profile"},
+				},
+				Out: &out,
+			}
+
+			result, err := generator.Generate()
+			if polls != tt.wantPolls {
+				t.Errorf("Expected %d polls, got %d", tt.wantPolls, polls)
+			}
+			if !strings.Contains(out.String(), "https://openam.example.com/device") || !strings.Contains(out.String(), "ABCD-EFGH") {
+				t.Errorf("Expected verification URL and user code in output, got %q", out.String())
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.AccessToken != "device-token" {
+				t.Errorf("Expected access token 'device-token', got %s", result.AccessToken)
+			}
+			if result.Metadata["grant_type"] != deviceCodeGrantType {
+				t.Errorf("Expected device code grant type in metadata, got %v", result.Metadata["grant_type"])
+			}
+		})
+	}
+}
+
+func TestDeviceCodeExpiresWhilePending(t *testing.T) {
+	deviceCodeDefaultInterval = 10 * time.Millisecond
+	defer func() { deviceCodeDefaultInterval = 5 * time.Second }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/am/oauth2/device/code" {
+			w.Write([]byte(`{"device_code":"device-123","user_code":"ABCD","verification_url":"https://openam.example.com/device","expires_in":1}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"authorization_pending"}`))
+	}))
+	defer server.Close()
+
+	generator := &DeviceCodeGenerator{
+		Config: TokenConfig{Platform: server.URL, ClientID: "device-client"},
+		Out:    &bytes.Buffer{},
+	}
+
+	_, err := generator.Generate()
+	if err == nil || !strings.Contains(err.Error(), "device code expired") {
+		t.Fatalf("Expected device code expiry error, got %v", err)
+	}
+}
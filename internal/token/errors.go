@@ -0,0 +1,103 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+var (
+	// ErrTokenEndpoint matches failures requesting a token from the PAIC token endpoint
+	ErrTokenEndpoint = errors.New("token request failed")
+	// ErrKeyParse matches failures loading or parsing the signing key
+	ErrKeyParse = errors.New("failed to load signing key")
+)
+
+// TokenEndpointError is returned when the token endpoint responds with a
+// non-200 status. It matches ErrTokenEndpoint. Callers can unwrap it to
+// inspect the response, for example to decide whether to retry:
+//
+//	var endpointErr *TokenEndpointError
+//	if errors.As(err, &endpointErr) && endpointErr.StatusCode >= 500 {
+//		// retry later
+//	}
+type TokenEndpointError struct {
+	StatusCode int    // HTTP status code of the response
+	Body       string // Raw response body, typically an OAuth 2.0 error object
+	URL        string // Token endpoint URL the request was sent to
+
+	ContentType string // Content-Type of the response
+}
+
+// htmlSnippetLength bounds how much of an HTML error page is included in the error
+const htmlSnippetLength = 200
+
+func (e *TokenEndpointError) Error() string {
+	if e.isHTML() {
+		return fmt.Sprintf("token endpoint returned an HTML page (status %d); the request likely did not reach PAIC: %s",
+			e.StatusCode, htmlSnippet(e.Body))
+	}
+	return fmt.Sprintf("token request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// isHTML reports whether the response is an HTML page, typically an error
+// page from a reverse proxy or load balancer in front of PAIC
+func (e *TokenEndpointError) isHTML() bool {
+	mediaType, _, _ := mime.ParseMediaType(e.ContentType)
+	return mediaType == "text/html" || strings.HasPrefix(strings.TrimSpace(e.Body), "<")
+}
+
+// htmlSnippet collapses whitespace in the page and truncates it for display
+func htmlSnippet(body string) string {
+	snippet := strings.Join(strings.Fields(body), " ")
+	if runes := []rune(snippet); len(runes) > htmlSnippetLength {
+		snippet = string(runes[:htmlSnippetLength]) + "..."
+	}
+	return snippet
+}
+
+// Is reports whether target is ErrTokenEndpoint
+func (e *TokenEndpointError) Is(target error) bool {
+	return target == ErrTokenEndpoint
+}
+
+// ClockSkewError is returned when PAIC rejects a service account assertion as
+// expired or not yet valid, which usually means the local clock is wrong.
+// It unwraps to the underlying TokenEndpointError.
+type ClockSkewError struct {
+	Err *TokenEndpointError
+}
+
+func (e *ClockSkewError) Error() string {
+	return e.Err.Error() + " (the assertion was rejected as expired or not yet valid; check that the system clock is correct or set clock_skew_seconds)"
+}
+
+func (e *ClockSkewError) Unwrap() error {
+	return e.Err
+}
+
+// clockSkewError wraps a token endpoint rejection of the assertion's time claims in a ClockSkewError
+func clockSkewError(err error) error {
+	var endpointErr *TokenEndpointError
+	if !errors.As(err, &endpointErr) || endpointErr.StatusCode < 400 || endpointErr.StatusCode >= 500 {
+		return err
+	}
+
+	var body struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal([]byte(endpointErr.Body), &body) != nil {
+		return err
+	}
+
+	description := strings.ToLower(body.ErrorDescription)
+	for _, hint := range []string{"expired", "not yet valid", "not before", "in the future", "clock"} {
+		if strings.Contains(description, hint) {
+			return &ClockSkewError{Err: endpointErr}
+		}
+	}
+	return err
+}
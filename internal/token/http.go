@@ -0,0 +1,392 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/logger"
+	"github.com/aaronwang/pctl/internal/version"
+)
+
+// oauth2Path is the PAIC OAuth 2.0 endpoint prefix relative to the platform URL
+const oauth2Path = "/am/oauth2"
+
+// reservedHeaders are set by pctl and cannot be replaced by configured headers
+var reservedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Content-Length":      true,
+	"Content-Type":        true,
+	"Cookie":              true,
+	"Host":                true,
+	"Proxy-Authorization": true, // Set from the proxy URL userinfo
+	"User-Agent":          true, // Use user_agent instead
+}
+
+// PaicTokenResponse represents the response from PAIC token endpoint
+type PaicTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+
+	IssuedTokenType string `json:"issued_token_type,omitempty"` // RFC 8693 type of a token exchange result
+
+	// Extra holds response fields not declared above, such as PAIC extensions
+	Extra map[string]interface{} `json:"-"`
+}
+
+// unknownFields decodes the fields of a token response body that PaicTokenResponse does not declare
+func unknownFields(body []byte) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	responseType := reflect.TypeOf(PaicTokenResponse{})
+	for i := 0; i < responseType.NumField(); i++ {
+		name, _, _ := strings.Cut(responseType.Field(i).Tag.Get("json"), ",")
+		delete(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// platformURL returns the configured platform URL without a trailing slash
+func platformURL(config TokenConfig) string {
+	baseURL := strings.TrimRight(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = strings.TrimRight(config.Platform, "/")
+	}
+	return baseURL
+}
+
+// oauth2EndpointURL returns the URL of the named PAIC OAuth 2.0 endpoint,
+// scoped to the configured realm. Every OAuth 2.0 URL, including the default
+// assertion audience, is built here so they stay consistent.
+func oauth2EndpointURL(config TokenConfig, endpoint string) string {
+	return platformURL(config) + oauth2Path + realmPath(config) + "/" + endpoint
+}
+
+// realmPath returns the path segment selecting the configured realm, such as
+// /realms/root/alpha, or "" for the default realm. Every PAIC endpoint URL
+// goes through it so all requests address the same realm.
+func realmPath(config TokenConfig) string {
+	if realm := strings.Trim(config.Realm, "/"); realm != "" {
+		return "/realms/" + realm
+	}
+	return ""
+}
+
+// tokenEndpointURL returns the PAIC token endpoint URL for the configuration:
+// token_endpoint when set, otherwise the realm's access_token endpoint
+func tokenEndpointURL(config TokenConfig) string {
+	if config.TokenEndpoint != "" {
+		return config.TokenEndpoint
+	}
+	return oauth2EndpointURL(config, "access_token")
+}
+
+// requestedScope returns the space-delimited scope to request from the token endpoint
+func requestedScope(config TokenConfig) string {
+	if config.Scope != "" {
+		return config.Scope
+	}
+	return strings.Join(config.Scopes, " ")
+}
+
+// httpClient returns the HTTP client for a request to PAIC: a copy of the
+// shared client when one is configured, so its connections are reused, or
+// otherwise a new client for the configuration
+func httpClient(config TokenConfig) (*http.Client, error) {
+	if config.HTTPClient == nil {
+		return newHTTPClient(config)
+	}
+	client := *config.HTTPClient
+	if client.Timeout == 0 {
+		client.Timeout = config.HTTPTimeout()
+	}
+	return &client, nil
+}
+
+// NewHTTPClient creates an HTTP client for requests to PAIC with the
+// configured TLS, proxy and timeout settings, for sharing between requests
+func NewHTTPClient(config TokenConfig) (*http.Client, error) {
+	return newHTTPClient(config)
+}
+
+// HTTPClientKey identifies the TLS and proxy settings of the HTTP client
+// NewHTTPClient creates, so configurations with equal keys can share one
+func HTTPClientKey(config TokenConfig) string {
+	return strings.Join([]string{
+		strconv.FormatBool(config.SSLVerificationEnabled()),
+		config.ClientCertFile,
+		config.ClientKeyFile,
+		config.Proxy,
+	}, "\x00")
+}
+
+// newHTTPClient creates the HTTP client used for requests to PAIC
+func newHTTPClient(config TokenConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.SSLVerificationEnabled() {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		certificate, err := LoadClientCertificate(config)
+		if err != nil {
+			return nil, err
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if config.Proxy != "" {
+		proxyURL, err := parseProxyURL(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+
+		// Basic credentials from the proxy URL userinfo. The transport adds them to
+		// plain http requests itself; HTTPS requests need them on the CONNECT.
+		if authorization := proxyAuthorization(proxyURL); authorization != "" {
+			transport.ProxyConnectHeader = http.Header{"Proxy-Authorization": {authorization}}
+		}
+	}
+
+	return &http.Client{
+		Timeout:   config.HTTPTimeout(),
+		Transport: transport,
+	}, nil
+}
+
+// LoadClientCertificate loads the mutual TLS client certificate and key
+// configured by client_cert_file and client_key_file
+func LoadClientCertificate(config TokenConfig) (tls.Certificate, error) {
+	if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+		return tls.Certificate{}, fmt.Errorf("client_cert_file and client_key_file must be set together")
+	}
+	certificate, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate %s and key %s: %w", config.ClientCertFile, config.ClientKeyFile, err)
+	}
+	return certificate, nil
+}
+
+// parseProxyURL parses the proxy URL, keeping any credentials it holds out of the error
+func parseProxyURL(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: scheme and host are required, as in http://proxy.example.com:3128")
+	}
+	return proxyURL, nil
+}
+
+// proxyAuthorization returns the Proxy-Authorization header value for the
+// proxy URL userinfo, or "" when the URL has no credentials
+func proxyAuthorization(proxyURL *url.URL) string {
+	if proxyURL.User == nil {
+		return ""
+	}
+	password, _ := proxyURL.User.Password()
+	credentials := proxyURL.User.Username() + ":" + password
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+}
+
+// userAgent returns the configured User-Agent, falling back to the pctl build version
+func userAgent(config TokenConfig) string {
+	if config.UserAgent != "" {
+		return config.UserAgent
+	}
+	return version.UserAgent()
+}
+
+// addClientCredentials adds the configured OAuth client credentials to the form data,
+// or returns them as an Authorization header for client_secret_basic. For
+// private_key_jwt only the client ID is added; see addClientAssertion.
+func addClientCredentials(data url.Values, config TokenConfig) http.Header {
+	if config.ClientAuthMethod() == ClientAuthSecretBasic && config.ClientSecret != "" {
+		// RFC 6749 section 2.3.1: form-encode the credentials before base64
+		credentials := url.QueryEscape(config.ClientID) + ":" + url.QueryEscape(config.ClientSecret)
+		return http.Header{
+			"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))},
+		}
+	}
+
+	if config.ClientID != "" {
+		data.Set("client_id", config.ClientID)
+	}
+	if config.ClientSecret != "" && config.ClientAuthMethod() != ClientAuthPrivateKeyJWT {
+		data.Set("client_secret", config.ClientSecret)
+	}
+	return nil
+}
+
+// addResources adds the configured RFC 8707 resource indicators to the form data
+func addResources(data url.Values, config TokenConfig) {
+	for _, resource := range config.Resource {
+		data.Add("resource", resource)
+	}
+}
+
+// postForm posts the form data to the endpoint with the additional headers, retrying transient failures
+func postForm(ctx context.Context, config TokenConfig, endpointURL string, data url.Values, header http.Header, log *slog.Logger) (*http.Response, []byte, error) {
+	return post(ctx, config, endpointURL, "application/x-www-form-urlencoded", []byte(data.Encode()), header, log)
+}
+
+// post sends the body to the endpoint with the content type and additional headers,
+// retrying transient failures. Redirects are returned to the caller rather than followed.
+func post(ctx context.Context, config TokenConfig, endpointURL, contentType string, body []byte, header http.Header, log *slog.Logger) (*http.Response, []byte, error) {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", contentType)
+	return send(ctx, config, "POST", endpointURL, body, header, log)
+}
+
+// get requests the endpoint with the additional headers, retrying transient failures
+func get(ctx context.Context, config TokenConfig, endpointURL string, header http.Header, log *slog.Logger) (*http.Response, []byte, error) {
+	return send(ctx, config, "GET", endpointURL, nil, header, log)
+}
+
+// send sends the request to the endpoint, retrying transient failures.
+// Redirects are returned to the caller rather than followed.
+func send(ctx context.Context, config TokenConfig, method, endpointURL string, body []byte, header http.Header, log *slog.Logger) (*http.Response, []byte, error) {
+	// Create HTTP client, or copy the shared one
+	client, err := httpClient(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	if config.Proxy != "" {
+		if proxyURL, err := url.Parse(config.Proxy); err == nil {
+			logger.OrDiscard(log).Debug("using proxy", "proxy", proxyURL.Redacted())
+		}
+	}
+
+	// Configured headers may not replace the headers pctl sets itself
+	for name := range config.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			logger.OrDiscard(log).Warn("ignoring configured header reserved by pctl", "header", name)
+		}
+	}
+
+	// Send request, retrying transient failures
+	return sendWithRetry(ctx, client, config, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpointURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range config.Headers {
+			if !reservedHeaders[http.CanonicalHeaderKey(name)] {
+				req.Header.Set(name, value)
+			}
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req.Header.Set("User-Agent", userAgent(config))
+		return req, nil
+	}, log)
+}
+
+// requestToken posts the form data to the token endpoint with the additional
+// headers and parses the PAIC response
+func requestToken(ctx context.Context, config TokenConfig, tokenURL string, data url.Values, header http.Header, log *slog.Logger) (*PaicTokenResponse, error) {
+	log = logger.OrDiscard(log)
+	resp, body, err := postForm(ctx, config, tokenURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTokenEndpoint, err)
+	}
+
+	log.Debug("token response received", "status", resp.StatusCode)
+
+	// Check response status. A proxy in front of PAIC may answer with an
+	// HTML page, even with a 200 status, when the request never reached PAIC.
+	endpointErr := &TokenEndpointError{
+		StatusCode:  resp.StatusCode,
+		Body:        string(body),
+		URL:         tokenURL,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if resp.StatusCode != http.StatusOK || endpointErr.isHTML() {
+		log.Debug("token request rejected", "status", resp.StatusCode, "body", string(body))
+		return nil, endpointErr
+	}
+
+	// Parse response
+	var tokenResponse PaicTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if tokenResponse.Extra, err = unknownFields(body); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	log.Debug("access token received",
+		"length", len(tokenResponse.AccessToken),
+		"token_type", tokenResponse.TokenType,
+		"expires_in", tokenResponse.ExpiresIn)
+
+	return &tokenResponse, nil
+}
+
+// Fingerprint returns the hex SHA-256 of the access token, for correlating
+// logs and audit records without storing the token itself
+func Fingerprint(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTokenResult builds a TokenResult from a PAIC token response
+func newTokenResult(config TokenConfig, tokenResponse *PaicTokenResponse, metadata map[string]interface{}) *TokenResult {
+	now := time.Now()
+	metadata["generated_at"] = now.Unix()
+	if config.Fingerprint {
+		metadata["fingerprint"] = Fingerprint(tokenResponse.AccessToken)
+	}
+	if len(tokenResponse.Extra) > 0 {
+		metadata["raw_response"] = tokenResponse.Extra
+	}
+
+	return &TokenResult{
+		AccessToken:  tokenResponse.AccessToken,
+		TokenType:    tokenResponse.TokenType,
+		ExpiresIn:    tokenResponse.ExpiresIn,
+		ExpiresAt:    now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second),
+		Scope:        tokenResponse.Scope,
+		RefreshToken: tokenResponse.RefreshToken,
+		IDToken:      tokenResponse.IDToken,
+		Metadata:     metadata,
+	}
+}
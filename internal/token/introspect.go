@@ -0,0 +1,58 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// IntrospectionResult represents the response from the PAIC token introspection endpoint
+type IntrospectionResult struct {
+	Active    bool   `json:"active" yaml:"active"`
+	Scope     string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Username  string `json:"username,omitempty" yaml:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty" yaml:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty" yaml:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty" yaml:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty" yaml:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty" yaml:"iss,omitempty"`
+}
+
+// Introspect checks whether the token is active using the PAIC introspection endpoint
+func Introspect(ctx context.Context, config TokenConfig, accessToken string, log *slog.Logger) (*IntrospectionResult, error) {
+	// Build introspection endpoint URL
+	introspectURL := oauth2EndpointURL(config, "introspect")
+
+	// Prepare form data
+	data := url.Values{
+		"token": {accessToken},
+	}
+	header := addClientCredentials(data, config)
+
+	log = logger.OrDiscard(log)
+	log.Debug("making introspection request", "url", introspectURL)
+
+	resp, body, err := postForm(ctx, config, introspectURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var result IntrospectionResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse introspection response: %w", err)
+	}
+
+	return &result, nil
+}
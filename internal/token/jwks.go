@@ -0,0 +1,71 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwksCache holds fetched key sets by URL for the lifetime of the process
+var jwksCache = struct {
+	sync.Mutex
+	sets map[string]*JWKS
+}{sets: make(map[string]*JWKS)}
+
+// fetchJWKS returns the key set served at the configured jwks_url, fetching it on first use
+func fetchJWKS(ctx context.Context, config TokenConfig, log *slog.Logger) (*JWKS, error) {
+	jwksCache.Lock()
+	defer jwksCache.Unlock()
+
+	if jwks, ok := jwksCache.sets[config.JWKSURL]; ok {
+		return jwks, nil
+	}
+
+	client, err := httpClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("fetching JWKS", "url", config.JWKSURL)
+	resp, body, err := sendWithRetry(ctx, client, config, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", config.JWKSURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", userAgent(config))
+		return req, nil
+	}, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS from %s: status %d: %s", config.JWKSURL, resp.StatusCode, string(body))
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS from %s: %w", config.JWKSURL, err)
+	}
+
+	jwksCache.sets[config.JWKSURL] = &jwks
+	return &jwks, nil
+}
+
+// Key returns the key with the given key ID
+func (s *JWKS) Key(kid string) (*JWK, bool) {
+	for i := range s.Keys {
+		if s.Keys[i].Kid == kid {
+			return &s.Keys[i], true
+		}
+	}
+	return nil, false
+}
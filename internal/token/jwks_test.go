@@ -0,0 +1,105 @@
+package token
+
+import (
+	"context"
+	"crypto/elliptic"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSigningKeyFromJWKS(t *testing.T) {
+	privateKey, jwk := ecJWK(t, elliptic.P256())
+	jwk.Kid = "signing-key"
+	_, other := ecJWK(t, elliptic.P384())
+	other.Kid = "other-key"
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{*other, *jwk}})
+	}))
+	defer server.Close()
+
+	generator := &ServiceAccountGenerator{
+		Config: TokenConfig{
+			ServiceAccountID: "test-service-account",
+			Platform:         "https://test.forgerock.com",
+			JWKSURL:          server.URL + "/jwks.json",
+			KeyID:            "signing-key",
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		key, method, err := generator.signingKey(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if method.Alg() != "ES256" {
+			t.Errorf("Expected ES256, got %s", method.Alg())
+		}
+		if !privateKey.Equal(key) {
+			t.Error("Expected the key matching keyId to be selected")
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("Expected JWKS to be fetched once, got %d", fetches)
+	}
+
+	// A kid missing from the cached set is reported without refetching
+	generator.Config.KeyID = "unknown-key"
+	_, _, err := generator.signingKey(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `no key with kid "unknown-key"`) {
+		t.Errorf("Expected missing kid error, got %v", err)
+	}
+}
+
+func TestSigningKeyFromJWKSErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			wantErr: "status 404",
+		},
+		{
+			name: "invalid JSON",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("<html>"))
+			},
+			wantErr: "failed to parse JWKS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         "https://test.forgerock.com",
+					JWKSURL:          server.URL,
+					KeyID:            "signing-key",
+					Retries:          -1,
+				},
+			}
+
+			_, _, err := generator.signingKey(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
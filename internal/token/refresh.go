@@ -0,0 +1,53 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// Refresh exchanges a refresh token for a new access token using the OAuth 2.0
+// refresh token grant. When PAIC does not rotate the refresh token, the
+// original is carried over to the result so it can be used again.
+func Refresh(ctx context.Context, config TokenConfig, refreshToken string, log *slog.Logger) (*TokenResult, error) {
+	log = logger.OrDiscard(log)
+
+	// Build token endpoint URL
+	tokenURL := tokenEndpointURL(config)
+
+	// Prepare form data
+	data := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	if scope := requestedScope(config); scope != "" {
+		data.Set("scope", scope)
+	}
+	header := addClientCredentials(data, config)
+
+	log.Debug("making token request", "url", tokenURL, "grant_type", "refresh_token")
+
+	// Exchange refresh token for access token
+	tokenResponse, err := requestToken(ctx, config, tokenURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange refresh token: %w", err)
+	}
+
+	rotated := tokenResponse.RefreshToken != ""
+	if !rotated {
+		tokenResponse.RefreshToken = refreshToken
+	}
+
+	// Build result
+	result := newTokenResult(config, tokenResponse, map[string]interface{}{
+		"grant_type":      "refresh_token",
+		"refresh_rotated": rotated,
+	})
+
+	log.Debug("token refreshed", "expires_at", result.ExpiresAt, "refresh_rotated", rotated)
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name            string
+		rotatedToken    string
+		expectedRefresh string
+	}{
+		{name: "rotated refresh token", rotatedToken: "new-refresh-token", expectedRefresh: "new-refresh-token"},
+		{name: "refresh token not rotated", expectedRefresh: "old-refresh-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("Failed to parse form: %v", err)
+				}
+				if r.PostForm.Get("grant_type") != "refresh_token" {
+					t.Errorf("Expected grant_type 'refresh_token', got %s", r.PostForm.Get("grant_type"))
+				}
+				if r.PostForm.Get("refresh_token") != "old-refresh-token" {
+					t.Errorf("Expected refresh_token 'old-refresh-token', got %s", r.PostForm.Get("refresh_token"))
+				}
+				if r.PostForm.Get("client_id") != "test-client" {
+					t.Errorf("Expected client_id 'test-client', got %s", r.PostForm.Get("client_id"))
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token":  "refreshed-access-token",
+					"refresh_token": tt.rotatedToken,
+					"token_type":    "Bearer",
+					"expires_in":    3599,
+				})
+			}))
+			defer server.Close()
+
+			config := TokenConfig{Platform: server.URL, ClientID: "test-client"}
+			result, err := Refresh(context.Background(), config, "old-refresh-token", nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result.AccessToken != "refreshed-access-token" {
+				t.Errorf("Expected refreshed access token, got %s", result.AccessToken)
+			}
+			if result.RefreshToken != tt.expectedRefresh {
+				t.Errorf("Expected refresh token %s, got %s", tt.expectedRefresh, result.RefreshToken)
+			}
+			if result.IsExpired() {
+				t.Error("Expected refreshed token to be valid")
+			}
+		})
+	}
+}
+
+func TestRefreshError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	config := TokenConfig{Platform: server.URL, ClientID: "test-client"}
+	if _, err := Refresh(context.Background(), config, "expired-refresh-token", nil); err == nil {
+		t.Error("Expected error for rejected refresh token")
+	}
+}
@@ -0,0 +1,164 @@
+package token
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+const (
+	// DefaultRetries is the number of retries used when retries is not set
+	DefaultRetries = 3
+	// DefaultRetryMaxWait caps the wait between retries when retry_max_wait_seconds is not set
+	DefaultRetryMaxWait = 30 * time.Second
+)
+
+// retryBaseDelay is the wait before the first retry, doubled on each subsequent attempt
+var retryBaseDelay = 500 * time.Millisecond
+
+// attemptsKey is the context key of the counter set by withAttemptCounter
+type attemptsKey struct{}
+
+// withAttemptCounter returns a context in which sendWithRetry adds each
+// request attempt, including retries, to attempts
+func withAttemptCounter(ctx context.Context, attempts *int) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, attempts)
+}
+
+// sendWithRetry sends the request built by newRequest, retrying connection errors,
+// 429 and 5xx responses with exponential backoff. The response body is read and closed.
+func sendWithRetry(ctx context.Context, client *http.Client, config TokenConfig, newRequest func() (*http.Request, error), log *slog.Logger) (*http.Response, []byte, error) {
+	log = logger.OrDiscard(log)
+	retries := config.MaxRetries()
+	maxWait := config.RetryMaxWait()
+
+	attempts, _ := ctx.Value(attemptsKey{}).(*int)
+	for attempt := 0; ; attempt++ {
+		if attempts != nil {
+			*attempts++
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		// Record phase timings when tracing is enabled
+		var trace *requestTrace
+		if config.Trace {
+			req, trace = traceRequest(req)
+		}
+
+		var wait time.Duration
+		resp, err := client.Do(req)
+		if trace != nil {
+			status := 0
+			if err == nil {
+				status = resp.StatusCode
+			}
+			trace.log(log, req, status)
+		}
+		if err != nil {
+			if ctx.Err() != nil || attempt >= retries || !retryableError(err) {
+				return nil, nil, fmt.Errorf("failed to make request to %s: %w", req.URL.Redacted(), err)
+			}
+			wait = backoff(attempt, maxWait)
+			log.Debug("request failed", "url", req.URL.Redacted(), "error", err)
+		} else {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+			}
+			if !retryableStatus(resp.StatusCode) || attempt >= retries {
+				return resp, body, nil
+			}
+			wait = retryAfter(resp.Header, maxWait)
+			if wait == 0 {
+				wait = backoff(attempt, maxWait)
+			}
+			log.Debug("transient response status", "url", req.URL.Redacted(), "status", resp.StatusCode)
+		}
+
+		log.Debug("retrying request", "wait", wait, "retry", attempt+1, "max_retries", retries)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, fmt.Errorf("request to %s cancelled: %w", req.URL.Redacted(), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableError reports whether a request error may succeed on retry.
+// Certificate verification failures are permanent and fail immediately.
+func retryableError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	return !errors.As(err, &certErr) && !errors.As(err, &unknownAuthority) && !errors.As(err, &hostnameErr)
+}
+
+// Transient reports whether err is a failure that may succeed when retried:
+// a network error other than certificate verification, or a token endpoint
+// response with a transient status. It classifies errors as retries do.
+func Transient(err error) bool {
+	var endpointErr *TokenEndpointError
+	if errors.As(err, &endpointErr) {
+		return retryableStatus(endpointErr.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && retryableError(err)
+}
+
+// retryableStatus reports whether a response status indicates a transient failure
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// backoff returns the exponential backoff delay for the attempt, capped at maxWait
+func backoff(attempt int, maxWait time.Duration) time.Duration {
+	wait := retryBaseDelay << attempt
+	if wait <= 0 || wait > maxWait {
+		return maxWait
+	}
+	return wait
+}
+
+// retryAfter parses the Retry-After header as seconds or an HTTP date, capped at maxWait.
+// It returns zero when the header is absent or invalid.
+func retryAfter(header http.Header, maxWait time.Duration) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return 0
+	}
+
+	if wait <= 0 {
+		return 0
+	}
+	if wait > maxWait {
+		return maxWait
+	}
+	return wait
+}
@@ -0,0 +1,121 @@
+package token
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestTokenRetries(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		retries      int
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{name: "recovers after transient failures", statuses: []int{503, 502, 200}, wantAttempts: 3},
+		{name: "retries rate limiting", statuses: []int{429, 200}, wantAttempts: 2},
+		{name: "client errors fail immediately", statuses: []int{400, 200}, wantErr: true, wantAttempts: 1},
+		{name: "gives up after max retries", statuses: []int{503, 503, 503, 503, 503}, wantErr: true, wantAttempts: 4},
+		{name: "negative retries disables retrying", statuses: []int{503, 200}, retries: -1, wantErr: true, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+				status := tt.statuses[attempt-1]
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					w.Write([]byte(`{"error":"temporarily_unavailable"}`))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"retried-token","token_type":"Bearer"}`))
+			}))
+			defer server.Close()
+
+			config := TokenConfig{BaseURL: server.URL, Retries: tt.retries}
+			response, err := requestToken(context.Background(), config, tokenEndpointURL(config), nil, nil, nil)
+
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.wantErr && response.AccessToken != "retried-token" {
+				t.Errorf("Expected access token 'retried-token', got %s", response.AccessToken)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "absent", value: "", want: 0},
+		{name: "seconds", value: "2", want: 2 * time.Second},
+		{name: "capped at max wait", value: "120", want: 10 * time.Second},
+		{name: "invalid", value: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			if got := retryAfter(header, 10*time.Second); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	header := http.Header{}
+	header.Set("Retry-After", time.Now().Add(5*time.Second).UTC().Format(http.TimeFormat))
+	if got := retryAfter(header, 10*time.Second); got <= 0 || got > 5*time.Second {
+		t.Errorf("Expected HTTP date Retry-After within 5s, got %s", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if got := backoff(0, time.Minute); got != retryBaseDelay {
+		t.Errorf("Expected first backoff %s, got %s", retryBaseDelay, got)
+	}
+	if got := backoff(2, time.Minute); got != 4*retryBaseDelay {
+		t.Errorf("Expected third backoff %s, got %s", 4*retryBaseDelay, got)
+	}
+	if got := backoff(20, time.Second); got != time.Second {
+		t.Errorf("Expected backoff capped at 1s, got %s", got)
+	}
+}
+
+func TestRequestTokenCertificateErrorNotRetried(t *testing.T) {
+	server := newTokenServer(t)
+	config := TokenConfig{BaseURL: server.URL}
+
+	start := time.Now()
+	_, err := requestToken(context.Background(), config, tokenEndpointURL(config), nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("Expected certificate error, got %v", err)
+	}
+	if time.Since(start) > retryBaseDelay {
+		t.Errorf("Expected certificate error to fail without retrying, took %s", time.Since(start))
+	}
+}
@@ -0,0 +1,42 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// Revoke revokes an access or refresh token using the PAIC revocation endpoint.
+// tokenTypeHint may be "access_token", "refresh_token" or empty.
+func Revoke(ctx context.Context, config TokenConfig, token, tokenTypeHint string, log *slog.Logger) error {
+	// Build revocation endpoint URL
+	revokeURL := oauth2EndpointURL(config, "token/revoke")
+
+	// Prepare form data
+	data := url.Values{
+		"token": {token},
+	}
+	if tokenTypeHint != "" {
+		data.Set("token_type_hint", tokenTypeHint)
+	}
+	header := addClientCredentials(data, config)
+
+	log = logger.OrDiscard(log)
+	log.Debug("making revocation request", "url", revokeURL)
+
+	resp, body, err := postForm(ctx, config, revokeURL, data, header, log)
+	if err != nil {
+		return fmt.Errorf("revocation request failed: %w", err)
+	}
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("revocation request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -1,26 +1,28 @@
 package token
 
 import (
-	"bytes"
+	"context"
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"io"
-	"math/big"
-	"net/http"
+	"log/slog"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/aaronwang/pctl/internal/logger"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// defaultServiceAccountClientID is the OAuth client PAIC registers for service accounts
+const defaultServiceAccountClientID = "service-account"
+
 // ServiceAccountGenerator handles service account token generation
 type ServiceAccountGenerator struct {
 	Config  TokenConfig
 	Verbose bool
+	Logger  *slog.Logger // Optional; defaults to debug output on stderr when Verbose
 }
 
 // JWK represents a JSON Web Key structure
@@ -36,157 +38,264 @@ type JWK struct {
 	DP  string `json:"dp"`  // First Factor CRT Exponent
 	DQ  string `json:"dq"`  // Second Factor CRT Exponent
 	QI  string `json:"qi"`  // First CRT Coefficient
-}
-
-// PaicTokenResponse represents the response from PAIC token endpoint
-type PaicTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int64  `json:"expires_in,omitempty"`
-	Scope       string `json:"scope,omitempty"`
+	Crv string `json:"crv"` // Elliptic Curve name
+	X   string `json:"x"`   // Elliptic Curve X coordinate
+	Y   string `json:"y"`   // Elliptic Curve Y coordinate
 }
 
 // Generate generates a service account token
 func (g *ServiceAccountGenerator) Generate() (*TokenResult, error) {
-	if g.Verbose {
-		fmt.Printf("Generating service account token for: %s\n", g.Config.ServiceAccountID)
-	}
+	return g.GenerateContext(context.Background())
+}
 
-	// Parse JWK from JSON string
-	var jwk JWK
-	if err := json.Unmarshal([]byte(g.Config.JWKJson), &jwk); err != nil {
-		return nil, fmt.Errorf("failed to parse JWK: %w", err)
-	}
+// GenerateContext generates a service account token, aborting the request when ctx is done
+func (g *ServiceAccountGenerator) GenerateContext(ctx context.Context) (*TokenResult, error) {
+	log := g.log()
+	log.Debug("generating service account token", "service_account_id", g.Config.ServiceAccountID)
 
-	// Create RSA private key from JWK
-	privateKey, err := g.jwkToRSAPrivateKey(&jwk)
+	// Load signing key from JWK, PEM or JWKS
+	privateKey, signingMethod, err := g.signingKey(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert JWK to RSA private key: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrKeyParse, err)
 	}
 
 	// Create JWT assertion
-	jwtAssertion, err := g.createJWTAssertion(privateKey)
+	jti, err := g.assertionID()
+	if err != nil {
+		return nil, err
+	}
+	jwtAssertion, err := g.createJWTAssertion(privateKey, signingMethod, jti)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create JWT assertion: %w", err)
 	}
 
-	if g.Verbose {
-		fmt.Printf("JWT assertion created successfully\n")
+	// Return the signed assertion itself for callers that run their own exchange
+	if g.Config.AssertionOnly {
+		return g.assertionResult(jwtAssertion, jti)
 	}
 
-	// Exchange JWT assertion for access token
-	tokenResponse, err := g.exchangeJWTForToken(jwtAssertion)
+	// Exchange JWT assertion for access token, timing the exchange and
+	// counting its request attempts for the result metadata
+	attempts := 0
+	start := time.Now()
+	tokenResponse, err := g.exchangeJWTForToken(withAttemptCounter(ctx, &attempts), jwtAssertion)
+	duration := time.Since(start)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange JWT for token: %w", err)
 	}
 
 	// Build result
-	now := time.Now()
-	expiresAt := now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
-	
-	result := &TokenResult{
-		AccessToken: tokenResponse.AccessToken,
-		TokenType:   tokenResponse.TokenType,
-		ExpiresIn:   tokenResponse.ExpiresIn,
-		ExpiresAt:   expiresAt,
-		Scope:       tokenResponse.Scope,
-		Metadata: map[string]interface{}{
-			"service_account_id": g.Config.ServiceAccountID,
-			"generated_at":       now.Unix(),
-			"platform":          g.Config.Platform,
-		},
-	}
-
-	if g.Verbose {
-		fmt.Printf("Token generated successfully, expires at: %s\n", result.ExpiresAt.Format(time.RFC3339))
+	metadata := map[string]interface{}{
+		"service_account_id": g.Config.ServiceAccountID,
+		"platform":          g.Config.Platform,
+		"duration_ms":        duration.Milliseconds(),
+		"attempts":           attempts,
 	}
+	if jti != "" {
+		metadata["jti"] = jti
+	}
+	result := newTokenResult(g.Config, tokenResponse, metadata)
+
+	log.Debug("service account token generated", "expires_at", result.ExpiresAt)
 
 	return result, nil
 }
 
-// jwkToRSAPrivateKey converts JWK to RSA private key
-func (g *ServiceAccountGenerator) jwkToRSAPrivateKey(jwk *JWK) (*rsa.PrivateKey, error) {
-	// Decode base64url components
-	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode modulus: %w", err)
+// assertionResult builds a result carrying the signed assertion in place of an access token
+func (g *ServiceAccountGenerator) assertionResult(jwtAssertion, jti string) (*TokenResult, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(jwtAssertion, claims); err != nil {
+		return nil, fmt.Errorf("failed to read JWT assertion: %w", err)
 	}
-	
-	d, err := base64.RawURLEncoding.DecodeString(jwk.D)
+	exp, err := claims.GetExpirationTime()
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode private exponent: %w", err)
+		return nil, fmt.Errorf("failed to read JWT assertion expiry: %w", err)
+	}
+	if exp == nil {
+		return nil, fmt.Errorf("failed to read JWT assertion expiry: assertion has no exp claim")
 	}
 
-	p, err := base64.RawURLEncoding.DecodeString(jwk.P)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode first prime: %w", err)
+	// Build the result as for an issued token, with the assertion's own expiry
+	result := newTokenResult(g.Config, &PaicTokenResponse{
+		AccessToken: jwtAssertion,
+		TokenType:   AssertionTokenType,
+		ExpiresIn:   int64(time.Until(exp.Time).Round(time.Second).Seconds()),
+	}, map[string]interface{}{
+		"service_account_id": g.Config.ServiceAccountID,
+		"platform":           g.Config.Platform,
+		"assertion_only":     true,
+	})
+	result.ExpiresAt = exp.Time
+	if jti != "" {
+		result.Metadata["jti"] = jti
 	}
 
-	q, err := base64.RawURLEncoding.DecodeString(jwk.Q)
+	g.log().Debug("returning JWT assertion without token exchange", "expires_at", result.ExpiresAt)
+
+	return result, nil
+}
+
+// log returns the configured logger, falling back to the default for the verbosity
+func (g *ServiceAccountGenerator) log() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return logger.Default(g.Verbose)
+}
+
+// signingKey loads the private key and selects the signing method, which is
+// signing_alg when set and otherwise follows from the key type
+func (g *ServiceAccountGenerator) signingKey(ctx context.Context) (crypto.Signer, jwt.SigningMethod, error) {
+	key, method, err := g.loadSigningKey(ctx)
+	if err != nil || g.Config.SigningAlg == "" {
+		return key, method, err
+	}
+	method, err = signingMethodForAlg(key, g.Config.SigningAlg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode second prime: %w", err)
+		return nil, nil, err
 	}
+	return key, method, nil
+}
 
-	// Create big integers from byte arrays
-	nInt := new(big.Int).SetBytes(n)
-	dInt := new(big.Int).SetBytes(d)
-	pInt := new(big.Int).SetBytes(p)
-	qInt := new(big.Int).SetBytes(q)
+// loadSigningKey loads the private key from the JWK, the PEM private key or the
+// key matching keyId in the JWKS at jwks_url, in that order of precedence
+func (g *ServiceAccountGenerator) loadSigningKey(ctx context.Context) (crypto.Signer, jwt.SigningMethod, error) {
+	switch {
+	case g.Config.JWKJson != "" || g.Config.PrivateKey != "":
+		key, method, nonStandard, err := parseSigningKey(g.Config.JWKJson, g.Config.PrivateKey, g.Config.KeyID, g.Config.StrictKey)
+		g.logNonStandardJWK(nonStandard)
+		return key, method, err
+	case g.Config.JWKSURL != "":
+		jwks, err := fetchJWKS(ctx, g.Config, g.log())
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := jwks.Key(g.Config.KeyID)
+		if !ok {
+			return nil, nil, fmt.Errorf("no key with kid %q found in JWKS from %s", g.Config.KeyID, g.Config.JWKSURL)
+		}
+		g.logNonStandardJWK(nonStandardJWKFields(key))
+		return jwkSigningKey(key, g.Config.StrictKey)
+	default:
+		return nil, nil, fmt.Errorf("no signing key configured: set jwk_json, privateKey or jwks_url")
+	}
+}
 
-	// Create RSA private key
-	key := &rsa.PrivateKey{
-		PublicKey: rsa.PublicKey{
-			N: nInt,
-			E: 65537, // Standard RSA exponent (AQAB in base64)
-		},
-		D:      dInt,
-		Primes: []*big.Int{pInt, qInt},
+// logNonStandardJWK notes JWK fields that are not unpadded base64url, which
+// are accepted but may be rejected by stricter tools
+func (g *ServiceAccountGenerator) logNonStandardJWK(fields []string) {
+	if len(fields) > 0 {
+		g.log().Debug("JWK fields use padded or standard base64 rather than base64url", "fields", fields)
 	}
+}
 
-	// Precompute values for faster operations
-	key.Precompute()
+// CheckSigningKey parses the JWK or PEM private key without using it, so
+// key problems can be reported before any request is made. A key fetched
+// from jwks_url is not checked, as that needs a network call.
+func CheckSigningKey(config TokenConfig) error {
+	if config.JWKJson == "" && config.PrivateKey == "" {
+		return nil
+	}
 
-	return key, nil
+	generator := &ServiceAccountGenerator{Config: config}
+	if _, _, err := generator.signingKey(context.Background()); err != nil {
+		return fmt.Errorf("%w: %w", ErrKeyParse, err)
+	}
+	return nil
 }
 
-// createJWTAssertion creates a JWT assertion for service account authentication
-func (g *ServiceAccountGenerator) createJWTAssertion(privateKey *rsa.PrivateKey) (string, error) {
-	now := time.Now()
-	
-	// Generate random JWT ID
+// CheckAudience reports a configured service account audience that does not
+// name the token endpoint the assertion is sent to. PAIC rejects such an
+// assertion with invalid_jwt, so a mismatch usually means only one of the
+// audience and the platform URL or realm was overridden.
+func CheckAudience(config TokenConfig) error {
+	if config.Type != TokenTypeServiceAccount || config.Audience == "" {
+		return nil
+	}
+
+	tokenURL := tokenEndpointURL(config)
+	audience, err := url.Parse(config.Audience)
+	if err != nil {
+		return fmt.Errorf("invalid audience %q: %w", config.Audience, err)
+	}
+	endpoint, err := url.Parse(tokenURL)
+	if err != nil {
+		return fmt.Errorf("invalid token endpoint URL %q: %w", tokenURL, err)
+	}
+	if !strings.EqualFold(audience.Host, endpoint.Host) ||
+		strings.TrimRight(audience.Path, "/") != strings.TrimRight(endpoint.Path, "/") {
+		return fmt.Errorf("audience %q does not match the token endpoint %q", config.Audience, tokenURL)
+	}
+	return nil
+}
+
+// assertionID returns the configured JWT ID, or a random one when none is set.
+// It returns "" when include_jti is false, so the assertion has no jti claim.
+func (g *ServiceAccountGenerator) assertionID() (string, error) {
+	if !g.Config.JTIEnabled() {
+		return "", nil
+	}
+	if g.Config.JTI != "" {
+		return g.Config.JTI, nil
+	}
+
 	jtiBytes := make([]byte, 16)
 	if _, err := rand.Read(jtiBytes); err != nil {
 		return "", fmt.Errorf("failed to generate JWT ID: %w", err)
 	}
-	jti := base64.RawURLEncoding.EncodeToString(jtiBytes)
+	return base64.RawURLEncoding.EncodeToString(jtiBytes), nil
+}
 
-	// Build audience URL
-	baseURL := strings.TrimRight(g.Config.BaseURL, "/")
-	if baseURL == "" {
-		baseURL = strings.TrimRight(g.Config.Platform, "/")
+// createJWTAssertion creates a JWT assertion for service account authentication
+func (g *ServiceAccountGenerator) createJWTAssertion(privateKey interface{}, signingMethod jwt.SigningMethod, jti string) (string, error) {
+	// Shift the assertion time by the configured offset to compensate for local clock skew
+	now := time.Now().Add(g.Config.ClockSkew())
+
+	// Use the configured audience, defaulting to the token endpoint URL PAIC expects
+	audience := g.Config.Audience
+	if audience == "" {
+		audience = tokenEndpointURL(g.Config)
 	}
-	audience := baseURL + "/am/oauth2/access_token"
 
-	// Determine expiration
-	expSeconds := g.Config.ExpSeconds
-	if expSeconds == 0 {
-		expSeconds = int(g.Config.ExpiresIn.Seconds())
-	}
-	if expSeconds == 0 {
-		expSeconds = 899 // Default to 899 seconds
+	// Determine the assertion expiration, which is independent of the access token lifetime
+	expSeconds := int(g.Config.AssertionExp().Seconds())
+
+	// PAIC rejects assertions that expire too far in the future; the access
+	// token lifetime is unaffected
+	if maxSeconds := int(g.Config.MaxAssertionExp().Seconds()); expSeconds > maxSeconds {
+		g.log().Debug("clamping JWT assertion expiry to the maximum PAIC accepts",
+			"requested_seconds", expSeconds,
+			"max_seconds", maxSeconds)
+		expSeconds = maxSeconds
 	}
 
-	// Create JWT claims
+	// Create JWT claims; iat, nbf and exp are all derived from the same now
 	claims := jwt.MapClaims{
 		"iss": g.Config.ServiceAccountID,
 		"sub": g.Config.ServiceAccountID,
 		"aud": audience,
+		"iat": now.Unix(),
+		"nbf": now.Add(-g.Config.NotBeforeSkew()).Unix(),
 		"exp": now.Unix() + int64(expSeconds),
-		"jti": jti,
+	}
+	if jti != "" {
+		claims["jti"] = jti
+	}
+
+	// Merge custom claims, which may not replace the required claims or set
+	// the jti include_jti leaves out
+	for name, value := range g.Config.CustomClaims {
+		if _, reserved := claims[name]; reserved || name == "jti" {
+			return "", fmt.Errorf("custom claim %q conflicts with a required assertion claim", name)
+		}
+		claims[name] = value
 	}
 
 	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token := jwt.NewWithClaims(signingMethod, claims)
+	if g.Config.KeyID != "" {
+		token.Header["kid"] = g.Config.KeyID
+	}
 
 	// Sign token
 	tokenString, err := token.SignedString(privateKey)
@@ -194,87 +303,40 @@ func (g *ServiceAccountGenerator) createJWTAssertion(privateKey *rsa.PrivateKey)
 		return "", fmt.Errorf("failed to sign JWT: %w", err)
 	}
 
-	if g.Verbose {
-		fmt.Printf("JWT assertion created for audience: %s\n", audience)
-		fmt.Printf("JWT expiration: %s\n", time.Unix(now.Unix()+int64(expSeconds), 0).Format(time.RFC3339))
-	}
+	g.log().Debug("JWT assertion created",
+		"audience", audience,
+		"jti", jti,
+		"expires_at", time.Unix(now.Unix()+int64(expSeconds), 0))
 
 	return tokenString, nil
 }
 
 // exchangeJWTForToken exchanges JWT assertion for access token
-func (g *ServiceAccountGenerator) exchangeJWTForToken(jwtAssertion string) (*PaicTokenResponse, error) {
+func (g *ServiceAccountGenerator) exchangeJWTForToken(ctx context.Context, jwtAssertion string) (*PaicTokenResponse, error) {
 	// Build token endpoint URL
-	baseURL := strings.TrimRight(g.Config.BaseURL, "/")
-	if baseURL == "" {
-		baseURL = strings.TrimRight(g.Config.Platform, "/")
+	tokenURL := tokenEndpointURL(g.Config)
+
+	// Use the configured OAuth client, falling back to the default service account client
+	clientID := g.Config.ClientID
+	if clientID == "" {
+		clientID = defaultServiceAccountClientID
 	}
-	tokenURL := baseURL + "/am/oauth2/access_token"
 
 	// Prepare form data
 	data := url.Values{
-		"client_id":   {"service-account"},
+		"client_id":   {clientID},
 		"grant_type":  {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
 		"assertion":   {jwtAssertion},
 		"scope":       {g.Config.Scope},
 	}
+	addResources(data, g.Config)
 
-	if g.Verbose {
-		fmt.Printf("Making token request to: %s\n", tokenURL)
-		fmt.Printf("Grant type: %s\n", "urn:ietf:params:oauth:grant-type:jwt-bearer")
-		fmt.Printf("Scope: %s\n", g.Config.Scope)
-	}
-
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	log := g.log()
+	log.Debug("making token request", "url", tokenURL, "grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer", "scope", g.Config.Scope)
 
-	// Create request
-	req, err := http.NewRequest("POST", tokenURL, bytes.NewBufferString(data.Encode()))
+	tokenResponse, err := requestToken(ctx, g.Config, tokenURL, data, nil, log)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, clockSkewError(err)
 	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", "pctl/0.1.0")
-
-	// Make request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make token request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if g.Verbose {
-		fmt.Printf("Response status: %d %s\n", resp.StatusCode, resp.Status)
-	}
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		if g.Verbose {
-			fmt.Printf("Response body: %s\n", string(body))
-		}
-		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var tokenResponse PaicTokenResponse
-	if err := json.Unmarshal(body, &tokenResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse token response: %w", err)
-	}
-
-	if g.Verbose {
-		fmt.Printf("Access token received (length: %d chars)\n", len(tokenResponse.AccessToken))
-		fmt.Printf("Token type: %s\n", tokenResponse.TokenType)
-		fmt.Printf("Expires in: %d seconds\n", tokenResponse.ExpiresIn)
-	}
-
-	return &tokenResponse, nil
-}
\ No newline at end of file
+	return tokenResponse, nil
+}
@@ -1,8 +1,25 @@
 package token
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestJWKParsing(t *testing.T) {
@@ -105,7 +122,7 @@ func TestJWKValidation(t *testing.T) {
 		{
 			name:    "empty JWK",
 			jwkJson: `{}`,
-			wantErr: false, // Will fail later in RSA conversion
+			wantErr: false, // Rejected by validateJWK
 		},
 	}
 
@@ -124,6 +141,78 @@ func TestJWKValidation(t *testing.T) {
 	}
 }
 
+func TestValidateJWK(t *testing.T) {
+	tests := []struct {
+		name    string
+		jwk     JWK
+		wantErr string
+	}{
+		{
+			name: "complete RSA JWK",
+			jwk:  JWK{Kty: "RSA", N: "n", E: "AQAB", D: "d", P: "p", Q: "q"},
+		},
+		{
+			name: "RSA JWK without exponent",
+			jwk:  JWK{Kty: "RSA", N: "n", D: "d", P: "p", Q: "q"},
+		},
+		{
+			name:    "empty JWK",
+			jwk:     JWK{},
+			wantErr: "JWK missing required field: n",
+		},
+		{
+			name:    "RSA JWK missing prime",
+			jwk:     JWK{Kty: "RSA", N: "n", E: "AQAB", D: "d", Q: "q"},
+			wantErr: "JWK missing required field: p",
+		},
+		{
+			name: "complete EC JWK",
+			jwk:  JWK{Kty: "EC", Crv: "P-256", X: "x", Y: "y", D: "d"},
+		},
+		{
+			name:    "EC JWK missing private key",
+			jwk:     JWK{Kty: "EC", Crv: "P-256", X: "x", Y: "y"},
+			wantErr: "JWK missing required field: d",
+		},
+		{
+			name:    "unsupported key type",
+			jwk:     JWK{Kty: "oct"},
+			wantErr: "unsupported JWK key type: oct",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJWK(&tt.jwk)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceAccountGenerateIncompleteJWK(t *testing.T) {
+	generator := &ServiceAccountGenerator{
+		Config: TokenConfig{
+			Type:             TokenTypeServiceAccount,
+			ServiceAccountID: "test-id",
+			Platform:         "https://test.forgerock.com",
+			JWKJson:          `{"kty":"RSA","n":"test","e":"AQAB","d":"test"}`,
+		},
+	}
+
+	_, err := generator.Generate()
+	if err == nil || !strings.Contains(err.Error(), "JWK missing required field: p") {
+		t.Errorf("Expected missing field error, got %v", err)
+	}
+}
+
 func TestConfigValidation(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -175,4 +264,769 @@ func TestConfigValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// ecJWK generates an EC key on the given curve and returns it as a JWK
+func ecJWK(t *testing.T, curve elliptic.Curve) (*ecdsa.PrivateKey, *JWK) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	return key, &JWK{
+		Kty: "EC",
+		Crv: curve.Params().Name,
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		D:   base64.RawURLEncoding.EncodeToString(key.D.FillBytes(make([]byte, size))),
+	}
+}
+
+func TestECJWKSigning(t *testing.T) {
+	tests := []struct {
+		curve elliptic.Curve
+		alg   string
+	}{
+		{elliptic.P256(), "ES256"},
+		{elliptic.P384(), "ES384"},
+		{elliptic.P521(), "ES512"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			key, jwk := ecJWK(t, tt.curve)
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         "https://test.forgerock.com",
+				},
+			}
+
+			privateKey, method, err := jwkSigningKey(jwk, false)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if method.Alg() != tt.alg {
+				t.Errorf("Expected signing method %s, got %s", tt.alg, method.Alg())
+			}
+
+			assertion, err := generator.createJWTAssertion(privateKey, method, "test-jti")
+			if err != nil {
+				t.Fatalf("Failed to create assertion: %v", err)
+			}
+
+			parsed, err := jwt.Parse(assertion, func(*jwt.Token) (interface{}, error) {
+				return &key.PublicKey, nil
+			}, jwt.WithAudience("https://test.forgerock.com/am/oauth2/access_token"))
+			if err != nil {
+				t.Fatalf("Failed to verify assertion: %v", err)
+			}
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("Expected header alg %s, got %s", tt.alg, parsed.Method.Alg())
+			}
+		})
+	}
+}
+
+func TestECJWKInvalidCurve(t *testing.T) {
+	_, _, err := jwkSigningKey(&JWK{Kty: "EC", Crv: "secp256k1", X: "AA", Y: "AA", D: "AA"}, false)
+	if err == nil {
+		t.Fatal("Expected error for unsupported curve")
+	}
+	if !strings.Contains(err.Error(), "unsupported elliptic curve") {
+		t.Errorf("Expected unsupported curve error, got: %v", err)
+	}
+
+	_, _, err = jwkSigningKey(&JWK{Kty: "oct"}, false)
+	if err == nil || !strings.Contains(err.Error(), "unsupported JWK key type") {
+		t.Errorf("Expected unsupported key type error, got: %v", err)
+	}
+}
+
+func TestExchangeJWTForTokenClientID(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientID string
+		want     string
+	}{
+		{name: "default client", clientID: "", want: "service-account"},
+		{name: "custom client", clientID: "tenant-sa-client", want: "tenant-sa-client"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("Failed to parse form: %v", err)
+				}
+				if got := r.PostForm.Get("client_id"); got != tt.want {
+					t.Errorf("Expected client_id %q, got %q", tt.want, got)
+				}
+				if got := r.PostForm.Get("assertion"); got != "signed-assertion" {
+					t.Errorf("Expected assertion to be sent, got %q", got)
+				}
+				if got := r.PostForm["resource"]; len(got) != 1 || got[0] != "https://api.example.com" {
+					t.Errorf("Expected resource to be sent, got %v", got)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"sa-token","token_type":"Bearer","expires_in":899}`))
+			}))
+			defer server.Close()
+
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         server.URL,
+					ClientID:         tt.clientID,
+					Resource:         []string{"https://api.example.com"},
+				},
+			}
+
+			response, err := generator.exchangeJWTForToken(context.Background(), "signed-assertion")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.AccessToken != "sa-token" {
+				t.Errorf("Expected access token 'sa-token', got %s", response.AccessToken)
+			}
+		})
+	}
+}
+
+// rsaJWKWithExponent builds an RSA key with the given public exponent and returns it as a JWK
+func rsaJWKWithExponent(t testing.TB, e int) (*rsa.PublicKey, *JWK) {
+	t.Helper()
+	one := big.NewInt(1)
+	eInt := big.NewInt(int64(e))
+	for {
+		p, err := rand.Prime(rand.Reader, 1024)
+		if err != nil {
+			t.Fatalf("Failed to generate prime: %v", err)
+		}
+		q, err := rand.Prime(rand.Reader, 1024)
+		if err != nil {
+			t.Fatalf("Failed to generate prime: %v", err)
+		}
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(eInt, phi)
+		if d == nil || p.Cmp(q) == 0 {
+			continue
+		}
+		n := new(big.Int).Mul(p, q)
+		encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+		return &rsa.PublicKey{N: n, E: e}, &JWK{
+			Kty: "RSA",
+			N:   encode(n),
+			E:   encode(eInt),
+			D:   encode(d),
+			P:   encode(p),
+			Q:   encode(q),
+		}
+	}
+}
+
+func TestRSAJWKPublicExponent(t *testing.T) {
+	publicKey, jwk := rsaJWKWithExponent(t, 3)
+	generator := &ServiceAccountGenerator{
+		Config: TokenConfig{
+			ServiceAccountID: "test-service-account",
+			Platform:         "https://test.forgerock.com",
+		},
+	}
+
+	privateKey, err := jwkToRSAPrivateKey(jwk, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if privateKey.E != 3 {
+		t.Errorf("Expected public exponent 3, got %d", privateKey.E)
+	}
+
+	assertion, err := generator.createJWTAssertion(privateKey, jwt.SigningMethodRS256, "test-jti")
+	if err != nil {
+		t.Fatalf("Failed to create assertion: %v", err)
+	}
+	if _, err := jwt.Parse(assertion, func(*jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	}); err != nil {
+		t.Errorf("Failed to verify assertion signed with non-standard exponent: %v", err)
+	}
+
+	// An empty exponent falls back to 65537
+	jwk.E = ""
+	privateKey, err = jwkToRSAPrivateKey(jwk, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if privateKey.E != 65537 {
+		t.Errorf("Expected default public exponent 65537, got %d", privateKey.E)
+	}
+}
+
+func TestPEMPrivateKeySigning(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal PKCS#8 key: %v", err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal EC key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		block     *pem.Block
+		publicKey interface{}
+		alg       string
+	}{
+		{"PKCS#1 RSA", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, &rsaKey.PublicKey, "RS256"},
+		{"PKCS#8 RSA", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, &rsaKey.PublicKey, "RS256"},
+		{"SEC 1 EC", &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, &ecKey.PublicKey, "ES256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         "https://test.forgerock.com",
+					PrivateKey:       string(pem.EncodeToMemory(tt.block)),
+					KeyID:            "test-kid",
+				},
+			}
+
+			privateKey, method, err := generator.signingKey(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			assertion, err := generator.createJWTAssertion(privateKey, method, "test-jti")
+			if err != nil {
+				t.Fatalf("Failed to create assertion: %v", err)
+			}
+
+			parsed, err := jwt.Parse(assertion, func(*jwt.Token) (interface{}, error) {
+				return tt.publicKey, nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to verify assertion: %v", err)
+			}
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("Expected alg %s, got %s", tt.alg, parsed.Method.Alg())
+			}
+			if parsed.Header["kid"] != "test-kid" {
+				t.Errorf("Expected kid header 'test-kid', got %v", parsed.Header["kid"])
+			}
+		})
+	}
+}
+
+func TestPEMPrivateKeyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pem     string
+		wantErr string
+	}{
+		{"not PEM", "not a pem block", "no PEM block found"},
+		{"unsupported block", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})), "unsupported PEM block type"},
+		{"corrupt key", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("x")})), "failed to parse privateKey RSA PRIVATE KEY block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &ServiceAccountGenerator{Config: TokenConfig{PrivateKey: tt.pem}}
+			_, _, err := generator.signingKey(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAssertionCustomClaims(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	generator := &ServiceAccountGenerator{
+		Config: TokenConfig{
+			ServiceAccountID: "test-service-account",
+			Platform:         "https://test.forgerock.com",
+			CustomClaims:     map[string]interface{}{"tenant": "alpha", "level": 2},
+		},
+	}
+
+	assertion, err := generator.createJWTAssertion(privateKey, jwt.SigningMethodES256, "test-jti")
+	if err != nil {
+		t.Fatalf("Failed to create assertion: %v", err)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	}); err != nil {
+		t.Fatalf("Failed to verify assertion: %v", err)
+	}
+	if claims["tenant"] != "alpha" || claims["level"] != float64(2) {
+		t.Errorf("Expected custom claims in assertion, got %v", claims)
+	}
+	if claims["iss"] != "test-service-account" {
+		t.Errorf("Expected iss to be the service account, got %v", claims["iss"])
+	}
+
+	// Custom claims may not override required claims
+	for _, name := range []string{"iss", "sub", "aud", "exp", "jti"} {
+		generator.Config.CustomClaims = map[string]interface{}{name: "override"}
+		_, err := generator.createJWTAssertion(privateKey, jwt.SigningMethodES256, "test-jti")
+		if err == nil || !strings.Contains(err.Error(), "conflicts with a required assertion claim") {
+			t.Errorf("Expected conflict error for custom claim %q, got %v", name, err)
+		}
+	}
+}
+
+func TestAssertionAudience(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		audience string
+		realm    string
+		expected string
+	}{
+		{name: "default token endpoint", expected: "https://test.forgerock.com/am/oauth2/access_token"},
+		{name: "configured audience", audience: "https://test.forgerock.com/am/oauth2", expected: "https://test.forgerock.com/am/oauth2"},
+		{name: "realm token endpoint", realm: "root/alpha", expected: "https://test.forgerock.com/am/oauth2/realms/root/alpha/access_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         "https://test.forgerock.com",
+					Audience:         tt.audience,
+					Realm:            tt.realm,
+				},
+			}
+
+			assertion, err := generator.createJWTAssertion(privateKey, jwt.SigningMethodES256, "test-jti")
+			if err != nil {
+				t.Fatalf("Failed to create assertion: %v", err)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (interface{}, error) {
+				return &privateKey.PublicKey, nil
+			}); err != nil {
+				t.Fatalf("Failed to verify assertion: %v", err)
+			}
+			if claims["aud"] != tt.expected {
+				t.Errorf("Expected aud %s, got %v", tt.expected, claims["aud"])
+			}
+		})
+	}
+}
+
+func TestAssertionExpClamp(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   TokenConfig
+		expected int64
+	}{
+		{name: "default lifetime", config: TokenConfig{}, expected: 899},
+		{name: "within default maximum", config: TokenConfig{AssertionExpSeconds: 600}, expected: 600},
+		{name: "clamped to default maximum", config: TokenConfig{AssertionExpSeconds: 3600}, expected: 900},
+		{name: "independent of token lifetime", config: TokenConfig{ExpiresIn: time.Hour, ExpSeconds: 3600}, expected: 899},
+		{name: "configured maximum", config: TokenConfig{AssertionExpSeconds: 3600, MaxAssertionExpSeconds: 300}, expected: 300},
+		{name: "clock skew offset", config: TokenConfig{ClockSkewSeconds: 60}, expected: 959},
+		{name: "negative clock skew offset", config: TokenConfig{ClockSkewSeconds: -60}, expected: 839},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.ServiceAccountID = "test-service-account"
+			tt.config.Platform = "https://test.forgerock.com"
+			generator := &ServiceAccountGenerator{Config: tt.config}
+
+			before := time.Now().Unix()
+			assertion, err := generator.createJWTAssertion(privateKey, jwt.SigningMethodES256, "test-jti")
+			if err != nil {
+				t.Fatalf("Failed to create assertion: %v", err)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (interface{}, error) {
+				return &privateKey.PublicKey, nil
+			}, jwt.WithoutClaimsValidation()); err != nil {
+				t.Fatalf("Failed to verify assertion: %v", err)
+			}
+			lifetime := int64(claims["exp"].(float64)) - before
+			if lifetime < tt.expected || lifetime > tt.expected+1 {
+				t.Errorf("Expected assertion lifetime %ds, got %ds", tt.expected, lifetime)
+			}
+		})
+	}
+}
+
+func TestExchangeJWTForTokenClockSkew(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		clockSkew bool
+	}{
+		{name: "expired assertion", status: http.StatusBadRequest, body: `{"error":"invalid_grant","error_description":"JWT has expired"}`, clockSkew: true},
+		{name: "assertion not yet valid", status: http.StatusBadRequest, body: `{"error":"invalid_jwt","error_description":"JWT is not yet valid"}`, clockSkew: true},
+		{name: "other invalid grant", status: http.StatusBadRequest, body: `{"error":"invalid_grant","error_description":"Unknown service account"}`, clockSkew: false},
+		{name: "non-JSON body", status: http.StatusBadRequest, body: "expired", clockSkew: false},
+		{name: "server error", status: http.StatusServiceUnavailable, body: `{"error":"server_error","error_description":"clock service down"}`, clockSkew: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         server.URL,
+					Retries:          -1,
+				},
+			}
+
+			_, err := generator.exchangeJWTForToken(context.Background(), "signed-assertion")
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			var skewErr *ClockSkewError
+			if got := errors.As(err, &skewErr); got != tt.clockSkew {
+				t.Fatalf("Expected ClockSkewError %v, got %v (%v)", tt.clockSkew, got, err)
+			}
+			if !errors.Is(err, ErrTokenEndpoint) {
+				t.Errorf("Expected error to match ErrTokenEndpoint, got %v", err)
+			}
+			if tt.clockSkew && !strings.Contains(err.Error(), "system clock") {
+				t.Errorf("Expected clock hint in error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAssertionTimeClaims(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		config    TokenConfig
+		nbfOffset int64
+	}{
+		{name: "nbf defaults to iat", config: TokenConfig{}, nbfOffset: 0},
+		{name: "nbf skew", config: TokenConfig{NotBeforeSkewSeconds: 30}, nbfOffset: 30},
+		{name: "negative nbf skew ignored", config: TokenConfig{NotBeforeSkewSeconds: -30}, nbfOffset: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.ServiceAccountID = "test-service-account"
+			tt.config.Platform = "https://test.forgerock.com"
+			generator := &ServiceAccountGenerator{Config: tt.config}
+
+			before := time.Now().Unix()
+			assertion, err := generator.createJWTAssertion(privateKey, jwt.SigningMethodES256, "test-jti")
+			if err != nil {
+				t.Fatalf("Failed to create assertion: %v", err)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (interface{}, error) {
+				return &privateKey.PublicKey, nil
+			}, jwt.WithLeeway(time.Minute)); err != nil {
+				t.Fatalf("Failed to verify assertion: %v", err)
+			}
+
+			iat := int64(claims["iat"].(float64))
+			if iat < before || iat > before+1 {
+				t.Errorf("Expected iat near %d, got %d", before, iat)
+			}
+			if nbf := int64(claims["nbf"].(float64)); nbf != iat-tt.nbfOffset {
+				t.Errorf("Expected nbf %d, got %d", iat-tt.nbfOffset, nbf)
+			}
+			if exp := int64(claims["exp"].(float64)); exp != iat+int64(DefaultAssertionExp.Seconds()) {
+				t.Errorf("Expected exp %d, got %d", iat+int64(DefaultAssertionExp.Seconds()), exp)
+			}
+		})
+	}
+}
+
+func TestServiceAccountJTIMetadata(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	var sentJTI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(r.PostForm.Get("assertion"), claims); err != nil {
+			t.Fatalf("Failed to decode assertion: %v", err)
+		}
+		sentJTI, _ = claims["jti"].(string)
+		if _, ok := claims["jti"]; !ok {
+			sentJTI = "<none>"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"sa-token","token_type":"Bearer","expires_in":899}`))
+	}))
+	defer server.Close()
+
+	disabled := false
+	tests := []struct {
+		name       string
+		jti        string
+		includeJTI *bool
+	}{
+		{name: "random jti", jti: ""},
+		{name: "configured jti", jti: "fixed-jti"},
+		{name: "jti disabled", includeJTI: &disabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &ServiceAccountGenerator{
+				Config: TokenConfig{
+					ServiceAccountID: "test-service-account",
+					Platform:         server.URL,
+					PrivateKey:       string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+					JTI:              tt.jti,
+					IncludeJTI:       tt.includeJTI,
+				},
+			}
+
+			result, err := generator.Generate()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.includeJTI != nil {
+				if sentJTI != "<none>" {
+					t.Errorf("Expected assertion without a jti claim, got %q", sentJTI)
+				}
+				if _, ok := result.Metadata["jti"]; ok {
+					t.Errorf("Expected no jti in metadata, got %v", result.Metadata["jti"])
+				}
+				return
+			}
+			if sentJTI == "" || sentJTI == "<none>" {
+				t.Fatal("Expected assertion to carry a jti")
+			}
+			if tt.jti != "" && sentJTI != tt.jti {
+				t.Errorf("Expected jti %q, got %q", tt.jti, sentJTI)
+			}
+			if result.Metadata["jti"] != sentJTI {
+				t.Errorf("Expected metadata jti %q, got %v", sentJTI, result.Metadata["jti"])
+			}
+		})
+	}
+}
+
+func TestServiceAccountAssertionOnly(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	generator := &ServiceAccountGenerator{
+		Config: TokenConfig{
+			ServiceAccountID: "test-service-account",
+			Platform:         server.URL,
+			PrivateKey:       string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+			JTI:              "fixed-jti",
+			AssertionOnly:    true,
+			Fingerprint:      true,
+		},
+	}
+
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no token request, got %d", requests)
+	}
+	if result.TokenType != AssertionTokenType {
+		t.Errorf("Expected token type %q, got %q", AssertionTokenType, result.TokenType)
+	}
+	if result.Metadata["assertion_only"] != true {
+		t.Errorf("Expected assertion_only metadata, got %v", result.Metadata["assertion_only"])
+	}
+	// The metadata has the same shape as for an issued token
+	if _, ok := result.Metadata["generated_at"].(int64); !ok {
+		t.Errorf("Expected generated_at metadata, got %v", result.Metadata["generated_at"])
+	}
+	if result.Metadata["fingerprint"] != Fingerprint(result.AccessToken) {
+		t.Errorf("Expected fingerprint metadata, got %v", result.Metadata["fingerprint"])
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(result.AccessToken, claims, func(*jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	}); err != nil {
+		t.Fatalf("Expected a valid signed assertion: %v", err)
+	}
+	if claims["jti"] != "fixed-jti" || claims["sub"] != "test-service-account" {
+		t.Errorf("Unexpected assertion claims: %v", claims)
+	}
+	exp, _ := claims.GetExpirationTime()
+	if !result.ExpiresAt.Equal(exp.Time) {
+		t.Errorf("Expected ExpiresAt %v to match assertion exp %v", result.ExpiresAt, exp.Time)
+	}
+}
+
+func TestAssertionResultWithoutExpiry(t *testing.T) {
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "test-service-account"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("Failed to build assertion: %v", err)
+	}
+
+	generator := &ServiceAccountGenerator{Config: TokenConfig{ServiceAccountID: "test-service-account"}}
+	_, err = generator.assertionResult(assertion, "")
+	if err == nil || !strings.Contains(err.Error(), "assertion has no exp claim") {
+		t.Errorf("Expected missing exp claim error, got %v", err)
+	}
+}
+
+func TestServiceAccountTimingMetadata(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"sa-token","token_type":"Bearer","expires_in":899}`))
+	}))
+	defer server.Close()
+
+	generator := &ServiceAccountGenerator{
+		Config: TokenConfig{
+			ServiceAccountID: "test-service-account",
+			Platform:         server.URL,
+			PrivateKey:       string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+		},
+	}
+
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Metadata["attempts"] != 2 {
+		t.Errorf("Expected 2 attempts after one retry, got %v", result.Metadata["attempts"])
+	}
+	if duration, ok := result.Metadata["duration_ms"].(int64); !ok || duration < 0 {
+		t.Errorf("Expected a non-negative duration_ms, got %v", result.Metadata["duration_ms"])
+	}
+}
+
+func TestCheckAudience(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TokenConfig
+		wantErr bool
+	}{
+		{
+			name:   "no audience",
+			config: TokenConfig{Type: TokenTypeServiceAccount, Platform: "https://openam.example.com"},
+		},
+		{
+			name:   "matching audience",
+			config: TokenConfig{Type: TokenTypeServiceAccount, Platform: "https://openam.example.com", Audience: "https://openam.example.com/am/oauth2/access_token"},
+		},
+		{
+			name:   "matching audience with trailing slash and host case",
+			config: TokenConfig{Type: TokenTypeServiceAccount, Platform: "https://openam.example.com", Audience: "https://OpenAM.example.com/am/oauth2/access_token/"},
+		},
+		{
+			name:   "matching realm audience",
+			config: TokenConfig{Type: TokenTypeServiceAccount, Platform: "https://openam.example.com", Realm: "root/alpha", Audience: "https://openam.example.com/am/oauth2/realms/root/alpha/access_token"},
+		},
+		{
+			name:    "different host",
+			config:  TokenConfig{Type: TokenTypeServiceAccount, Platform: "https://openam.example.com", Audience: "https://other.example.com/am/oauth2/access_token"},
+			wantErr: true,
+		},
+		{
+			name:    "different realm",
+			config:  TokenConfig{Type: TokenTypeServiceAccount, Platform: "https://openam.example.com", Realm: "root/alpha", Audience: "https://openam.example.com/am/oauth2/access_token"},
+			wantErr: true,
+		},
+		{
+			name:   "token exchange target audience is not checked",
+			config: TokenConfig{Type: TokenTypeTokenExchange, Platform: "https://openam.example.com", Audience: "https://api.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAudience(tt.config)
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "does not match the token endpoint")) {
+				t.Errorf("Expected audience mismatch error, got %v", err)
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ParseSigningKey parses a JWK or PEM-encoded private key into a signing key
+// and the matching JWT signing method. The JWK takes precedence when both are
+// given. When jwkJSON is a JWK Set, keyID selects the key to use.
+func ParseSigningKey(jwkJSON, pemData, keyID string) (crypto.Signer, jwt.SigningMethod, error) {
+	key, method, _, err := parseSigningKey(jwkJSON, pemData, keyID, false)
+	return key, method, err
+}
+
+// parseSigningKey parses the JWK or PEM private key as ParseSigningKey does,
+// also returning the JWK fields that are not unpadded base64url. In strict
+// mode the RSA JWK components are checked for consistency.
+func parseSigningKey(jwkJSON, pemData, keyID string, strict bool) (crypto.Signer, jwt.SigningMethod, []string, error) {
+	switch {
+	case jwkJSON != "":
+		jwk, err := parseJWK(jwkJSON, keyID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		key, method, err := jwkSigningKey(jwk, strict)
+		return key, method, nonStandardJWKFields(jwk), err
+	case pemData != "":
+		key, method, err := pemToPrivateKey(pemData)
+		return key, method, nil, err
+	default:
+		return nil, nil, nil, fmt.Errorf("no signing key given: set a JWK or PEM private key")
+	}
+}
+
+// parseJWK parses a single JWK, or selects the key with kid keyID from a JWK Set
+func parseJWK(jwkJSON, keyID string) (*JWK, error) {
+	var set struct {
+		Keys json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(jwkJSON), &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWK: %w", err)
+	}
+
+	if set.Keys == nil {
+		var jwk JWK
+		if err := json.Unmarshal([]byte(jwkJSON), &jwk); err != nil {
+			return nil, fmt.Errorf("failed to parse JWK: %w", err)
+		}
+		return &jwk, nil
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal([]byte(jwkJSON), &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse JWK Set: %w", err)
+	}
+	key, ok := jwks.Key(keyID)
+	if !ok {
+		return nil, fmt.Errorf("no key with kid %q found in JWK Set", keyID)
+	}
+	return key, nil
+}
+
+// jwkEncodings are the base64 variants accepted for JWK fields, starting with
+// the unpadded base64url RFC 7518 requires. Some key-generation tools emit
+// padded or standard base64 instead.
+var jwkEncodings = []*base64.Encoding{
+	base64.RawURLEncoding,
+	base64.URLEncoding,
+	base64.RawStdEncoding,
+	base64.StdEncoding,
+}
+
+// decodeJWKField decodes a base64url JWK field, falling back to the padded
+// and standard base64 encodings
+func decodeJWKField(value string) ([]byte, error) {
+	var firstErr error
+	for _, encoding := range jwkEncodings {
+		decoded, err := encoding.DecodeString(value)
+		if err == nil {
+			return decoded, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// nonStandardJWKFields returns the names of JWK fields that are not
+// unpadded base64url but decode with one of the fallback encodings
+func nonStandardJWKFields(jwk *JWK) []string {
+	fields := [][2]string{
+		{"n", jwk.N}, {"e", jwk.E}, {"d", jwk.D}, {"p", jwk.P}, {"q", jwk.Q},
+		{"x", jwk.X}, {"y", jwk.Y},
+	}
+
+	var names []string
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if _, err := base64.RawURLEncoding.DecodeString(field[1]); err != nil {
+			names = append(names, field[0])
+		}
+	}
+	return names
+}
+
+// jwkSigningKey checks the JWK is complete, converts it to a private key and
+// selects the signing method from its key type. In strict mode RSA keys are
+// checked with checkRSAKey.
+func jwkSigningKey(jwk *JWK, strict bool) (crypto.Signer, jwt.SigningMethod, error) {
+	if err := validateJWK(jwk); err != nil {
+		return nil, nil, err
+	}
+
+	switch jwk.Kty {
+	case "EC":
+		key, err := ecPrivateKeyFromJWK(jwk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert JWK to EC private key: %w", err)
+		}
+		method, err := ecSigningMethod(key.Curve)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, method, nil
+	default:
+		key, err := rsaPrivateKeyFromJWK(jwk, strict)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert JWK to RSA private key: %w", err)
+		}
+		return key, jwt.SigningMethodRS256, nil
+	}
+}
+
+// validateJWK checks that the JWK has the private key fields required for its key type.
+// The RSA public exponent may be omitted and defaults to 65537.
+func validateJWK(jwk *JWK) error {
+	// Required fields as name/value pairs, in the order they are reported
+	var required [][2]string
+	switch jwk.Kty {
+	case "EC":
+		required = [][2]string{{"crv", jwk.Crv}, {"x", jwk.X}, {"y", jwk.Y}, {"d", jwk.D}}
+	case "RSA", "":
+		required = [][2]string{{"n", jwk.N}, {"d", jwk.D}, {"p", jwk.P}, {"q", jwk.Q}}
+	default:
+		return fmt.Errorf("unsupported JWK key type: %s", jwk.Kty)
+	}
+
+	for _, field := range required {
+		if field[1] == "" {
+			return fmt.Errorf("JWK missing required field: %s", field[0])
+		}
+	}
+	return nil
+}
+
+// pemToPrivateKey parses a PEM-encoded PKCS#1, PKCS#8 or SEC 1 private key
+func pemToPrivateKey(pemData string) (crypto.Signer, jwt.SigningMethod, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, nil, fmt.Errorf("failed to parse privateKey: no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, nil, fmt.Errorf("failed to parse privateKey: unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse privateKey %s block: %w", block.Type, err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		method, err := ecSigningMethod(k.Curve)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, method, nil
+	default:
+		return nil, nil, fmt.Errorf("failed to parse privateKey: unsupported key type %T", key)
+	}
+}
+
+// checkRSAKey checks that the modulus is the product of the primes and that
+// the private exponent inverts the public exponent modulo each prime minus one.
+// A key failing these checks still signs, but PAIC cannot verify its signatures.
+func checkRSAKey(key *rsa.PrivateKey) error {
+	one := big.NewInt(1)
+	product := new(big.Int).Set(one)
+	for _, prime := range key.Primes {
+		if prime.Cmp(one) <= 0 {
+			return fmt.Errorf("JWK prime must be greater than 1")
+		}
+		product.Mul(product, prime)
+	}
+	if product.Cmp(key.N) != 0 {
+		return fmt.Errorf("JWK modulus n does not match the product of primes p and q")
+	}
+
+	// d*e must be 1 modulo lcm(p-1, q-1), so 1 modulo each p-1
+	de := new(big.Int).Mul(key.D, big.NewInt(int64(key.E)))
+	for _, prime := range key.Primes {
+		pminus1 := new(big.Int).Sub(prime, one)
+		if new(big.Int).Mod(de, pminus1).Cmp(one) != 0 {
+			return fmt.Errorf("JWK private exponent d does not match public exponent e")
+		}
+	}
+	return nil
+}
+
+// rsaPrivateKeyFromJWK converts JWK to RSA private key. In strict mode the
+// modulus and private exponent are checked against the primes.
+func rsaPrivateKeyFromJWK(jwk *JWK, strict bool) (*rsa.PrivateKey, error) {
+	// Decode base64url components
+	n, err := decodeJWKField(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode modulus: %w", err)
+	}
+
+	d, err := decodeJWKField(jwk.D)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private exponent: %w", err)
+	}
+
+	p, err := decodeJWKField(jwk.P)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode first prime: %w", err)
+	}
+
+	q, err := decodeJWKField(jwk.Q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode second prime: %w", err)
+	}
+
+	// Decode public exponent, defaulting to the standard 65537 (AQAB) when absent
+	e := 65537
+	if jwk.E != "" {
+		eBytes, err := decodeJWKField(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode public exponent: %w", err)
+		}
+		eInt := new(big.Int).SetBytes(eBytes)
+		if !eInt.IsInt64() || eInt.Int64() < 2 || eInt.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid public exponent: %s", eInt)
+		}
+		e = int(eInt.Int64())
+	}
+
+	// Create big integers from byte arrays
+	nInt := new(big.Int).SetBytes(n)
+	dInt := new(big.Int).SetBytes(d)
+	pInt := new(big.Int).SetBytes(p)
+	qInt := new(big.Int).SetBytes(q)
+
+	// Create RSA private key
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{
+			N: nInt,
+			E: e,
+		},
+		D:      dInt,
+		Primes: []*big.Int{pInt, qInt},
+	}
+
+	if strict {
+		if err := checkRSAKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	// Precompute values for faster operations
+	key.Precompute()
+
+	return key, nil
+}
+
+// ecPrivateKeyFromJWK converts JWK to ECDSA private key
+func ecPrivateKeyFromJWK(jwk *JWK) (*ecdsa.PrivateKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported elliptic curve: %q (expected P-256, P-384 or P-521)", jwk.Crv)
+	}
+
+	// Decode base64url components
+	x, err := decodeJWKField(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode x coordinate: %w", err)
+	}
+
+	y, err := decodeJWKField(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode y coordinate: %w", err)
+	}
+
+	d, err := decodeJWKField(jwk.D)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+
+	// Create ECDSA private key
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, fmt.Errorf("public key point is not on curve %s", jwk.Crv)
+	}
+
+	return key, nil
+}
+
+// ecSigningMethod returns the ECDSA signing method matching the curve
+func ecSigningMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve.Params().Name {
+	case "P-256":
+		return jwt.SigningMethodES256, nil
+	case "P-384":
+		return jwt.SigningMethodES384, nil
+	case "P-521":
+		return jwt.SigningMethodES512, nil
+	default:
+		return nil, fmt.Errorf("unsupported elliptic curve: %s", curve.Params().Name)
+	}
+}
+
+// SigningAlgs lists the JWS algorithms signing_alg may select
+var SigningAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// signingMethodForAlg returns the signing method for the JWS algorithm,
+// checking that it suits the key: RSA keys sign with RS or PS algorithms,
+// and EC keys with the ES algorithm for their curve
+func signingMethodForAlg(key crypto.Signer, alg string) (jwt.SigningMethod, error) {
+	var allowed []string
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		allowed = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PrivateKey:
+		method, err := ecSigningMethod(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		allowed = []string{method.Alg()}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	if !slices.Contains(allowed, alg) {
+		return nil, fmt.Errorf("signing_alg %s does not match the %s key: must be %s", alg, keyTypeName(key), strings.Join(allowed, ", "))
+	}
+	return jwt.GetSigningMethod(alg), nil
+}
+
+// keyTypeName describes the key type and, for EC keys, the curve for error messages
+func keyTypeName(key crypto.Signer) string {
+	if k, ok := key.(*ecdsa.PrivateKey); ok {
+		return "EC " + k.Curve.Params().Name
+	}
+	return "RSA"
+}
+
+// jwkToRSAPrivateKey converts an RSA JWK through the same path as
+// ParseSigningKey, for callers that need the RSA key itself
+func jwkToRSAPrivateKey(jwk *JWK, strict bool) (*rsa.PrivateKey, error) {
+	key, _, err := jwkSigningKey(jwk, strict)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("JWK is not an RSA key: %s", jwk.Kty)
+	}
+	return rsaKey, nil
+}
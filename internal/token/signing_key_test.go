@@ -0,0 +1,258 @@
+package token
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseSigningKey(t *testing.T) {
+	jwkKey, jwk := ecJWK(t, elliptic.P256())
+	jwk.Kid = "key-1"
+	jwkJSON, err := json.Marshal(jwk)
+	if err != nil {
+		t.Fatalf("Failed to marshal JWK: %v", err)
+	}
+	_, other := ecJWK(t, elliptic.P384())
+	other.Kid = "key-2"
+	jwksJSON, err := json.Marshal(JWKS{Keys: []JWK{*other, *jwk}})
+	if err != nil {
+		t.Fatalf("Failed to marshal JWK Set: %v", err)
+	}
+
+	pemKey, _ := ecJWK(t, elliptic.P384())
+	der, err := x509.MarshalECPrivateKey(pemKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	pemData := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	tests := []struct {
+		name    string
+		jwkJSON string
+		pemData string
+		keyID   string
+		wantKey *ecdsa.PrivateKey
+		wantAlg string
+		wantErr string
+	}{
+		{name: "JWK", jwkJSON: string(jwkJSON), wantKey: jwkKey, wantAlg: "ES256"},
+		{name: "PEM", pemData: pemData, wantKey: pemKey, wantAlg: "ES384"},
+		{name: "JWK takes precedence over PEM", jwkJSON: string(jwkJSON), pemData: pemData, wantKey: jwkKey, wantAlg: "ES256"},
+		{name: "JWK Set selects key by kid", jwkJSON: string(jwksJSON), keyID: "key-1", wantKey: jwkKey, wantAlg: "ES256"},
+		{name: "JWK Set without matching kid", jwkJSON: string(jwksJSON), keyID: "missing", wantErr: `no key with kid "missing" found in JWK Set`},
+		{name: "incomplete JWK", jwkJSON: `{"kty":"EC","crv":"P-256"}`, wantErr: "JWK missing required field: x"},
+		{name: "invalid JSON", jwkJSON: "{not json", wantErr: "failed to parse JWK"},
+		{name: "no key", wantErr: "no signing key given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, method, err := ParseSigningKey(tt.jwkJSON, tt.pemData, tt.keyID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if method.Alg() != tt.wantAlg {
+				t.Errorf("Expected signing method %s, got %s", tt.wantAlg, method.Alg())
+			}
+			key, ok := signer.(*ecdsa.PrivateKey)
+			if !ok || !key.Equal(tt.wantKey) {
+				t.Errorf("Expected the parsed key to match the source key")
+			}
+		})
+	}
+}
+
+func TestDecodeJWKField(t *testing.T) {
+	want := []byte{0xfb, 0xff, 0xfe, 0x01}
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "base64url", value: base64.RawURLEncoding.EncodeToString(want)},
+		{name: "padded base64url", value: base64.URLEncoding.EncodeToString(want)},
+		{name: "standard base64", value: base64.RawStdEncoding.EncodeToString(want)},
+		{name: "padded standard base64", value: base64.StdEncoding.EncodeToString(want)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeJWKField(tt.value)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Expected %x, got %x", want, got)
+			}
+		})
+	}
+
+	if _, err := decodeJWKField("not base64!"); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+}
+
+func TestNonStandardJWKEncoding(t *testing.T) {
+	key, jwk := ecJWK(t, elliptic.P256())
+
+	// Re-encode the coordinates as padded standard base64
+	for _, field := range []*string{&jwk.X, &jwk.Y} {
+		decoded, err := base64.RawURLEncoding.DecodeString(*field)
+		if err != nil {
+			t.Fatalf("Failed to decode field: %v", err)
+		}
+		*field = base64.StdEncoding.EncodeToString(decoded)
+	}
+
+	if got := nonStandardJWKFields(jwk); len(got) != 2 || got[0] != "x" || got[1] != "y" {
+		t.Errorf("Expected x and y to be reported as non-standard, got %v", got)
+	}
+
+	jwkJSON, err := json.Marshal(jwk)
+	if err != nil {
+		t.Fatalf("Failed to marshal JWK: %v", err)
+	}
+	privateKey, _, nonStandard, err := parseSigningKey(string(jwkJSON), "", "", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(nonStandard) != 2 {
+		t.Errorf("Expected the parse to report x and y, got %v", nonStandard)
+	}
+	if !privateKey.(*ecdsa.PrivateKey).Equal(key) {
+		t.Error("Expected the parsed key to match the source key")
+	}
+}
+
+func TestJWKToRSAPrivateKeyStrict(t *testing.T) {
+	_, jwk := rsaJWKWithExponent(t, 65537)
+	_, other := rsaJWKWithExponent(t, 65537)
+
+	tests := []struct {
+		name    string
+		modify  func(jwk *JWK)
+		wantErr string
+	}{
+		{name: "consistent key", modify: func(*JWK) {}},
+		{name: "modulus from another key", modify: func(jwk *JWK) { jwk.N = other.N }, wantErr: "modulus n does not match"},
+		{name: "private exponent from another key", modify: func(jwk *JWK) { jwk.D = other.D }, wantErr: "private exponent d does not match"},
+		{name: "different public exponent", modify: func(jwk *JWK) { jwk.E = "Aw" }, wantErr: "private exponent d does not match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := *jwk
+			tt.modify(&modified)
+
+			// Without strict checks the key is accepted as given
+			if _, err := jwkToRSAPrivateKey(&modified, false); err != nil {
+				t.Fatalf("Unexpected error without strict checks: %v", err)
+			}
+
+			_, err := jwkToRSAPrivateKey(&modified, true)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// BenchmarkJWKToRSAPrivateKey measures converting an RSA JWK, including its
+// precomputation, for a key that signs once, as a one-shot CLI run does, and
+// for a key that signs several times. Skipping the precomputation made no
+// difference for one signature and was slower for several, so it is kept.
+func BenchmarkJWKToRSAPrivateKey(b *testing.B) {
+	_, jwk := rsaJWKWithExponent(b, 65537)
+
+	for _, signatures := range []int{1, 4} {
+		b.Run(fmt.Sprintf("signatures=%d", signatures), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				key, err := jwkToRSAPrivateKey(jwk, false)
+				if err != nil {
+					b.Fatalf("Unexpected error: %v", err)
+				}
+				for j := 0; j < signatures; j++ {
+					if _, err := jwt.New(jwt.SigningMethodRS256).SignedString(key); err != nil {
+						b.Fatalf("Failed to sign: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSigningMethodForAlg(t *testing.T) {
+	_, rsaJWK := rsaJWKWithExponent(t, 65537)
+	rsaKey, err := jwkToRSAPrivateKey(rsaJWK, false)
+	if err != nil {
+		t.Fatalf("Failed to convert RSA JWK: %v", err)
+	}
+	p256Key, _ := ecJWK(t, elliptic.P256())
+	p384Key, _ := ecJWK(t, elliptic.P384())
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		alg     string
+		wantErr string
+	}{
+		{name: "RSA RS256", key: rsaKey, alg: "RS256"},
+		{name: "RSA RS512", key: rsaKey, alg: "RS512"},
+		{name: "RSA PS384", key: rsaKey, alg: "PS384"},
+		{name: "RSA with EC alg", key: rsaKey, alg: "ES256", wantErr: "signing_alg ES256 does not match the RSA key"},
+		{name: "P-256 ES256", key: p256Key, alg: "ES256"},
+		{name: "P-384 ES384", key: p384Key, alg: "ES384"},
+		{name: "P-256 with P-384 alg", key: p256Key, alg: "ES384", wantErr: "does not match the EC P-256 key: must be ES256"},
+		{name: "EC with RSA alg", key: p384Key, alg: "RS256", wantErr: "does not match the EC P-384 key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, err := signingMethodForAlg(tt.key, tt.alg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// The assertion is signed and labelled with the selected algorithm
+			signed, err := jwt.New(method).SignedString(tt.key)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			parsed, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return tt.key.Public(), nil })
+			if err != nil {
+				t.Fatalf("Failed to verify: %v", err)
+			}
+			if parsed.Header["alg"] != tt.alg {
+				t.Errorf("Expected alg %s, got %v", tt.alg, parsed.Header["alg"])
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// tokenExchangeGrantType is the RFC 8693 token exchange grant type
+const tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// TokenExchangeGenerator handles delegation and impersonation tokens using
+// the OAuth 2.0 token exchange grant (RFC 8693)
+type TokenExchangeGenerator struct {
+	Config  TokenConfig
+	Verbose bool
+	Logger  *slog.Logger // Optional; defaults to debug output on stderr when Verbose
+}
+
+// Generate exchanges the subject token, and the actor token when set, for a new token
+func (g *TokenExchangeGenerator) Generate() (*TokenResult, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext exchanges the subject token, and the actor token when set,
+// for a new token, aborting the request when ctx is done
+func (g *TokenExchangeGenerator) GenerateContext(ctx context.Context) (*TokenResult, error) {
+	log := g.log()
+	log.Debug("generating token exchange token", "client_id", g.Config.ClientID, "actor", g.Config.ActorToken != "")
+
+	// Build token endpoint URL
+	tokenURL := tokenEndpointURL(g.Config)
+
+	// Prepare form data; the actor token makes this delegation rather than impersonation
+	data := url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {g.Config.SubjectToken},
+		"subject_token_type": {tokenTypeURI(g.Config.SubjectTokenType)},
+	}
+	if g.Config.ActorToken != "" {
+		data.Set("actor_token", g.Config.ActorToken)
+		data.Set("actor_token_type", tokenTypeURI(g.Config.ActorTokenType))
+	}
+	if g.Config.RequestedTokenType != "" {
+		data.Set("requested_token_type", g.Config.RequestedTokenType)
+	}
+	if g.Config.Audience != "" {
+		data.Set("audience", g.Config.Audience)
+	}
+	if scope := requestedScope(g.Config); scope != "" {
+		data.Set("scope", scope)
+	}
+	addResources(data, g.Config)
+	header := addClientCredentials(data, g.Config)
+	if g.Config.ClientAuthMethod() == ClientAuthPrivateKeyJWT {
+		if err := addClientAssertion(ctx, data, g.Config, tokenURL, log); err != nil {
+			return nil, fmt.Errorf("failed to create client assertion: %w", err)
+		}
+	}
+
+	log.Debug("making token request", "url", tokenURL, "grant_type", tokenExchangeGrantType, "scope", requestedScope(g.Config))
+
+	// Exchange the subject and actor tokens for a new token
+	tokenResponse, err := requestToken(ctx, g.Config, tokenURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	// Build result
+	result := newTokenResult(g.Config, tokenResponse, map[string]interface{}{
+		"client_id":         g.Config.ClientID,
+		"grant_type":        tokenExchangeGrantType,
+		"issued_token_type": tokenResponse.IssuedTokenType,
+	})
+
+	log.Debug("token exchange token generated", "expires_at", result.ExpiresAt, "issued_token_type", tokenResponse.IssuedTokenType)
+
+	return result, nil
+}
+
+// tokenTypeURI returns the token type identifier, defaulting to an access token
+func tokenTypeURI(tokenType string) string {
+	if tokenType == "" {
+		return TokenTypeURIAccessToken
+	}
+	return tokenType
+}
+
+// log returns the configured logger, falling back to the default for the verbosity
+func (g *TokenExchangeGenerator) log() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return logger.Default(g.Verbose)
+}
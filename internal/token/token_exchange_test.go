@@ -0,0 +1,110 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenExchangeGenerator(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            TokenConfig
+		expectedActor     string
+		expectedActorType string
+		expectedSubType   string
+	}{
+		{
+			name:            "impersonation",
+			config:          TokenConfig{SubjectToken: "subject-token"},
+			expectedSubType: TokenTypeURIAccessToken,
+		},
+		{
+			name: "delegation with actor",
+			config: TokenConfig{
+				SubjectToken:     "subject-token",
+				SubjectTokenType: TokenTypeURIIDToken,
+				ActorToken:       "actor-token",
+			},
+			expectedActor:     "actor-token",
+			expectedActorType: TokenTypeURIAccessToken,
+			expectedSubType:   TokenTypeURIIDToken,
+		},
+		{
+			name: "configured actor token type",
+			config: TokenConfig{
+				SubjectToken:   "subject-token",
+				ActorToken:     "actor-jwt",
+				ActorTokenType: TokenTypeURIJWT,
+			},
+			expectedActor:     "actor-jwt",
+			expectedActorType: TokenTypeURIJWT,
+			expectedSubType:   TokenTypeURIAccessToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("Failed to parse form: %v", err)
+				}
+				form := r.PostForm
+				if form.Get("grant_type") != tokenExchangeGrantType {
+					t.Errorf("Expected token exchange grant, got %s", form.Get("grant_type"))
+				}
+				if form.Get("subject_token") != "subject-token" {
+					t.Errorf("Expected subject_token 'subject-token', got %s", form.Get("subject_token"))
+				}
+				if form.Get("subject_token_type") != tt.expectedSubType {
+					t.Errorf("Expected subject_token_type %s, got %s", tt.expectedSubType, form.Get("subject_token_type"))
+				}
+				if form.Get("actor_token") != tt.expectedActor {
+					t.Errorf("Expected actor_token %q, got %q", tt.expectedActor, form.Get("actor_token"))
+				}
+				if form.Get("actor_token_type") != tt.expectedActorType {
+					t.Errorf("Expected actor_token_type %q, got %q", tt.expectedActorType, form.Get("actor_token_type"))
+				}
+				if form.Get("client_id") != "test-client" || form.Get("client_secret") != "test-secret" {
+					t.Errorf("Expected client credentials, got %v", form)
+				}
+				if form.Get("audience") != "https://api.example.com" {
+					t.Errorf("Expected audience, got %s", form.Get("audience"))
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token":      "exchanged-access-token",
+					"issued_token_type": TokenTypeURIAccessToken,
+					"token_type":        "Bearer",
+					"expires_in":        300,
+				})
+			}))
+			defer server.Close()
+
+			config := tt.config
+			config.Platform = server.URL
+			config.ClientID = "test-client"
+			config.ClientSecret = "test-secret"
+			config.Audience = "https://api.example.com"
+
+			generator := &TokenExchangeGenerator{Config: config}
+			result, err := generator.GenerateContext(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result.AccessToken != "exchanged-access-token" {
+				t.Errorf("Expected exchanged access token, got %s", result.AccessToken)
+			}
+			if result.Metadata["issued_token_type"] != TokenTypeURIAccessToken {
+				t.Errorf("Expected issued_token_type in metadata, got %v", result.Metadata["issued_token_type"])
+			}
+			if _, ok := result.Metadata["raw_response"]; ok {
+				t.Errorf("Expected issued_token_type to be a known response field, got raw_response %v", result.Metadata["raw_response"])
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package token
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// requestTrace records the phase timings of one HTTP request attempt
+type requestTrace struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// traceRequest returns the request with an httptrace.ClientTrace recording its phase timings
+func traceRequest(req *http.Request) (*http.Request, *requestTrace) {
+	t := &requestTrace{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotConn:              func(info httptrace.GotConnInfo) { t.reused = info.Reused },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// log writes the phase timings at info level. Phases that did not happen,
+// such as DNS and connect on a reused connection, are left out.
+func (t *requestTrace) log(log *slog.Logger, req *http.Request, status int) {
+	attrs := []any{"method", req.Method, "url", req.URL.Redacted(), "reused_connection", t.reused}
+	if status != 0 {
+		attrs = append(attrs, "status", status)
+	}
+	for _, phase := range []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"dns", t.dnsStart, t.dnsDone},
+		{"connect", t.connectStart, t.connectDone},
+		{"tls", t.tlsStart, t.tlsDone},
+		{"first_byte", t.start, t.firstByte},
+	} {
+		if !phase.start.IsZero() && !phase.end.IsZero() {
+			attrs = append(attrs, phase.name, phase.end.Sub(phase.start))
+		}
+	}
+	attrs = append(attrs, "total", time.Since(t.start))
+	log.Info("request timing", attrs...)
+}
@@ -1,6 +1,8 @@
 package token
 
 import (
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -8,23 +10,72 @@ import (
 type TokenType string
 
 const (
-	TokenTypeServiceAccount TokenType = "service-account"
-	TokenTypeUser           TokenType = "user"
-	TokenTypeCustom         TokenType = "custom"
+	TokenTypeServiceAccount    TokenType = "service-account"
+	TokenTypeUser              TokenType = "user"
+	TokenTypeCustom            TokenType = "custom"
+	TokenTypeAuthorizationCode TokenType = "authorization-code" // Interactive browser login with PKCE
+	TokenTypeTokenExchange     TokenType = "token-exchange"     // RFC 8693 token exchange for delegation and impersonation
+	TokenTypeDeviceCode        TokenType = "device-code"        // RFC 8628 device authorization for machines without a browser
 )
 
+// Client authentication methods for sending clientId and clientSecret to PAIC
+const (
+	ClientAuthSecretPost    = "client_secret_post"  // Credentials in the form body
+	ClientAuthSecretBasic   = "client_secret_basic" // Credentials in an HTTP Basic Authorization header
+	ClientAuthPrivateKeyJWT = "private_key_jwt"     // A client assertion JWT signed with the configured key
+)
+
+// RFC 8693 token type identifiers for the subject, actor and requested tokens of a token exchange
+const (
+	TokenTypeURIAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeURIRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeURIIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeURIJWT          = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// AssertionTokenType is the token type of results holding a service account
+// JWT assertion rather than an access token (RFC 8693 token type identifier)
+const AssertionTokenType = TokenTypeURIJWT
+
+// DefaultHTTPTimeout is the HTTP timeout used when timeout_seconds is not set
+const DefaultHTTPTimeout = 30 * time.Second
+
+// DefaultAssertionExp is the JWT assertion lifetime used when assertion_exp_seconds is not set
+const DefaultAssertionExp = 899 * time.Second
+
+// DefaultMaxAssertionExp caps the JWT assertion lifetime when max_assertion_exp_seconds is not set
+const DefaultMaxAssertionExp = 900 * time.Second
+
+// ConfigAPIVersion is the current token configuration layout
+const ConfigAPIVersion = "pctl/v1"
+
 // TokenConfig represents the configuration for token generation
 type TokenConfig struct {
+	// Configuration layout; unset for the older authflow-compatible layout
+	APIVersion string `yaml:"apiVersion" json:"apiVersion"`
+
 	// Token type
 	Type TokenType `yaml:"type" json:"type"`
 	
 	// PAIC connection details
 	BaseURL      string `yaml:"baseUrl" json:"baseUrl"`
 	Platform     string `yaml:"platform" json:"platform"` // Alternative name for baseUrl
+	Realm        string `yaml:"realm" json:"realm"`       // OAuth 2.0 realm path such as "root/alpha"; the default realm endpoints are used when unset
+	TokenEndpoint string `yaml:"token_endpoint" json:"token_endpoint"` // Token endpoint URL used verbatim, e.g. behind a gateway that rewrites paths; derived from the platform URL and realm when unset
 	Username     string `yaml:"username" json:"username"`
 	Password     string `yaml:"password" json:"password"`
 	ClientID     string `yaml:"clientId" json:"clientId"`
 	ClientSecret string `yaml:"clientSecret" json:"clientSecret"`
+	RefreshToken string `yaml:"refreshToken" json:"refreshToken"` // Used by the refresh token grant
+
+	TokenEndpointAuthMethod string `yaml:"token_endpoint_auth_method" json:"token_endpoint_auth_method"` // client_secret_post (default), client_secret_basic or private_key_jwt
+
+	// Multi-factor user authentication
+	OTP               string `yaml:"otp" json:"otp"`                                 // One-time password for authentication tree callbacks; logs in through the tree
+	AuthTree          string `yaml:"auth_tree" json:"auth_tree"`                     // Authentication tree to log in through, such as a 2FA tree; the realm default when only otp is set
+	RedirectURI       string `yaml:"redirect_uri" json:"redirect_uri"`               // OAuth client redirect URI for the authorization code exchange
+	NoBrowser         bool   `yaml:"no_browser" json:"no_browser"`                   // Print the authorization-code login URL instead of opening a browser
+	SessionCookieName string `yaml:"session_cookie_name" json:"session_cookie_name"` // PAIC session cookie name, defaults to iPlanetDirectoryPro
 	
 	// Service Account specific
 	ServiceAccountID   string `yaml:"service_account_id" json:"service_account_id"`
@@ -32,24 +83,154 @@ type TokenConfig struct {
 	PrivateKey         string `yaml:"privateKey" json:"privateKey"`
 	KeyID              string `yaml:"keyId" json:"keyId"`
 	JWKJson            string `yaml:"jwk_json" json:"jwk_json"` // JWK as JSON string
+	JWKFile            string `yaml:"jwk_file" json:"jwk_file"` // Path to a file containing the JWK
+	JWKSURL            string `yaml:"jwks_url" json:"jwks_url"` // JWKS to fetch the key matching keyId from
+	StrictKey          bool   `yaml:"strict_key" json:"strict_key"` // Check that the RSA JWK modulus and private exponent match its primes
+	SigningAlg         string `yaml:"signing_alg" json:"signing_alg"` // Assertion JWS algorithm such as RS384 or PS256; follows from the key type when unset
+
+	// Token exchange (RFC 8693); token types default to an access token
+	SubjectToken       string `yaml:"subject_token" json:"subject_token"`               // Token representing the user the new token acts for
+	SubjectTokenType   string `yaml:"subject_token_type" json:"subject_token_type"`     // Token type URI of subject_token
+	ActorToken         string `yaml:"actor_token" json:"actor_token"`                   // Token of the party acting on the subject's behalf, added as the act claim
+	ActorTokenType     string `yaml:"actor_token_type" json:"actor_token_type"`         // Token type URI of actor_token
+	RequestedTokenType string `yaml:"requested_token_type" json:"requested_token_type"` // Token type URI to request; PAIC chooses when unset
 	
 	// Token properties
-	Audience  string        `yaml:"audience" json:"audience"`
+	Audience  string        `yaml:"audience" json:"audience"` // Assertion aud claim, defaulting to the token endpoint URL PAIC expects; the target audience for token exchange
 	Issuer    string        `yaml:"issuer" json:"issuer"`
 	Subject   string        `yaml:"subject" json:"subject"`
-	ExpiresIn time.Duration `yaml:"expiresIn" json:"expiresIn"`
+	ExpiresIn time.Duration `yaml:"expiresIn" json:"expiresIn"` // Requested access token lifetime, as a duration such as "30m" or "1h", or seconds
 	ExpSeconds int          `yaml:"exp_seconds" json:"exp_seconds"` // Alternative expiry format
+	StrictExpiry bool       `yaml:"strict_expiry" json:"strict_expiry"` // Reject exp_seconds and expiresIn set to different lifetimes, rather than warning
+	StrictAudience bool     `yaml:"strict_audience" json:"strict_audience"` // Reject a service account audience that does not match the token endpoint, rather than warning
+
+	// Service account JWT assertion lifetime. The assertion is only presented to
+	// the token endpoint, so this is independent of the access token lifetime.
+	AssertionExpSeconds    int `yaml:"assertion_exp_seconds" json:"assertion_exp_seconds"`         // Assertion exp claim, defaults to 899 seconds
+	MaxAssertionExpSeconds int `yaml:"max_assertion_exp_seconds" json:"max_assertion_exp_seconds"` // Cap on the assertion exp PAIC accepts, defaults to 900 seconds
+	ClockSkewSeconds       int `yaml:"clock_skew_seconds" json:"clock_skew_seconds"`               // Added to the assertion time claims; positive when the local clock is behind
+	NotBeforeSkewSeconds   int `yaml:"nbf_skew_seconds" json:"nbf_skew_seconds"`                   // How far before iat the assertion nbf is set, defaults to 0
+
+	JTI        string `yaml:"jti" json:"jti"`                 // Fixed assertion jti for deterministic testing; random when unset
+	IncludeJTI *bool  `yaml:"include_jti" json:"include_jti"` // Add a jti claim to the assertion, enabled when unset
+
+	AssertionOnly bool `yaml:"assertion_only" json:"assertion_only"` // Return the signed service account assertion without exchanging it
+
 	Scopes    []string      `yaml:"scopes" json:"scopes"`
 	Scope     string        `yaml:"scope" json:"scope"` // Alternative single scope format
+
+	Resource []string `yaml:"resource" json:"resource"` // RFC 8707 resource indicators sent with service account and custom token requests
+
+	RequireScope bool `yaml:"require_scope" json:"require_scope"` // Reject user and custom token requests without a scope
 	
 	// Output and behavior
 	OutputFormat string `yaml:"output_format" json:"output_format"`
 	Verbose      bool   `yaml:"verbose" json:"verbose"`
-	VerifySSL    bool   `yaml:"verify_ssl" json:"verify_ssl"`
+	VerifySSL    *bool  `yaml:"verify_ssl" json:"verify_ssl"` // TLS certificate verification, enabled when unset
 	Proxy        string `yaml:"proxy" json:"proxy"`
+	UserAgent    string `yaml:"user_agent" json:"user_agent"` // User-Agent for requests to PAIC, defaults to pctl/<version>
+	Fingerprint  bool   `yaml:"fingerprint" json:"fingerprint"` // Add the access token SHA-256 to the result metadata
+	Trace        bool   `yaml:"trace" json:"trace"`             // Log DNS, connect, TLS and first byte timings of each request at info level
+
+	AllowInsecureURL bool `yaml:"allow_insecure_url" json:"allow_insecure_url"` // Permit a plain http platform URL
+
+	ClientCertFile string `yaml:"client_cert_file" json:"client_cert_file"` // PEM client certificate for mutual TLS with PAIC
+	ClientKeyFile  string `yaml:"client_key_file" json:"client_key_file"`   // PEM private key of client_cert_file
+
+	Headers map[string]string `yaml:"headers" json:"headers"` // Additional headers for requests to PAIC; headers pctl sets are not replaced
+
+	// HTTP client behavior
+	TimeoutSeconds      int `yaml:"timeout_seconds" json:"timeout_seconds"`               // HTTP timeout, defaults to 30 seconds
+	Retries             int `yaml:"retries" json:"retries"`                               // Retries for transient failures, defaults to 3; negative disables
+	RetryMaxWaitSeconds int `yaml:"retry_max_wait_seconds" json:"retry_max_wait_seconds"` // Maximum wait between retries, defaults to 30 seconds
 	
 	// Custom claims
 	CustomClaims map[string]interface{} `yaml:"customClaims" json:"customClaims"`
+
+	// Warnings collected while loading the configuration
+	Warnings []string `yaml:"-" json:"-"`
+
+	// Shared HTTP client for requests to PAIC, so connections are reused across
+	// token generations. Its transport replaces the configured TLS and proxy
+	// settings; the configured timeout applies when it has none.
+	HTTPClient *http.Client `yaml:"-" json:"-"`
+}
+
+// SSLVerificationEnabled reports whether TLS certificates should be verified.
+// Verification is on unless verify_ssl is explicitly set to false.
+func (c TokenConfig) SSLVerificationEnabled() bool {
+	return c.VerifySSL == nil || *c.VerifySSL
+}
+
+// JTIEnabled reports whether the service account assertion has a jti claim.
+// The claim is included unless include_jti is explicitly set to false.
+func (c TokenConfig) JTIEnabled() bool {
+	return c.IncludeJTI == nil || *c.IncludeJTI
+}
+
+// HTTPTimeout returns the configured HTTP timeout, falling back to DefaultHTTPTimeout
+func (c TokenConfig) HTTPTimeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return DefaultHTTPTimeout
+}
+
+// MaxRetries returns the number of retries for transient failures
+func (c TokenConfig) MaxRetries() int {
+	switch {
+	case c.Retries < 0:
+		return 0
+	case c.Retries == 0:
+		return DefaultRetries
+	default:
+		return c.Retries
+	}
+}
+
+// RetryMaxWait returns the maximum wait between retries, falling back to DefaultRetryMaxWait
+func (c TokenConfig) RetryMaxWait() time.Duration {
+	if c.RetryMaxWaitSeconds > 0 {
+		return time.Duration(c.RetryMaxWaitSeconds) * time.Second
+	}
+	return DefaultRetryMaxWait
+}
+
+// ClientAuthMethod returns the client authentication method, falling back to ClientAuthSecretPost
+func (c TokenConfig) ClientAuthMethod() string {
+	if c.TokenEndpointAuthMethod == "" {
+		return ClientAuthSecretPost
+	}
+	return c.TokenEndpointAuthMethod
+}
+
+// AssertionExp returns the JWT assertion lifetime, falling back to DefaultAssertionExp
+func (c TokenConfig) AssertionExp() time.Duration {
+	if c.AssertionExpSeconds > 0 {
+		return time.Duration(c.AssertionExpSeconds) * time.Second
+	}
+	return DefaultAssertionExp
+}
+
+// ClockSkew returns the offset added to the assertion time claims
+func (c TokenConfig) ClockSkew() time.Duration {
+	return time.Duration(c.ClockSkewSeconds) * time.Second
+}
+
+// NotBeforeSkew returns how far before iat the assertion nbf claim is set
+func (c TokenConfig) NotBeforeSkew() time.Duration {
+	if c.NotBeforeSkewSeconds > 0 {
+		return time.Duration(c.NotBeforeSkewSeconds) * time.Second
+	}
+	return 0
+}
+
+// MaxAssertionExp returns the maximum JWT assertion lifetime, falling back to DefaultMaxAssertionExp
+func (c TokenConfig) MaxAssertionExp() time.Duration {
+	if c.MaxAssertionExpSeconds > 0 {
+		return time.Duration(c.MaxAssertionExpSeconds) * time.Second
+	}
+	return DefaultMaxAssertionExp
 }
 
 // TokenResult represents the result of token generation
@@ -60,5 +241,33 @@ type TokenResult struct {
 	ExpiresAt    time.Time              `json:"expires_at" yaml:"expires_at"`
 	Scope        string                 `json:"scope,omitempty" yaml:"scope,omitempty"`
 	RefreshToken string                 `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+	IDToken      string                 `json:"id_token,omitempty" yaml:"id_token,omitempty"` // OpenID Connect ID token, when the openid scope was granted
 	Metadata     map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
-}
\ No newline at end of file
+	Decoded      *DecodedToken          `json:"decoded,omitempty" yaml:"decoded,omitempty"` // Access token claims, when decoding was requested
+}
+
+// Bearer returns the Authorization header value for the access token, using
+// the issued token type. An empty or differently cased "bearer" type is
+// normalized to "Bearer".
+func (r *TokenResult) Bearer() string {
+	tokenType := r.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + r.AccessToken
+}
+
+// IsExpired reports whether the token has expired.
+// A zero ExpiresAt is treated as unknown and therefore expired.
+func (r *TokenResult) IsExpired() bool {
+	return r.ExpiresWithin(0)
+}
+
+// ExpiresWithin reports whether the token expires within d from now.
+// A zero ExpiresAt is treated as unknown and therefore expiring.
+func (r *TokenResult) ExpiresWithin(d time.Duration) bool {
+	if r.ExpiresAt.IsZero() {
+		return true
+	}
+	return !time.Now().Add(d).Before(r.ExpiresAt)
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenResultExpiry(t *testing.T) {
+	tests := []struct {
+		name            string
+		expiresAt       time.Time
+		within          time.Duration
+		wantExpired     bool
+		wantExpiresSoon bool
+	}{
+		{
+			name:            "valid token",
+			expiresAt:       time.Now().Add(time.Hour),
+			within:          time.Minute,
+			wantExpired:     false,
+			wantExpiresSoon: false,
+		},
+		{
+			name:            "expires within window",
+			expiresAt:       time.Now().Add(30 * time.Second),
+			within:          time.Minute,
+			wantExpired:     false,
+			wantExpiresSoon: true,
+		},
+		{
+			name:            "expired token",
+			expiresAt:       time.Now().Add(-time.Minute),
+			within:          time.Minute,
+			wantExpired:     true,
+			wantExpiresSoon: true,
+		},
+		{
+			name:            "unknown expiry",
+			expiresAt:       time.Time{},
+			within:          0,
+			wantExpired:     true,
+			wantExpiresSoon: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &TokenResult{AccessToken: "test-token", ExpiresAt: tt.expiresAt}
+
+			if got := result.IsExpired(); got != tt.wantExpired {
+				t.Errorf("IsExpired() = %v, want %v", got, tt.wantExpired)
+			}
+			if got := result.ExpiresWithin(tt.within); got != tt.wantExpiresSoon {
+				t.Errorf("ExpiresWithin(%s) = %v, want %v", tt.within, got, tt.wantExpiresSoon)
+			}
+		})
+	}
+}
+
+func TestTokenResultBearer(t *testing.T) {
+	tests := []struct {
+		tokenType string
+		want      string
+	}{
+		{tokenType: "Bearer", want: "Bearer test-token"},
+		{tokenType: "bearer", want: "Bearer test-token"},
+		{tokenType: "", want: "Bearer test-token"},
+		{tokenType: "DPoP", want: "DPoP test-token"},
+	}
+
+	for _, tt := range tests {
+		result := &TokenResult{AccessToken: "test-token", TokenType: tt.tokenType}
+		if got := result.Bearer(); got != tt.want {
+			t.Errorf("Bearer() with token type %q = %q, want %q", tt.tokenType, got, tt.want)
+		}
+	}
+}
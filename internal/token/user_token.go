@@ -1,49 +1,109 @@
 package token
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"log/slog"
+	"net/url"
+
+	"github.com/aaronwang/pctl/internal/logger"
 )
 
 // UserTokenGenerator handles user authentication token generation
 type UserTokenGenerator struct {
 	Config  TokenConfig
 	Verbose bool
+	Logger  *slog.Logger // Optional; defaults to debug output on stderr when Verbose
+
+	// OTPPrompt is called for a one-time password when the auth_tree
+	// authentication tree requires one and Config.OTP is empty. When nil,
+	// Config.OTP is required.
+	OTPPrompt func(prompt string) (string, error)
 }
 
-// Generate generates a user authentication token
+// Generate generates a user authentication token using the OAuth 2.0 password grant
 func (g *UserTokenGenerator) Generate() (*TokenResult, error) {
-	if g.Verbose {
-		fmt.Printf("Generating user token for: %s\n", g.Config.Username)
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext generates a user authentication token using the OAuth 2.0 password grant, aborting the request when ctx is done
+func (g *UserTokenGenerator) GenerateContext(ctx context.Context) (*TokenResult, error) {
+	log := g.log()
+	log.Debug("generating user token", "username", g.Config.Username)
+
+	// A one-time password or authentication tree needs the tree login, as the
+	// password grant cannot answer multi-factor callbacks
+	if g.Config.OTP != "" || g.Config.AuthTree != "" {
+		log.Debug("authenticating through the authentication tree", "tree", g.Config.AuthTree)
+		tokenResponse, err := g.exchangeAuthTree(ctx, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to exchange credentials for token: %w", err)
+		}
+		return g.result(tokenResponse, "authorization_code"), nil
 	}
 
-	// TODO: Implement actual user token generation
-	// This would involve:
-	// 1. Making an OAuth 2.0 password grant request to PAIC
-	// 2. Handling MFA if required
-	// 3. Parsing the response and returning the token
-
-	// For now, return a mock token for testing
-	now := time.Now()
-	expiresIn := int64(g.Config.ExpiresIn.Seconds())
-	
-	result := &TokenResult{
-		AccessToken:  "mock_user_token_" + g.Config.Username,
-		TokenType:    "Bearer",
-		ExpiresIn:    expiresIn,
-		ExpiresAt:    now.Add(g.Config.ExpiresIn),
-		Scope:        "openid profile email",
-		RefreshToken: "mock_refresh_token_" + g.Config.Username,
-		Metadata: map[string]interface{}{
-			"username":     g.Config.Username,
-			"generated_at": now.Unix(),
-			"grant_type":   "password",
-		},
+	// Build token endpoint URL
+	tokenURL := tokenEndpointURL(g.Config)
+
+	// Prepare form data
+	data := url.Values{
+		"grant_type": {"password"},
+		"username":   {g.Config.Username},
+		"password":   {g.Config.Password},
+		"scope":      {requestedScope(g.Config)},
 	}
+	header := addClientCredentials(data, g.Config)
 
-	if g.Verbose {
-		fmt.Printf("User token generated successfully, expires at: %s\n", result.ExpiresAt.Format(time.RFC3339))
+	log.Debug("making token request", "url", tokenURL, "grant_type", "password", "scope", requestedScope(g.Config))
+
+	// Exchange user credentials for access token
+	tokenResponse, err := requestToken(ctx, g.Config, tokenURL, data, header, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange credentials for token: %w", err)
 	}
 
-	return result, nil
-}
\ No newline at end of file
+	return g.result(tokenResponse, "password"), nil
+}
+
+// result builds the token result for the grant that issued the token
+func (g *UserTokenGenerator) result(tokenResponse *PaicTokenResponse, grantType string) *TokenResult {
+	result := newTokenResult(g.Config, tokenResponse, map[string]interface{}{
+		"username":   g.Config.Username,
+		"grant_type": grantType,
+	})
+
+	g.log().Debug("user token generated", "expires_at", result.ExpiresAt)
+
+	return result
+}
+
+// exchangeAuthTree logs in through the authentication tree and exchanges the
+// resulting session for an access token using the authorization code grant
+func (g *UserTokenGenerator) exchangeAuthTree(ctx context.Context, log *slog.Logger) (*PaicTokenResponse, error) {
+	tokenID, err := g.completeAuthTree(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := g.authorizationCode(ctx, tokenID, log)
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {g.Config.RedirectURI},
+	}
+	header := addClientCredentials(data, g.Config)
+
+	return requestToken(ctx, g.Config, tokenEndpointURL(g.Config), data, header, log)
+}
+
+// log returns the configured logger, falling back to the default for the verbosity
+func (g *UserTokenGenerator) log() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return logger.Default(g.Verbose)
+}
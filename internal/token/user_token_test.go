@@ -0,0 +1,130 @@
+package token
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserTokenGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/am/oauth2/access_token" {
+			t.Errorf("Expected token endpoint path, got %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("grant_type") != "password" {
+			t.Errorf("Expected grant_type 'password', got %s", r.PostForm.Get("grant_type"))
+		}
+		if r.PostForm.Get("username") != "testuser" || r.PostForm.Get("password") != "testpass" {
+			t.Errorf("Unexpected credentials: %s/%s", r.PostForm.Get("username"), r.PostForm.Get("password"))
+		}
+		if r.PostForm.Get("client_id") != "test-client" {
+			t.Errorf("Expected client_id 'test-client', got %s", r.PostForm.Get("client_id"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "user-access-token",
+			"refresh_token": "user-refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3599,
+			"scope":         "openid profile",
+		})
+	}))
+	defer server.Close()
+
+	generator := &UserTokenGenerator{
+		Config: TokenConfig{
+			Type:         TokenTypeUser,
+			Platform:     server.URL,
+			Username:     "testuser",
+			Password:     "testpass",
+			ClientID:     "test-client",
+			ClientSecret: "test-secret",
+			Scope:        "openid profile",
+		},
+	}
+
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.AccessToken != "user-access-token" {
+		t.Errorf("Expected access token 'user-access-token', got %s", result.AccessToken)
+	}
+	if result.RefreshToken != "user-refresh-token" {
+		t.Errorf("Expected refresh token 'user-refresh-token', got %s", result.RefreshToken)
+	}
+	if result.ExpiresAt.IsZero() {
+		t.Error("Expected ExpiresAt to be set")
+	}
+}
+
+func TestUserTokenGenerateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_grant","error_description":"Authentication failed"}`))
+	}))
+	defer server.Close()
+
+	generator := &UserTokenGenerator{
+		Config: TokenConfig{
+			Type:     TokenTypeUser,
+			Platform: server.URL,
+			Username: "testuser",
+			Password: "wrong",
+		},
+	}
+
+	_, err := generator.Generate()
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "status 401") || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Expected error to include status and body, got: %v", err)
+	}
+}
+
+func TestUserTokenGenerateLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "user-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3599,
+		})
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	generator := &UserTokenGenerator{
+		Config: TokenConfig{
+			Type:     TokenTypeUser,
+			Platform: server.URL,
+			Username: "testuser",
+			Password: "testpass",
+		},
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	if _, err := generator.Generate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{`"msg":"generating user token"`, `"username":"testuser"`, `"msg":"access token received"`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected log output to contain %s, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "user-access-token") {
+		t.Error("Expected access token to be kept out of the log")
+	}
+}
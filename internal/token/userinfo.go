@@ -0,0 +1,44 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/aaronwang/pctl/internal/logger"
+)
+
+// UserInfo holds the claims returned by the PAIC userinfo endpoint
+type UserInfo map[string]interface{}
+
+// GetUserInfo returns the claims about the identity the access token represents
+// from the PAIC userinfo endpoint
+func GetUserInfo(ctx context.Context, config TokenConfig, accessToken string, log *slog.Logger) (UserInfo, error) {
+	userInfoURL := oauth2EndpointURL(config, "userinfo")
+
+	log = logger.OrDiscard(log)
+	log.Debug("making userinfo request", "url", userInfoURL)
+
+	resp, body, err := get(ctx, config, userInfoURL, http.Header{
+		"Authorization": {"Bearer " + accessToken},
+		"Accept":        {"application/json"},
+	}, log)
+	if err != nil {
+		return nil, fmt.Errorf("userinfo request failed: %w", err)
+	}
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var info UserInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse userinfo response: %w", err)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,42 @@
+// Package version holds the pctl build version.
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// defaultVersion is the release version reported when neither ldflags nor
+// the module build information provide one, as for go build in a checkout
+const defaultVersion = "0.1.0"
+
+// Version is the pctl release version, set at build time with
+//
+//	go build -ldflags "-X github.com/aaronwang/pctl/internal/version.Version=1.2.3"
+//
+// Without it, the module version recorded by go install is used.
+var Version = defaultVersion
+
+func init() {
+	if Version != defaultVersion {
+		return
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		Version = moduleVersion(info.Main.Version)
+	}
+}
+
+// moduleVersion returns the release version for a module version from the
+// build information, such as v1.2.3, falling back to the default for
+// development builds
+func moduleVersion(version string) string {
+	if version == "" || version == "(devel)" {
+		return defaultVersion
+	}
+	return strings.TrimPrefix(version, "v")
+}
+
+// UserAgent returns the default User-Agent sent with requests to PAIC
+func UserAgent() string {
+	return "pctl/" + Version
+}
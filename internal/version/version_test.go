@@ -0,0 +1,20 @@
+package version
+
+import "testing"
+
+func TestModuleVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{version: "v1.2.3", want: "1.2.3"},
+		{version: "v0.0.0-20261016120000-abcdef123456", want: "0.0.0-20261016120000-abcdef123456"},
+		{version: "(devel)", want: defaultVersion},
+		{version: "", want: defaultVersion},
+	}
+	for _, tt := range tests {
+		if got := moduleVersion(tt.version); got != tt.want {
+			t.Errorf("moduleVersion(%q): expected %q, got %q", tt.version, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package token
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// DefaultBatchConcurrency is the number of tokens GenerateBatch generates at once when concurrency is not positive
+const DefaultBatchConcurrency = 4
+
+// BatchResult is the outcome of generating the token for one configuration in a batch
+type BatchResult struct {
+	Result *token.TokenResult
+	Err    error
+}
+
+// batchEntry is a batch result as rendered in JSON and YAML output
+type batchEntry struct {
+	Name  string             `json:"name" yaml:"name"`
+	Token *token.TokenResult `json:"token,omitempty" yaml:"token,omitempty"`
+	Error string             `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// GenerateBatch generates a token for each configuration, with at most concurrency
+// generations in flight. Options other than Config apply to every generation.
+// Results are returned in the order of configs. Without an HTTPClient in the
+// options, configurations with the same TLS and proxy settings share one.
+func GenerateBatch(ctx context.Context, options GeneratorOptions, configs []token.TokenConfig, concurrency int) []BatchResult {
+	return GenerateBatchFunc(ctx, options, configs, concurrency, nil)
+}
+
+// GenerateBatchFunc generates tokens as GenerateBatch does, also calling
+// done with the index of each configuration and its result as soon as it
+// completes, so results can be streamed. Calls to done are not concurrent.
+// done may be nil.
+func GenerateBatchFunc(ctx context.Context, options GeneratorOptions, configs []token.TokenConfig, concurrency int, done func(i int, result BatchResult)) []BatchResult {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	httpClients := batchHTTPClients(options, configs)
+
+	results := make([]BatchResult, len(configs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var doneMu sync.Mutex
+	for i := range configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			clientOptions := options
+			clientOptions.Config = configs[i]
+			clientOptions.HTTPClient = httpClients[i]
+			result, err := NewClient(clientOptions).GenerateContext(ctx)
+			results[i] = BatchResult{Result: result, Err: err}
+			if done != nil {
+				doneMu.Lock()
+				defer doneMu.Unlock()
+				done(i, results[i])
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// batchHTTPClients returns the HTTP client for each configuration: the one in
+// the options, or one shared by the configurations with equal TLS and proxy
+// settings. A configuration whose client cannot be created gets nil, so its
+// generation reports the error.
+func batchHTTPClients(options GeneratorOptions, configs []token.TokenConfig) []*http.Client {
+	clients := make([]*http.Client, len(configs))
+	shared := make(map[string]*http.Client)
+	for i, config := range configs {
+		if options.HTTPClient != nil || config.HTTPClient != nil {
+			clients[i] = cmp.Or(options.HTTPClient, config.HTTPClient)
+			continue
+		}
+		key := token.HTTPClientKey(config)
+		client, ok := shared[key]
+		if !ok {
+			var err error
+			if client, err = token.NewHTTPClient(config); err == nil {
+				// Leave the timeout to each configuration
+				client.Timeout = 0
+			} else {
+				client = nil
+			}
+			shared[key] = client
+		}
+		clients[i] = client
+	}
+	return clients
+}
+
+// FormatBatch formats batch results, labelled by the corresponding names,
+// according to the specified format
+func (c *Client) FormatBatch(names []string, results []BatchResult) (string, error) {
+	entries := make([]batchEntry, len(results))
+	for i, result := range results {
+		entries[i] = batchEntry{Name: names[i], Token: result.Result}
+		if result.Err != nil {
+			entries[i].Error = result.Err.Error()
+		}
+	}
+	if c.options.OutputFormat == OutputFormatJSONL {
+		return formatJSONLines(entries)
+	}
+	if output, ok, err := c.formatStructured(entries); ok {
+		return output, err
+	}
+
+	var output strings.Builder
+	switch c.options.OutputFormat {
+	case OutputFormatExport:
+		return "", fmt.Errorf("export output is not supported for batches")
+
+	case OutputFormatRaw:
+		// One token per line; failures are left to the caller to report
+		for _, entry := range entries {
+			if entry.Token != nil {
+				output.WriteString(entry.Token.AccessToken + "\n")
+			}
+		}
+
+	default:
+		for i, entry := range entries {
+			if i > 0 {
+				output.WriteString("\n")
+			}
+			if entry.Error != "" {
+				output.WriteString(fmt.Sprintf("%s: failed: %s\n", entry.Name, entry.Error))
+				continue
+			}
+			formatted, err := c.FormatOutput(entry.Token)
+			if err != nil {
+				return "", err
+			}
+			output.WriteString(entry.Name + ":\n" + formatted)
+		}
+	}
+	return output.String(), nil
+}
+
+// cacheRefreshEntry is the outcome of caching the token for one configuration,
+// without the token itself
+type cacheRefreshEntry struct {
+	Name      string `json:"name" yaml:"name"`
+	Cached    bool   `json:"cached" yaml:"cached"`
+	ExpiresAt string `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// FormatCacheRefresh reports whether the token for each configuration, labelled
+// by the corresponding names, was cached, according to the specified format.
+// The tokens themselves are never included.
+func (c *Client) FormatCacheRefresh(names []string, results []BatchResult) (string, error) {
+	entries := make([]cacheRefreshEntry, len(results))
+	for i, result := range results {
+		entries[i] = cacheRefreshEntry{Name: names[i], Cached: result.Err == nil}
+		if result.Err != nil {
+			entries[i].Error = result.Err.Error()
+		} else {
+			entries[i].ExpiresAt = result.Result.ExpiresAt.Format(time.RFC3339)
+		}
+	}
+	if c.options.OutputFormat == OutputFormatJSONL {
+		return formatJSONLines(entries)
+	}
+	if output, ok, err := c.formatStructured(entries); ok {
+		return output, err
+	}
+
+	var output strings.Builder
+	for i, entry := range entries {
+		if entry.Error != "" {
+			output.WriteString(fmt.Sprintf("%s: failed: %s\n", entry.Name, entry.Error))
+			continue
+		}
+		output.WriteString(fmt.Sprintf("%s: cached, expires at %s\n", entry.Name, c.formatTime(results[i].Result.ExpiresAt)))
+	}
+	return output.String(), nil
+}
+
+// formatJSONLines renders each entry as a compact JSON object on its own line
+func formatJSONLines[T any](entries []T) (string, error) {
+	var output strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		output.Write(data)
+		output.WriteString("\n")
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,213 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestGenerateBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		r.ParseForm()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + r.PostForm.Get("client_id"),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer server.Close()
+
+	var configs []token.TokenConfig
+	for _, clientID := range []string{"a", "b", "c", "d", "e", "f"} {
+		config := customClientConfig(server)
+		config.ClientID = clientID
+		configs = append(configs, config)
+	}
+	configs[3].ClientSecret = ""
+
+	results := GenerateBatch(context.Background(), GeneratorOptions{}, configs, 2)
+
+	if len(results) != len(configs) {
+		t.Fatalf("Expected %d results, got %d", len(configs), len(results))
+	}
+	for i, result := range results {
+		if i == 3 {
+			if result.Err == nil || !containsString(result.Err.Error(), "clientSecret is required") {
+				t.Errorf("Expected validation error for result 3, got %v", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("Unexpected error for result %d: %v", i, result.Err)
+		}
+		if want := "token-" + configs[i].ClientID; result.Result.AccessToken != want {
+			t.Errorf("Expected %s at index %d, got %s", want, i, result.Result.AccessToken)
+		}
+	}
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", maxInFlight)
+	}
+}
+
+func TestGenerateBatchFunc(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		// Later configurations finish first
+		if r.PostForm.Get("client_id") == "a" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + r.PostForm.Get("client_id"),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer server.Close()
+
+	var configs []token.TokenConfig
+	for _, clientID := range []string{"a", "b", "c"} {
+		config := customClientConfig(server)
+		config.ClientID = clientID
+		configs = append(configs, config)
+	}
+
+	var order []int
+	results := GenerateBatchFunc(context.Background(), GeneratorOptions{}, configs, 3, func(i int, result BatchResult) {
+		if result.Err != nil {
+			t.Errorf("Unexpected error for result %d: %v", i, result.Err)
+		} else if want := "token-" + configs[i].ClientID; result.Result.AccessToken != want {
+			t.Errorf("Expected %s for index %d, got %s", want, i, result.Result.AccessToken)
+		}
+		order = append(order, i)
+	})
+
+	if len(order) != len(configs) || len(results) != len(configs) {
+		t.Fatalf("Expected %d results, got %d streamed and %d returned", len(configs), len(order), len(results))
+	}
+	if order[len(order)-1] != 0 {
+		t.Errorf("Expected results in completion order with the slow configuration last, got %v", order)
+	}
+}
+
+func TestBatchHTTPClients(t *testing.T) {
+	disabled := false
+	configs := []token.TokenConfig{
+		{Platform: "https://a.example.com"},
+		{Platform: "https://b.example.com", TimeoutSeconds: 5},
+		{Platform: "https://c.example.com", VerifySSL: &disabled},
+		{Platform: "https://d.example.com", Proxy: "http://proxy.example.com:3128"},
+		{Platform: "https://e.example.com", Proxy: "http://proxy.example.com:3128"},
+	}
+
+	clients := batchHTTPClients(GeneratorOptions{}, configs)
+	if clients[0] == nil || clients[0] != clients[1] {
+		t.Error("Expected configurations with the same TLS and proxy settings to share a client")
+	}
+	if clients[0] == clients[2] || clients[0] == clients[3] || clients[2] == clients[3] {
+		t.Error("Expected configurations with different TLS or proxy settings to have their own client")
+	}
+	if clients[3] != clients[4] {
+		t.Error("Expected configurations with the same proxy to share a client")
+	}
+	if clients[0].Timeout != 0 {
+		t.Errorf("Expected shared clients to leave the timeout to each configuration, got %s", clients[0].Timeout)
+	}
+
+	shared := &http.Client{}
+	for i, client := range batchHTTPClients(GeneratorOptions{HTTPClient: shared}, configs) {
+		if client != shared {
+			t.Errorf("Expected configuration %d to use the client from the options", i)
+		}
+	}
+}
+
+func TestFormatBatch(t *testing.T) {
+	names := []string{"a.yaml", "b.yaml"}
+	results := []BatchResult{
+		{Result: &token.TokenResult{AccessToken: "token-a", TokenType: "Bearer"}},
+		{Err: context.DeadlineExceeded},
+	}
+
+	tests := []struct {
+		format  OutputFormat
+		want    []string
+		wantErr bool
+	}{
+		{format: OutputFormatText, want: []string{"a.yaml:\nToken Generation Result:", "Access Token: ...\n", "b.yaml: failed: context deadline exceeded"}},
+		{format: OutputFormatJSON, want: []string{`"name": "a.yaml"`, `"access_token": "token-a"`, `"error": "context deadline exceeded"`}},
+		{format: OutputFormatYAML, want: []string{"- name: a.yaml", "access_token: token-a", "error: context deadline exceeded"}},
+		{format: OutputFormatRaw, want: []string{"token-a\n"}},
+		{format: OutputFormatJSONL, want: []string{`{"name":"a.yaml","token":{"access_token":"token-a","token_type":"Bearer",`, "}}\n" + `{"name":"b.yaml","error":"context deadline exceeded"}` + "\n"}},
+		{format: OutputFormatExport, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			output, err := NewClient(GeneratorOptions{OutputFormat: tt.format}).FormatBatch(names, results)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !containsString(output, want) {
+					t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatCacheRefresh(t *testing.T) {
+	names := []string{"a.yaml", "b.yaml"}
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	results := []BatchResult{
+		{Result: &token.TokenResult{AccessToken: "token-a", TokenType: "Bearer", ExpiresAt: expiresAt}},
+		{Err: context.DeadlineExceeded},
+	}
+
+	tests := []struct {
+		format OutputFormat
+		want   []string
+	}{
+		{format: OutputFormatText, want: []string{"a.yaml: cached, expires at 2030-01-02T03:04:05Z", "b.yaml: failed: context deadline exceeded"}},
+		{format: OutputFormatJSON, want: []string{`"name": "a.yaml"`, `"cached": true`, `"expires_at": "2030-01-02T03:04:05Z"`, `"cached": false`, `"error": "context deadline exceeded"`}},
+		{format: OutputFormatYAML, want: []string{"- name: a.yaml", "cached: true", "error: context deadline exceeded"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			output, err := NewClient(GeneratorOptions{OutputFormat: tt.format, TimeFormat: TimeFormatRFC3339}).FormatCacheRefresh(names, results)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !containsString(output, want) {
+					t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+				}
+			}
+			if containsString(output, "token-a") {
+				t.Errorf("Expected output without the token, got:\n%s", output)
+			}
+		})
+	}
+}
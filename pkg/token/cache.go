@@ -0,0 +1,331 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// DefaultCacheBuffer is how close to expiry a cached token may get before it is regenerated
+const DefaultCacheBuffer = 30 * time.Second
+
+// FileCache stores generated tokens on disk, one file per configuration
+type FileCache struct {
+	Dir    string
+	Buffer time.Duration
+}
+
+// NewFileCache creates a token cache in dir, defaulting to ~/.pctl/cache when dir is empty
+func NewFileCache(dir string, buffer time.Duration) (*FileCache, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine cache directory: %w", err)
+		}
+		dir = filepath.Join(home, ".pctl", "cache")
+	}
+
+	return &FileCache{
+		Dir:    dir,
+		Buffer: buffer,
+	}, nil
+}
+
+// CacheKey returns the cache key for a configuration, derived from the
+// service account, platform, scope and resource indicators the token is issued
+// for, and the key, audience and client authentication it is obtained with
+func CacheKey(c *token.TokenConfig) string {
+	platform := c.BaseURL
+	if platform == "" {
+		platform = c.Platform
+	}
+	scope := c.Scope
+	if scope == "" {
+		scope = strings.Join(c.Scopes, " ")
+	}
+
+	parts := []string{
+		string(c.Type),
+		c.ServiceAccountID,
+		c.Username,
+		c.ClientID,
+		platform,
+		scope,
+	}
+	// Only key on resources when set, so existing cache entries stay valid
+	if len(c.Resource) > 0 {
+		parts = append(parts, strings.Join(c.Resource, " "))
+	}
+	// Tokens issued for different assertion claims may carry different grants
+	if len(c.CustomClaims) > 0 {
+		if claims, err := json.Marshal(c.CustomClaims); err == nil {
+			parts = append(parts, "claims="+string(claims))
+		}
+	}
+	// Tokens from different realms are not interchangeable
+	if c.Realm != "" {
+		parts = append(parts, "realm="+c.Realm)
+	}
+	if c.TokenEndpoint != "" {
+		parts = append(parts, "token_endpoint="+c.TokenEndpoint)
+	}
+	// Tokens obtained with a different signing key, assertion audience or
+	// client authentication are not interchangeable
+	if c.KeyID != "" {
+		parts = append(parts, "key_id="+c.KeyID)
+	}
+	if c.Audience != "" && c.Type != token.TokenTypeTokenExchange {
+		parts = append(parts, "audience="+c.Audience)
+	}
+	if c.TokenEndpointAuthMethod != "" {
+		parts = append(parts, "auth_method="+c.TokenEndpointAuthMethod)
+	}
+	// Exchanged tokens act for a specific subject and actor
+	if c.Type == token.TokenTypeTokenExchange {
+		parts = append(parts, c.SubjectToken, c.ActorToken, c.RequestedTokenType, c.Audience)
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// Get returns the cached token for the configuration if it is not within the buffer of expiring,
+// with ExpiresIn set to the seconds remaining. Missing, unreadable or corrupt cache entries are treated as a cache miss.
+func (fc *FileCache) Get(c *token.TokenConfig) (*token.TokenResult, bool) {
+	data, err := os.ReadFile(fc.path(c))
+	if err != nil {
+		return nil, false
+	}
+
+	var result token.TokenResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false
+	}
+
+	if result.AccessToken == "" || result.ExpiresWithin(fc.Buffer) {
+		return nil, false
+	}
+
+	// Report the remaining lifetime, not the lifetime when it was issued
+	result.ExpiresIn = int64(time.Until(result.ExpiresAt) / time.Second)
+	return &result, true
+}
+
+// Put stores the token for the configuration in the cache
+func (fc *FileCache) Put(c *token.TokenConfig, result *token.TokenResult) error {
+	if err := os.MkdirAll(fc.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheFile{TokenResult: *result, Cache: describeCacheKey(c)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := WriteFileAtomic(fc.path(c), data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the cache file path for the configuration
+func (fc *FileCache) path(c *token.TokenConfig) string {
+	return filepath.Join(fc.Dir, CacheKey(c)+".json")
+}
+
+// cacheFile is a cache entry as stored on disk: the token result, which is
+// all Get reads, and what the token was issued for, so List can describe it
+type cacheFile struct {
+	token.TokenResult
+	Cache *cacheKeyDescription `json:"cache,omitempty"`
+}
+
+// cacheKeyDescription holds the readable parts of the cache key
+type cacheKeyDescription struct {
+	Type     string `json:"type,omitempty"`
+	Account  string `json:"account,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// describeCacheKey returns the readable parts of the configuration's cache key
+func describeCacheKey(c *token.TokenConfig) *cacheKeyDescription {
+	description := &cacheKeyDescription{
+		Type:     string(c.Type),
+		Account:  c.ServiceAccountID,
+		Platform: c.BaseURL,
+		Scope:    c.Scope,
+	}
+	for _, account := range []string{c.Username, c.ClientID} {
+		if description.Account == "" {
+			description.Account = account
+		}
+	}
+	if description.Platform == "" {
+		description.Platform = c.Platform
+	}
+	if description.Scope == "" {
+		description.Scope = strings.Join(c.Scopes, " ")
+	}
+	return description
+}
+
+// CacheEntry describes a cached token without the token itself
+type CacheEntry struct {
+	Key       string    `json:"key" yaml:"key"`
+	Type      string    `json:"type,omitempty" yaml:"type,omitempty"`
+	Account   string    `json:"account,omitempty" yaml:"account,omitempty"`   // Service account ID, username or client ID
+	Platform  string    `json:"platform,omitempty" yaml:"platform,omitempty"` // Unknown for entries cached by older versions, except service account tokens
+	Scope     string    `json:"scope,omitempty" yaml:"scope,omitempty"`
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+	Valid     bool      `json:"valid" yaml:"valid"` // Whether Get would return the token
+	Error     string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// List describes the cache entries, ordered by key. Entries that cannot be
+// read are listed as invalid with the error. An empty or missing cache
+// directory has no entries.
+func (fc *FileCache) List() ([]CacheEntry, error) {
+	paths, err := fc.entryPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, 0, len(paths))
+	for _, path := range paths {
+		entry := CacheEntry{Key: strings.TrimSuffix(filepath.Base(path), ".json")}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+
+		var file cacheFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			entry.Error = "corrupt cache entry: " + err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+
+		// Entries cached by older versions only have the result metadata
+		description := file.Cache
+		if description == nil {
+			description = describeCachedResult(&file.TokenResult)
+		}
+		entry.Type = description.Type
+		entry.Account = description.Account
+		entry.Platform = description.Platform
+		entry.Scope = description.Scope
+		if entry.Scope == "" {
+			entry.Scope = file.Scope
+		}
+		entry.ExpiresAt = file.ExpiresAt
+		entry.Valid = file.AccessToken != "" && !file.ExpiresWithin(fc.Buffer)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// describeCachedResult describes a cache entry stored without a description
+// from the account and platform in the result metadata
+func describeCachedResult(result *token.TokenResult) *cacheKeyDescription {
+	description := &cacheKeyDescription{}
+	for _, field := range []string{"service_account_id", "username", "client_id"} {
+		if account, ok := result.Metadata[field].(string); ok && description.Account == "" {
+			description.Account = account
+		}
+	}
+	description.Platform, _ = result.Metadata["platform"].(string)
+	return description
+}
+
+// Clear removes every cache entry, returning how many were removed
+func (fc *FileCache) Clear() (int, error) {
+	paths, err := fc.entryPaths()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return i, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+	}
+	return len(paths), nil
+}
+
+// entryPaths returns the paths of the cache entry files, ordered by key
+func (fc *FileCache) entryPaths() ([]string, error) {
+	files, err := os.ReadDir(fc.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			paths = append(paths, filepath.Join(fc.Dir, file.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// FormatCacheList formats cache entries according to the specified format.
+// Raw output lists one cache key per line.
+func (c *Client) FormatCacheList(entries []CacheEntry) (string, error) {
+	if c.options.OutputFormat == OutputFormatJSONL {
+		return formatJSONLines(entries)
+	}
+	if output, ok, err := c.formatStructured(entries); ok {
+		return output, err
+	}
+
+	var output strings.Builder
+	switch c.options.OutputFormat {
+	case OutputFormatExport:
+		return "", fmt.Errorf("export output is not supported for cache entries")
+
+	case OutputFormatRaw:
+		for _, entry := range entries {
+			output.WriteString(entry.Key + "\n")
+		}
+
+	default:
+		for _, entry := range entries {
+			if entry.Error != "" {
+				output.WriteString(fmt.Sprintf("%s: unreadable: %s\n", entry.Key, entry.Error))
+				continue
+			}
+			status := "expired"
+			if entry.Valid {
+				status = "valid"
+			}
+			var described []string
+			for _, part := range []string{entry.Type, entry.Account, entry.Platform, entry.Scope} {
+				if part != "" {
+					described = append(described, part)
+				}
+			}
+			output.WriteString(fmt.Sprintf("%s: %s, expires at %s", entry.Key, status, c.formatTime(entry.ExpiresAt)))
+			if len(described) > 0 {
+				output.WriteString(" (" + strings.Join(described, ", ") + ")")
+			}
+			output.WriteString("\n")
+		}
+	}
+	return output.String(), nil
+}
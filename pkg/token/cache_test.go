@@ -0,0 +1,328 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestFileCache(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir(), DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	config := &token.TokenConfig{
+		Type:             token.TokenTypeServiceAccount,
+		ServiceAccountID: "test-id",
+		Platform:         "https://test.forgerock.com",
+		Scope:            "fr:am:*",
+	}
+
+	if _, ok := cache.Get(config); ok {
+		t.Fatal("Expected cache miss for empty cache")
+	}
+
+	result := &token.TokenResult{
+		AccessToken: "cached-token",
+		TokenType:   "Bearer",
+		ExpiresIn:   3600,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := cache.Put(config, result); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+
+	cached, ok := cache.Get(config)
+	if !ok {
+		t.Fatal("Expected cache hit after Put")
+	}
+	if cached.AccessToken != "cached-token" {
+		t.Errorf("Expected cached token 'cached-token', got %s", cached.AccessToken)
+	}
+
+	info, err := os.Stat(cache.path(config))
+	if err != nil {
+		t.Fatalf("Failed to stat cache file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected cache file permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// A different scope must not share the cache entry
+	other := *config
+	other.Scope = "fr:idm:*"
+	if _, ok := cache.Get(&other); ok {
+		t.Error("Expected cache miss for different scope")
+	}
+}
+
+func TestFileCacheExpiry(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir(), DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	config := &token.TokenConfig{ServiceAccountID: "test-id", Platform: "https://test.forgerock.com"}
+	result := &token.TokenResult{
+		AccessToken: "expiring-token",
+		ExpiresAt:   time.Now().Add(10 * time.Second),
+	}
+	if err := cache.Put(config, result); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+
+	if _, ok := cache.Get(config); ok {
+		t.Error("Expected token within the buffer of expiry to be a cache miss")
+	}
+}
+
+func TestFileCacheRemainingExpiresIn(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir(), DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	// Issued for an hour, with ten minutes left
+	config := &token.TokenConfig{ServiceAccountID: "test-id", Platform: "https://test.forgerock.com"}
+	result := &token.TokenResult{
+		AccessToken: "aging-token",
+		ExpiresIn:   3600,
+		ExpiresAt:   time.Now().Add(10 * time.Minute),
+	}
+	if err := cache.Put(config, result); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+
+	cached, ok := cache.Get(config)
+	if !ok {
+		t.Fatal("Expected cache hit")
+	}
+	if cached.ExpiresIn > 600 || cached.ExpiresIn < 595 {
+		t.Errorf("Expected ExpiresIn to be the remaining 600 seconds, got %d", cached.ExpiresIn)
+	}
+}
+
+func TestFileCacheCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewFileCache(dir, DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	config := &token.TokenConfig{ServiceAccountID: "test-id", Platform: "https://test.forgerock.com"}
+	if err := os.WriteFile(filepath.Join(dir, CacheKey(config)+".json"), []byte("{not json"), 0600); err != nil {
+		t.Fatalf("Failed to write corrupt cache file: %v", err)
+	}
+
+	if _, ok := cache.Get(config); ok {
+		t.Error("Expected corrupt cache entry to be ignored")
+	}
+}
+
+func TestCacheKeyResource(t *testing.T) {
+	config := &token.TokenConfig{
+		Type:             token.TokenTypeServiceAccount,
+		ServiceAccountID: "test-id",
+		Platform:         "https://test.forgerock.com",
+	}
+	withResource := *config
+	withResource.Resource = []string{"https://api.example.com"}
+
+	if CacheKey(config) == CacheKey(&withResource) {
+		t.Error("Expected resource indicators to change the cache key")
+	}
+
+	withClaims := *config
+	withClaims.CustomClaims = map[string]interface{}{"level": int64(3)}
+	if CacheKey(config) == CacheKey(&withClaims) {
+		t.Error("Expected custom claims to change the cache key")
+	}
+
+	withRealm := *config
+	withRealm.Realm = "root/alpha"
+	if CacheKey(config) == CacheKey(&withRealm) {
+		t.Error("Expected the realm to change the cache key")
+	}
+
+	withTokenEndpoint := *config
+	withTokenEndpoint.TokenEndpoint = "https://gateway.example.com/oauth/token"
+	if CacheKey(config) == CacheKey(&withTokenEndpoint) {
+		t.Error("Expected the token endpoint to change the cache key")
+	}
+
+	otherKey := *config
+	otherKey.KeyID = "other-key"
+	withKey := *config
+	withKey.KeyID = "signing-key"
+	if CacheKey(config) == CacheKey(&withKey) || CacheKey(&withKey) == CacheKey(&otherKey) {
+		t.Error("Expected the key ID to change the cache key")
+	}
+
+	withAudience := *config
+	withAudience.Audience = "https://gateway.example.com/oauth/token"
+	if CacheKey(config) == CacheKey(&withAudience) {
+		t.Error("Expected the audience to change the cache key")
+	}
+
+	withAuthMethod := *config
+	withAuthMethod.TokenEndpointAuthMethod = token.ClientAuthSecretBasic
+	if CacheKey(config) == CacheKey(&withAuthMethod) {
+		t.Error("Expected the client authentication method to change the cache key")
+	}
+
+	// Keys for configurations without resources are unchanged
+	hash := sha256.Sum256([]byte(strings.Join([]string{"service-account", "test-id", "", "", "https://test.forgerock.com", ""}, "\x00")))
+	if got := CacheKey(config); got != hex.EncodeToString(hash[:]) {
+		t.Errorf("Expected cache key without resources to be unchanged, got %q", got)
+	}
+}
+
+func TestCacheKeyTokenExchange(t *testing.T) {
+	config := &token.TokenConfig{
+		Type:         token.TokenTypeTokenExchange,
+		ClientID:     "test-client",
+		SubjectToken: "subject-one",
+		Platform:     "https://test.forgerock.com",
+	}
+	otherSubject := *config
+	otherSubject.SubjectToken = "subject-two"
+	withActor := *config
+	withActor.ActorToken = "actor-token"
+
+	if CacheKey(config) == CacheKey(&otherSubject) {
+		t.Error("Expected the subject token to change the cache key")
+	}
+	if CacheKey(config) == CacheKey(&withActor) {
+		t.Error("Expected the actor token to change the cache key")
+	}
+}
+
+func TestFileCacheList(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir(), DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	entries, err := cache.List()
+	if err != nil {
+		t.Fatalf("Failed to list empty cache: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("Expected no entries, got %d", len(entries))
+	}
+
+	valid := &token.TokenConfig{
+		Type:             token.TokenTypeServiceAccount,
+		ServiceAccountID: "valid-id",
+		Platform:         "https://test.forgerock.com",
+		Scope:            "fr:am:*",
+	}
+	expired := &token.TokenConfig{
+		Type:     token.TokenTypeCustom,
+		ClientID: "expired-client",
+		BaseURL:  "https://other.forgerock.com",
+		Scopes:   []string{"fr:idm:*", "fr:am:*"},
+	}
+	if err := cache.Put(valid, &token.TokenResult{AccessToken: "valid-token", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+	if err := cache.Put(expired, &token.TokenResult{AccessToken: "expired-token", ExpiresAt: time.Now().Add(10 * time.Second)}); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+	// Entries cached by older versions are described from the result metadata
+	legacy := `{"access_token":"legacy-token","expires_at":"2000-01-01T00:00:00Z","scope":"fr:am:*","metadata":{"service_account_id":"legacy-id","platform":"https://legacy.forgerock.com"}}`
+	if err := os.WriteFile(filepath.Join(cache.Dir, "legacy.json"), []byte(legacy), 0600); err != nil {
+		t.Fatalf("Failed to write legacy entry: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cache.Dir, "corrupt.json"), []byte("{not json"), 0600); err != nil {
+		t.Fatalf("Failed to write corrupt entry: %v", err)
+	}
+
+	entries, err = cache.List()
+	if err != nil {
+		t.Fatalf("Failed to list cache: %v", err)
+	}
+	byKey := make(map[string]CacheEntry)
+	for _, entry := range entries {
+		byKey[entry.Key] = entry
+	}
+	if len(byKey) != 4 {
+		t.Fatalf("Expected 4 entries, got %+v", entries)
+	}
+
+	tests := []struct {
+		key  string
+		want CacheEntry
+	}{
+		{CacheKey(valid), CacheEntry{Type: "service-account", Account: "valid-id", Platform: "https://test.forgerock.com", Scope: "fr:am:*", Valid: true}},
+		{CacheKey(expired), CacheEntry{Type: "custom", Account: "expired-client", Platform: "https://other.forgerock.com", Scope: "fr:idm:* fr:am:*"}},
+		{"legacy", CacheEntry{Account: "legacy-id", Platform: "https://legacy.forgerock.com", Scope: "fr:am:*"}},
+	}
+	for _, tt := range tests {
+		got, ok := byKey[tt.key]
+		if !ok {
+			t.Errorf("Expected an entry for key %s", tt.key)
+			continue
+		}
+		if got.Type != tt.want.Type || got.Account != tt.want.Account || got.Platform != tt.want.Platform || got.Scope != tt.want.Scope || got.Valid != tt.want.Valid || got.Error != "" {
+			t.Errorf("Entry %s: expected %+v, got %+v", tt.key, tt.want, got)
+		}
+	}
+	if byKey["corrupt"].Error == "" || byKey["corrupt"].Valid {
+		t.Errorf("Expected the corrupt entry to be invalid with an error, got %+v", byKey["corrupt"])
+	}
+
+	// Listings never include the tokens
+	output, err := NewClient(GeneratorOptions{OutputFormat: OutputFormatJSON}).FormatCacheList(entries)
+	if err != nil {
+		t.Fatalf("Failed to format entries: %v", err)
+	}
+	if strings.Contains(output, "-token") {
+		t.Errorf("Expected no tokens in the listing, got %s", output)
+	}
+}
+
+func TestFileCacheClear(t *testing.T) {
+	cache, err := NewFileCache(filepath.Join(t.TempDir(), "cache"), DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	// A missing cache directory has nothing to clear
+	if removed, err := cache.Clear(); err != nil || removed != 0 {
+		t.Fatalf("Expected to clear nothing, got %d, %v", removed, err)
+	}
+
+	for _, id := range []string{"first-id", "second-id"} {
+		config := &token.TokenConfig{Type: token.TokenTypeServiceAccount, ServiceAccountID: id}
+		if err := cache.Put(config, &token.TokenResult{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatalf("Failed to store token: %v", err)
+		}
+	}
+	// Files other than cache entries are left alone
+	other := filepath.Join(cache.Dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		t.Fatalf("Failed to clear cache: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 entries removed, got %d", removed)
+	}
+	if entries, err := cache.List(); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries after clearing, got %+v, %v", entries, err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected other files to be kept: %v", err)
+	}
+}
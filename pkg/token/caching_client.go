@@ -0,0 +1,61 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// DefaultRefreshWindow is how close to expiry a token may get before CachingClient regenerates it
+const DefaultRefreshWindow = time.Minute
+
+// CachingClient keeps the last generated token in memory and regenerates it
+// only when it is within the refresh window of expiring. It is safe for concurrent use.
+type CachingClient struct {
+	client        *Client
+	refreshWindow time.Duration
+
+	mu     sync.Mutex
+	result *token.TokenResult
+}
+
+// NewCachingClient creates a caching client around client, using
+// DefaultRefreshWindow when refreshWindow is not positive
+func NewCachingClient(client *Client, refreshWindow time.Duration) *CachingClient {
+	if refreshWindow <= 0 {
+		refreshWindow = DefaultRefreshWindow
+	}
+	return &CachingClient{
+		client:        client,
+		refreshWindow: refreshWindow,
+	}
+}
+
+// Token returns a valid access token, generating a new one when the cached
+// token is missing or within the refresh window of expiring
+func (c *CachingClient) Token(ctx context.Context) (string, error) {
+	result, err := c.Result(ctx)
+	if err != nil {
+		return "", err
+	}
+	return result.AccessToken, nil
+}
+
+// Result returns the cached token result, generating a new one when the cached
+// token is missing or within the refresh window of expiring
+func (c *CachingClient) Result(ctx context.Context) (*token.TokenResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.result == nil || c.result.ExpiresWithin(c.refreshWindow) {
+		result, err := c.client.GenerateContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.result = result
+	}
+
+	return c.result, nil
+}
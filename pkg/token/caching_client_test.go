@@ -0,0 +1,93 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// newCountingTokenServer returns a token endpoint issuing numbered tokens that expire after expiresIn seconds
+func newCountingTokenServer(t *testing.T, expiresIn int, requests *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// customClientConfig returns a client credentials configuration for the test server
+func customClientConfig(server *httptest.Server) token.TokenConfig {
+	return token.TokenConfig{
+		Type:             token.TokenTypeCustom,
+		Platform:         server.URL,
+		ClientID:         "test-client",
+		ClientSecret:     "test-secret",
+		AllowInsecureURL: true,
+	}
+}
+
+func TestCachingClientReusesToken(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 3600, &requests)
+	client := NewCachingClient(NewClient(GeneratorOptions{Config: customClientConfig(server)}), 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := client.Token(context.Background())
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if tok != "token-1" {
+				t.Errorf("Expected token-1, got %s", tok)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", requests)
+	}
+}
+
+func TestCachingClientRefreshesNearExpiry(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 30, &requests)
+	client := NewCachingClient(NewClient(GeneratorOptions{Config: customClientConfig(server)}), time.Minute)
+
+	for i := 1; i <= 2; i++ {
+		tok, err := client.Token(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := fmt.Sprintf("token-%d", i); tok != expected {
+			t.Errorf("Expected %s, got %s", expected, tok)
+		}
+	}
+}
+
+func TestCachingClientError(t *testing.T) {
+	client := NewCachingClient(NewClient(GeneratorOptions{Config: token.TokenConfig{Type: token.TokenTypeCustom}}), 0)
+
+	if _, err := client.Token(context.Background()); err == nil {
+		t.Error("Expected error for invalid configuration")
+	}
+}
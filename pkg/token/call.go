@@ -0,0 +1,84 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// CallResult holds the response to an API request authorized with an access token
+type CallResult = token.CallResult
+
+// ParseCall parses an API request given as "METHOD URL" or just "URL". Without
+// a method, the request is a GET, or a POST when it has a body.
+func ParseCall(call string, hasBody bool) (method, apiURL string, err error) {
+	fields := strings.Fields(call)
+	switch len(fields) {
+	case 1:
+		method = http.MethodGet
+		if hasBody {
+			method = http.MethodPost
+		}
+		apiURL = fields[0]
+	case 2:
+		method, apiURL = strings.ToUpper(fields[0]), fields[1]
+	default:
+		return "", "", fmt.Errorf("invalid call %q: expected \"METHOD URL\"", call)
+	}
+
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid call URL %q: %w", apiURL, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid call URL %q: an http or https URL with a host is required", apiURL)
+	}
+	return method, apiURL, nil
+}
+
+// Call generates an access token and sends the API request with it as a bearer
+// Authorization header. The token is only sent over plain http when the
+// configuration allows an insecure URL.
+func (c *Client) Call(ctx context.Context, method, apiURL string, body []byte) (*CallResult, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid call URL %q: %w", apiURL, err)
+	}
+	if u.Scheme == "http" && !c.options.Config.AllowInsecureURL {
+		return nil, fmt.Errorf("%w: invalid call URL %q: plain http is not allowed, use https or set allow_insecure_url", ErrValidation, apiURL)
+	}
+
+	result, err := c.GenerateContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("token generation failed: %w", err)
+	}
+
+	return token.Call(ctx, c.options.Config, method, apiURL, body, result.AccessToken, c.logger())
+}
+
+// FormatCallResult formats the API response according to the specified format.
+// Raw output is the response body alone, and text output the status line
+// followed by the response body.
+func (c *Client) FormatCallResult(result *CallResult) (string, error) {
+	if output, ok, err := c.formatStructured(result); ok {
+		return output, err
+	}
+	if c.options.OutputFormat == OutputFormatRaw {
+		return result.Body, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("HTTP %s\n", result.Status))
+	if result.Body != "" {
+		output.WriteString("\n")
+		output.WriteString(result.Body)
+		if !strings.HasSuffix(result.Body, "\n") {
+			output.WriteString("\n")
+		}
+	}
+	return output.String(), nil
+}
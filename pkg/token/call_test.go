@@ -0,0 +1,175 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestParseCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       string
+		hasBody    bool
+		wantMethod string
+		wantURL    string
+		wantErr    string
+	}{
+		{name: "method and URL", call: "GET https://api.example.com/me", wantMethod: "GET", wantURL: "https://api.example.com/me"},
+		{name: "lowercase method", call: "delete https://api.example.com/users/1", wantMethod: "DELETE", wantURL: "https://api.example.com/users/1"},
+		{name: "URL only", call: "https://api.example.com/me", wantMethod: "GET", wantURL: "https://api.example.com/me"},
+		{name: "URL only with body", call: "https://api.example.com/users", hasBody: true, wantMethod: "POST", wantURL: "https://api.example.com/users"},
+		{name: "empty", call: "", wantErr: "expected \"METHOD URL\""},
+		{name: "too many fields", call: "GET https://api.example.com/me extra", wantErr: "expected \"METHOD URL\""},
+		{name: "relative URL", call: "GET /me", wantErr: "http or https URL with a host is required"},
+		{name: "unsupported scheme", call: "GET ftp://api.example.com/me", wantErr: "http or https URL with a host is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, apiURL, err := ParseCall(tt.call, tt.hasBody)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if method != tt.wantMethod || apiURL != tt.wantURL {
+				t.Errorf("Expected %s %s, got %s %s", tt.wantMethod, tt.wantURL, method, apiURL)
+			}
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/am/oauth2/access_token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "generated-token",
+				"token_type":   "Bearer",
+				"expires_in":   3599,
+			})
+		case "/api/me":
+			if got := r.Header.Get("Authorization"); got != "Bearer generated-token" {
+				t.Errorf("Expected generated token in Authorization header, got %q", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{
+				"method":       r.Method,
+				"content_type": r.Header.Get("Content-Type"),
+				"body":         string(body),
+			})
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		method          string
+		body            string
+		wantContentType string
+	}{
+		{name: "no body", method: "GET"},
+		{name: "JSON body", method: "POST", body: `{"name":"test"}`, wantContentType: "application/json"},
+		{name: "form body", method: "POST", body: "name=test", wantContentType: "application/x-www-form-urlencoded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(GeneratorOptions{Config: customClientConfig(server)})
+
+			result, err := client.Call(context.Background(), tt.method, server.URL+"/api/me", []byte(tt.body))
+			if err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+			if result.StatusCode != http.StatusCreated {
+				t.Errorf("Expected status 201, got %d", result.StatusCode)
+			}
+
+			var echoed map[string]string
+			if err := json.Unmarshal([]byte(result.Body), &echoed); err != nil {
+				t.Fatalf("Failed to parse response body %q: %v", result.Body, err)
+			}
+			if echoed["method"] != tt.method || echoed["body"] != tt.body || echoed["content_type"] != tt.wantContentType {
+				t.Errorf("Unexpected request %v", echoed)
+			}
+		})
+	}
+}
+
+func TestCallDoesNotForwardPAICSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/am/oauth2/access_token":
+			if got := r.Header.Get("X-Gateway-Key"); got != "gateway-secret" {
+				t.Errorf("Expected configured header on the token request, got %q", got)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "generated-token",
+				"token_type":   "Bearer",
+				"expires_in":   3599,
+			})
+		case "/api/me":
+			if got := r.Header.Get("X-Gateway-Key"); got != "" {
+				t.Errorf("Expected no configured header on the API request, got %q", got)
+			}
+			if got := r.Header.Get("User-Agent"); got == "custom-agent" {
+				t.Errorf("Expected the default User-Agent on the API request, got %q", got)
+			}
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	config := customClientConfig(server)
+	config.Headers = map[string]string{"X-Gateway-Key": "gateway-secret"}
+	config.UserAgent = "custom-agent"
+	client := NewClient(GeneratorOptions{Config: config})
+
+	if _, err := client.Call(context.Background(), "GET", server.URL+"/api/me", nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+}
+
+func TestCallRejectsPlainHTTP(t *testing.T) {
+	client := NewClient(GeneratorOptions{
+		Config: token.TokenConfig{
+			Type:     token.TokenTypeCustom,
+			Platform: "https://openam.example.com",
+			ClientID: "test-client",
+		},
+	})
+
+	_, err := client.Call(context.Background(), "GET", "http://api.example.com/me", nil)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+}
+
+func TestFormatCallResult(t *testing.T) {
+	client := NewClient(GeneratorOptions{OutputFormat: OutputFormatText})
+
+	output, err := client.FormatCallResult(&CallResult{StatusCode: 200, Status: "200 OK", Body: `{"sub":"user"}`})
+	if err != nil {
+		t.Fatalf("FormatCallResult failed: %v", err)
+	}
+	if want := "HTTP 200 OK\n\n{\"sub\":\"user\"}\n"; output != want {
+		t.Errorf("Expected %q, got %q", want, output)
+	}
+}
@@ -0,0 +1,71 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// CheckResult lists the problems found in a token configuration
+type CheckResult struct {
+	Valid    bool     `json:"valid" yaml:"valid"`
+	Problems []string `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
+
+// Check validates the configuration, parses the service account or
+// private_key_jwt signing key and loads the mTLS client certificate without
+// making any network calls, reporting every problem found
+func Check(c *token.TokenConfig) *CheckResult {
+	var problems []string
+	for _, err := range splitErrors(validateConfig(c)) {
+		problems = append(problems, err.Error())
+	}
+	if c.Type == token.TokenTypeServiceAccount || c.TokenEndpointAuthMethod == token.ClientAuthPrivateKeyJWT {
+		if err := token.CheckSigningKey(*c); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if c.ClientCertFile != "" && c.ClientKeyFile != "" {
+		if _, err := token.LoadClientCertificate(*c); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return &CheckResult{Valid: len(problems) == 0, Problems: problems}
+}
+
+// splitErrors flattens errors combined with errors.Join into their parts
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, splitErrors(e)...)
+	}
+	return errs
+}
+
+// FormatCheck formats the check result according to the specified format
+func (c *Client) FormatCheck(result *CheckResult) (string, error) {
+	if output, ok, err := c.formatStructured(result); ok {
+		return output, err
+	}
+
+	if result.Valid {
+		return "Configuration is valid\n", nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Configuration has %d problem(s):\n", len(result.Problems)))
+	for _, problem := range result.Problems {
+		output.WriteString(fmt.Sprintf("- %s\n", problem))
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,88 @@
+package token
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   token.TokenConfig
+		problems []string
+	}{
+		{
+			name: "valid custom config",
+			config: token.TokenConfig{
+				Type:         token.TokenTypeCustom,
+				Platform:     "https://test.forgerock.com",
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+			},
+		},
+		{
+			name: "invalid config",
+			config: token.TokenConfig{
+				Type:     token.TokenTypeCustom,
+				Platform: "https://test.forgerock.com",
+			},
+			problems: []string{"clientId is required for custom tokens", "clientSecret is required for custom tokens"},
+		},
+		{
+			name: "unparseable key",
+			config: token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				Platform:         "https://test.forgerock.com",
+				ServiceAccountID: "test-service-account",
+				JWKJson:          "{not json",
+			},
+			problems: []string{"failed to parse JWK"},
+		},
+		{
+			name: "JWKS key not fetched",
+			config: token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				Platform:         "https://test.forgerock.com",
+				ServiceAccountID: "test-service-account",
+				JWKSURL:          "https://keys.invalid/jwks.json",
+				KeyID:            "key-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Check(&tt.config)
+			if result.Valid != (len(tt.problems) == 0) {
+				t.Errorf("Expected valid %v, got %v (%v)", len(tt.problems) == 0, result.Valid, result.Problems)
+			}
+			if len(result.Problems) != len(tt.problems) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.problems), result.Problems)
+			}
+			for i, want := range tt.problems {
+				if !containsString(result.Problems[i], want) {
+					t.Errorf("Expected problem containing %q, got %q", want, result.Problems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	first := errors.New("first")
+	second := errors.New("second")
+	third := errors.New("third")
+
+	errs := splitErrors(errors.Join(first, errors.Join(second, third)))
+	if len(errs) != 3 || errs[0] != first || errs[1] != second || errs[2] != third {
+		t.Errorf("Expected joined errors to be flattened, got %v", errs)
+	}
+	if errs := splitErrors(first); len(errs) != 1 || errs[0] != first {
+		t.Errorf("Expected single error, got %v", errs)
+	}
+	if errs := splitErrors(nil); errs != nil {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
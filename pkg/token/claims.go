@@ -0,0 +1,35 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseClaim parses a key=value claim, inferring the value type: true and
+// false are booleans, integers and decimals are numbers and anything else is
+// a string. Wrap the value in double quotes to keep it a string, as in
+// level="1".
+func ParseClaim(claim string) (string, interface{}, error) {
+	name, value, ok := strings.Cut(claim, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", nil, fmt.Errorf("invalid claim %q: expected key=value", claim)
+	}
+
+	switch {
+	case len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`):
+		return name, value[1 : len(value)-1], nil
+	case value == "true":
+		return name, true, nil
+	case value == "false":
+		return name, false, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return name, n, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "xXpPnN") {
+		return name, f, nil
+	}
+	return name, value, nil
+}
@@ -0,0 +1,46 @@
+package token
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClaim(t *testing.T) {
+	tests := []struct {
+		claim     string
+		wantName  string
+		wantValue interface{}
+		wantErr   bool
+	}{
+		{claim: "department=engineering", wantName: "department", wantValue: "engineering"},
+		{claim: "admin=true", wantName: "admin", wantValue: true},
+		{claim: "admin=false", wantName: "admin", wantValue: false},
+		{claim: "level=3", wantName: "level", wantValue: int64(3)},
+		{claim: "weight=0.5", wantName: "weight", wantValue: 0.5},
+		{claim: `level="3"`, wantName: "level", wantValue: "3"},
+		{claim: "flag=True", wantName: "flag", wantValue: "True"},
+		{claim: "value=NaN", wantName: "value", wantValue: "NaN"},
+		{claim: "url=https://example.com/?a=b", wantName: "url", wantValue: "https://example.com/?a=b"},
+		{claim: "empty=", wantName: "empty", wantValue: ""},
+		{claim: "novalue", wantErr: true},
+		{claim: "=value", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.claim, func(t *testing.T) {
+			name, value, err := ParseClaim(tt.claim)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.claim)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if name != tt.wantName || !reflect.DeepEqual(value, tt.wantValue) {
+				t.Errorf("Expected %s=%#v, got %s=%#v", tt.wantName, tt.wantValue, name, value)
+			}
+		})
+	}
+}
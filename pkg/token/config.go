@@ -1,8 +1,17 @@
 package token
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"time"
 	"strings"
 
@@ -10,8 +19,67 @@ import (
 	"github.com/aaronwang/pctl/internal/token"
 )
 
-// LoadConfig loads token configuration from a YAML file
-func LoadConfig(configPath string) (*token.TokenConfig, error) {
+const (
+	// DefaultHTTPTimeout is the HTTP timeout used when timeout_seconds is not set
+	DefaultHTTPTimeout = token.DefaultHTTPTimeout
+	// DefaultRetries is the number of retries used when retries is not set
+	DefaultRetries = token.DefaultRetries
+	// DefaultRetryMaxWait caps the wait between retries when retry_max_wait_seconds is not set
+	DefaultRetryMaxWait = token.DefaultRetryMaxWait
+	// DefaultAssertionExp is the service account JWT assertion lifetime used when assertion_exp_seconds is not set
+	DefaultAssertionExp = token.DefaultAssertionExp
+)
+
+// LoadConfig loads token configuration from one or more YAML or JSON files.
+// Later files override earlier ones field by field: non-empty values replace
+// earlier ones and customClaims are merged key by key.
+// Files with a .json extension or content starting with "{" are parsed as JSON.
+// String values may reference environment variables as ${VAR} or ${VAR:-default};
+// a literal "$" must be escaped as "$$". Credential fields may instead reference
+// an OS keychain entry as keychain:<service>.
+func LoadConfig(configPaths ...string) (*token.TokenConfig, error) {
+	if len(configPaths) == 0 {
+		return nil, fmt.Errorf("config path is required")
+	}
+
+	var config token.TokenConfig
+	for _, configPath := range configPaths {
+		fileConfig, err := readConfigFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfig(&config, fileConfig)
+	}
+
+	// Resolve keychain:<service> references in credential fields
+	if err := resolveSecrets(&config); err != nil {
+		return nil, err
+	}
+
+	// Normalize older layouts to the current one
+	if err := migrateConfig(&config); err != nil {
+		return nil, err
+	}
+
+	// Read JWK from file when not provided inline
+	if config.JWKFile != "" {
+		if config.JWKJson != "" {
+			config.Warnings = append(config.Warnings, "both jwk_json and jwk_file are set; using jwk_json")
+		} else {
+			jwkData, err := os.ReadFile(config.JWKFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read jwk_file: %w", err)
+			}
+			config.JWKJson = strings.TrimSpace(string(jwkData))
+		}
+	}
+
+	return &config, nil
+}
+
+// readConfigFile reads and decodes a single config file, resolving a relative
+// jwk_file, client_cert_file or client_key_file against the file's directory
+func readConfigFile(configPath string) (*token.TokenConfig, error) {
 	if configPath == "" {
 		return nil, fmt.Errorf("config path is required")
 	}
@@ -22,70 +90,355 @@ func LoadConfig(configPath string) (*token.TokenConfig, error) {
 	}
 
 	var config token.TokenConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if isJSONConfig(configPath, data) {
+		err = decodeJSONConfig(data, &config)
+	} else {
+		err = decodeYAMLConfig(data, &config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range []*string{&config.JWKFile, &config.ClientCertFile, &config.ClientKeyFile} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(filepath.Dir(configPath), *path)
+		}
+	}
+
+	return &config, nil
+}
+
+// mergeConfig copies the non-empty fields of src over dst, merging maps key by key
+func mergeConfig(dst, src *token.TokenConfig) {
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+
+	for i := 0; i < srcValue.NumField(); i++ {
+		field := srcValue.Field(i)
+		if field.IsZero() {
+			continue
+		}
+
+		target := dstValue.Field(i)
+		if field.Kind() == reflect.Map && !target.IsNil() {
+			for _, key := range field.MapKeys() {
+				target.SetMapIndex(key, field.MapIndex(key))
+			}
+			continue
+		}
+		target.Set(field)
 	}
+}
 
-	// Set defaults and normalize fields
-	if config.Type == "" {
-		config.Type = token.TokenTypeServiceAccount
+// isJSONConfig reports whether the config file should be parsed as JSON
+func isJSONConfig(configPath string, data []byte) bool {
+	if strings.EqualFold(filepath.Ext(configPath), ".json") {
+		return true
 	}
-	
-	// Handle alternative field names from authflow format
-	if config.Platform != "" && config.BaseURL == "" {
-		config.BaseURL = config.Platform
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// decodeYAMLConfig parses YAML config data, expanding environment variable references
+func decodeYAMLConfig(data []byte, config *token.TokenConfig) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
 	}
-	
-	// Convert exp_seconds to ExpiresIn duration
-	if config.ExpSeconds > 0 && config.ExpiresIn == 0 {
-		config.ExpiresIn = time.Duration(config.ExpSeconds) * time.Second
+
+	// Expand ${VAR} and ${VAR:-default} references from the environment
+	if err := expandEnvNode(&node, ""); err != nil {
+		return fmt.Errorf("failed to expand config file: %w", err)
 	}
-	
-	// Set default expiry if none specified
-	if config.ExpiresIn == 0 {
-		config.ExpiresIn = 60 * time.Minute // Default 1 hour
+
+	// Normalize a human-readable expiresIn to a duration yaml.v3 can decode
+	if len(node.Content) == 1 && node.Content[0].Kind == yaml.MappingNode {
+		mapping := node.Content[0]
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			// An empty or null expiresIn is left unset, as before durations were parsed
+			if mapping.Content[i].Value != "expiresIn" || mapping.Content[i+1].ShortTag() == "!!null" {
+				continue
+			}
+			expiresIn, err := parseExpiresIn(mapping.Content[i+1].Value)
+			if err != nil {
+				return err
+			}
+			mapping.Content[i+1].Value = expiresIn.String()
+			mapping.Content[i+1].Tag = "!!str"
+		}
 	}
 
-	// Convert single scope string to scopes array
-	if config.Scope != "" && len(config.Scopes) == 0 {
-		config.Scopes = strings.Split(config.Scope, " ")
+	if err := node.Decode(config); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
 	}
+	return nil
+}
 
-	return &config, nil
+// decodeJSONConfig parses JSON config data, expanding environment variable references
+func decodeJSONConfig(data []byte, config *token.TokenConfig) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Expand ${VAR} and ${VAR:-default} references from the environment
+	value, err := expandEnvValue(value, "")
+	if err != nil {
+		return fmt.Errorf("failed to expand config file: %w", err)
+	}
+
+	// Normalize a human-readable expiresIn to the nanoseconds time.Duration decodes from
+	if fields, ok := value.(map[string]interface{}); ok {
+		if raw, ok := fields["expiresIn"]; ok && raw != nil {
+			expiresIn, err := parseExpiresIn(fmt.Sprint(raw))
+			if err != nil {
+				return err
+			}
+			fields["expiresIn"] = int64(expiresIn)
+		}
+	}
+
+	expanded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := json.Unmarshal(expanded, config); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return nil
+}
+
+// ErrValidation matches configuration validation failures
+var ErrValidation = errors.New("configuration validation failed")
+
+// maxExpiresInSeconds is the largest number of seconds a time.Duration can hold
+const maxExpiresInSeconds = math.MaxInt64 / int64(time.Second)
+
+// parseExpiresIn parses an expiresIn value given as a duration such as "30m",
+// "1h" or "900s", or as a number of seconds. Integers were nanoseconds before
+// durations were parsed, so one too large to be seconds is reported rather
+// than overflowing.
+func parseExpiresIn(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("invalid expiresIn %q: must not be negative", value)
+		}
+		if seconds > maxExpiresInSeconds {
+			return 0, fmt.Errorf("invalid expiresIn %q: integers are seconds, not nanoseconds; use a duration such as 1h", value)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	expiresIn, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiresIn %q: expected a duration such as 30m, 1h or 900s", value)
+	}
+	if expiresIn < 0 {
+		return 0, fmt.Errorf("invalid expiresIn %q: must not be negative", value)
+	}
+	return expiresIn, nil
 }
 
-// Validate validates the token configuration
+// Validate validates the token configuration, reporting every failure rather
+// than only the first. Errors match ErrValidation.
 func Validate(c *token.TokenConfig) error {
-	if c.BaseURL == "" && c.Platform == "" {
-		return fmt.Errorf("baseUrl or platform is required")
+	if err := validateConfig(c); err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	return nil
+}
+
+// validateConfig validates the token configuration for token generation,
+// combining every failure found with errors.Join
+func validateConfig(c *token.TokenConfig) error {
+	var errs []error
+	if err := validatePlatformURL(c); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.TokenEndpointAuthMethod {
+	case "", token.ClientAuthSecretPost, token.ClientAuthSecretBasic, token.ClientAuthPrivateKeyJWT:
+	default:
+		errs = append(errs, fmt.Errorf("invalid token_endpoint_auth_method %q: must be %s, %s or %s", c.TokenEndpointAuthMethod, token.ClientAuthSecretPost, token.ClientAuthSecretBasic, token.ClientAuthPrivateKeyJWT))
+	}
+
+	if err := validateRealm(c.Realm); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.SigningAlg != "" && !slices.Contains(token.SigningAlgs, c.SigningAlg) {
+		errs = append(errs, fmt.Errorf("invalid signing_alg %q: must be one of %s", c.SigningAlg, strings.Join(token.SigningAlgs, ", ")))
+	}
+
+	if c.TokenEndpoint != "" {
+		if err := validateEndpointURL("token_endpoint", c.TokenEndpoint, c.AllowInsecureURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		errs = append(errs, fmt.Errorf("client_cert_file and client_key_file must be set together"))
+	}
+
+	if c.StrictExpiry {
+		if err := checkExpiry(c); err != nil {
+			errs = append(errs, fmt.Errorf("%w (unset strict_expiry to only warn)", err))
+		}
+	}
+
+	if c.JTI != "" && !c.JTIEnabled() {
+		errs = append(errs, fmt.Errorf("jti is set but include_jti is false"))
+	}
+
+	if c.AssertionOnly && c.Type != token.TokenTypeServiceAccount {
+		errs = append(errs, fmt.Errorf("assertion_only is only supported for service account tokens"))
 	}
 
 	switch c.Type {
 	case token.TokenTypeServiceAccount:
 		if c.ServiceAccountID == "" {
-			return fmt.Errorf("service_account_id is required for service account tokens")
+			errs = append(errs, fmt.Errorf("service_account_id is required for service account tokens"))
+		}
+		if c.JWKJson == "" && c.PrivateKey == "" && c.JWKSURL == "" {
+			errs = append(errs, fmt.Errorf("jwk_json, privateKey or jwks_url is required for service account tokens"))
+		} else if c.JWKJson == "" && c.PrivateKey == "" && c.KeyID == "" {
+			errs = append(errs, fmt.Errorf("keyId is required with jwks_url"))
 		}
-		if c.JWKJson == "" && c.PrivateKey == "" {
-			return fmt.Errorf("jwk_json or privateKey is required for service account tokens")
+		if c.StrictAudience {
+			if err := token.CheckAudience(*c); err != nil {
+				errs = append(errs, fmt.Errorf("%w (unset strict_audience to only warn)", err))
+			}
 		}
 	case token.TokenTypeUser:
 		if c.Username == "" {
-			return fmt.Errorf("username is required for user tokens")
+			errs = append(errs, fmt.Errorf("username is required for user tokens"))
 		}
 		if c.Password == "" {
-			return fmt.Errorf("password is required for user tokens")
+			errs = append(errs, fmt.Errorf("password is required for user tokens"))
+		}
+		if c.RequireScope && !hasScope(c) {
+			errs = append(errs, fmt.Errorf("scope is required for user tokens when require_scope is set"))
+		}
+		if (c.OTP != "" || c.AuthTree != "") && c.RedirectURI == "" {
+			errs = append(errs, fmt.Errorf("redirect_uri is required for user tokens with otp or auth_tree"))
 		}
 	case token.TokenTypeCustom:
 		if c.ClientID == "" {
-			return fmt.Errorf("clientId is required for custom tokens")
+			errs = append(errs, fmt.Errorf("clientId is required for custom tokens"))
+		}
+		if c.TokenEndpointAuthMethod == token.ClientAuthPrivateKeyJWT {
+			if c.JWKJson == "" && c.PrivateKey == "" && c.JWKSURL == "" {
+				errs = append(errs, fmt.Errorf("jwk_json, privateKey or jwks_url is required with private_key_jwt"))
+			} else if c.JWKJson == "" && c.PrivateKey == "" && c.KeyID == "" {
+				errs = append(errs, fmt.Errorf("keyId is required with jwks_url"))
+			}
+		} else if c.ClientSecret == "" {
+			errs = append(errs, fmt.Errorf("clientSecret is required for custom tokens"))
+		}
+		if c.RequireScope && !hasScope(c) {
+			errs = append(errs, fmt.Errorf("scope is required for custom tokens when require_scope is set"))
+		}
+	case token.TokenTypeAuthorizationCode:
+		if c.ClientID == "" {
+			errs = append(errs, fmt.Errorf("clientId is required for authorization-code tokens"))
+		}
+		if c.RedirectURI != "" {
+			if u, err := url.Parse(c.RedirectURI); err != nil || !token.IsLoopbackRedirectURI(u) {
+				errs = append(errs, fmt.Errorf("invalid redirect_uri %q: must be an http loopback URL with a port for authorization-code tokens", c.RedirectURI))
+			}
+		}
+	case token.TokenTypeDeviceCode:
+		if c.ClientID == "" {
+			errs = append(errs, fmt.Errorf("clientId is required for device-code tokens"))
+		}
+	case token.TokenTypeTokenExchange:
+		if c.ClientID == "" {
+			errs = append(errs, fmt.Errorf("clientId is required for token-exchange tokens"))
+		}
+		if c.SubjectToken == "" {
+			errs = append(errs, fmt.Errorf("subject_token is required for token-exchange tokens"))
+		}
+		if c.ActorTokenType != "" && c.ActorToken == "" {
+			errs = append(errs, fmt.Errorf("actor_token_type is set without actor_token"))
 		}
-		if c.ClientSecret == "" {
-			return fmt.Errorf("clientSecret is required for custom tokens")
+		if c.TokenEndpointAuthMethod == token.ClientAuthPrivateKeyJWT && c.JWKJson == "" && c.PrivateKey == "" && c.JWKSURL == "" {
+			errs = append(errs, fmt.Errorf("jwk_json, privateKey or jwks_url is required with private_key_jwt"))
 		}
 	default:
-		return fmt.Errorf("invalid token type: %s", c.Type)
+		errs = append(errs, fmt.Errorf("invalid token type: %s", c.Type))
 	}
 
+	return errors.Join(errs...)
+}
+
+// hasScope reports whether the configuration requests at least one scope
+func hasScope(c *token.TokenConfig) bool {
+	return strings.TrimSpace(c.Scope+" "+strings.Join(c.Scopes, " ")) != ""
+}
+
+// validateRealm validates that the realm is a plain path of non-empty segments
+func validateRealm(realm string) error {
+	if realm == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(strings.Trim(realm, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, "?#% \t") {
+			return fmt.Errorf("invalid realm %q: must be a path such as root/alpha", realm)
+		}
+	}
+	return nil
+}
+
+// validatePlatformURL validates that the platform URL is an absolute https URL,
+// or http when allow_insecure_url is set
+func validatePlatformURL(c *token.TokenConfig) error {
+	platform := c.BaseURL
+	if platform == "" {
+		platform = c.Platform
+	}
+	if platform == "" {
+		return fmt.Errorf("baseUrl or platform is required")
+	}
+	return validateEndpointURL("platform URL", platform, c.AllowInsecureURL)
+}
+
+// validateEndpointURL validates that the named URL is an absolute https URL,
+// or http when allowInsecure is set
+func validateEndpointURL(name, value string, allowInsecure bool) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !allowInsecure {
+			return fmt.Errorf("invalid %s %q: plain http is not allowed, use https or set allow_insecure_url", name, value)
+		}
+	default:
+		return fmt.Errorf("invalid %s %q: scheme must be https", name, value)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid %s %q: host is required", name, value)
+	}
+
+	return nil
+}
+
+// validateClientConfig validates the platform and client credentials needed
+// to call PAIC OAuth 2.0 endpoints other than the token endpoint. Errors match ErrValidation.
+func validateClientConfig(c *token.TokenConfig) error {
+	if err := validatePlatformURL(c); err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("%w: clientId is required", ErrValidation)
+	}
+	if c.TokenEndpointAuthMethod == token.ClientAuthSecretBasic && c.ClientSecret == "" {
+		return fmt.Errorf("%w: clientSecret is required with client_secret_basic", ErrValidation)
+	}
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package token
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -114,6 +115,352 @@ func TestLoadConfigErrors(t *testing.T) {
 	}
 }
 
+func TestLoadConfigJWKFile(t *testing.T) {
+	tempDir := t.TempDir()
+	jwk := `{"kty":"RSA","n":"test","e":"AQAB","d":"test"}`
+	if err := os.WriteFile(filepath.Join(tempDir, "key.json"), []byte(jwk+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to create JWK file: %v", err)
+	}
+
+	t.Run("relative path resolves against config dir", func(t *testing.T) {
+		configPath := filepath.Join(tempDir, "config.yaml")
+		content := `
+service_account_id: "test-id"
+jwk_file: "key.json"
+platform: "https://test.forgerock.com"
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create temp config file: %v", err)
+		}
+
+		config, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.JWKJson != jwk {
+			t.Errorf("Expected JWK to be read from file, got %q", config.JWKJson)
+		}
+		if len(config.Warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", config.Warnings)
+		}
+	})
+
+	t.Run("inline jwk_json wins", func(t *testing.T) {
+		configPath := filepath.Join(tempDir, "both.yaml")
+		content := `
+service_account_id: "test-id"
+jwk_json: '{"kty":"RSA"}'
+jwk_file: "key.json"
+platform: "https://test.forgerock.com"
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create temp config file: %v", err)
+		}
+
+		config, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.JWKJson != `{"kty":"RSA"}` {
+			t.Errorf("Expected inline JWK to win, got %q", config.JWKJson)
+		}
+		if len(config.Warnings) != 1 {
+			t.Errorf("Expected a warning about jwk_json and jwk_file, got %v", config.Warnings)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		configPath := filepath.Join(tempDir, "missing.yaml")
+		content := `
+service_account_id: "test-id"
+jwk_file: "does-not-exist.json"
+platform: "https://test.forgerock.com"
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create temp config file: %v", err)
+		}
+
+		if _, err := LoadConfig(configPath); err == nil {
+			t.Error("Expected error for missing jwk_file")
+		}
+	})
+}
+
+func TestLoadConfigEnvExpansion(t *testing.T) {
+	t.Setenv("PCTL_TEST_JWK", `{"kty":"RSA","n":"test"}`)
+	t.Setenv("PCTL_TEST_EXP", "600")
+
+	tests := []struct {
+		name        string
+		yamlContent string
+		wantErr     string
+		validate    func(t *testing.T, config *token.TokenConfig)
+	}{
+		{
+			name: "expands variables and defaults",
+			yamlContent: `
+service_account_id: "${PCTL_TEST_UNSET:-default-id}"
+jwk_json: '${PCTL_TEST_JWK}'
+platform: "https://test.forgerock.com"
+password: "pa$$word"
+exp_seconds: ${PCTL_TEST_EXP}
+`,
+			validate: func(t *testing.T, config *token.TokenConfig) {
+				if config.ServiceAccountID != "default-id" {
+					t.Errorf("Expected default value 'default-id', got %s", config.ServiceAccountID)
+				}
+				if config.JWKJson != `{"kty":"RSA","n":"test"}` {
+					t.Errorf("Expected JWK from environment, got %s", config.JWKJson)
+				}
+				if config.Password != "pa$word" {
+					t.Errorf("Expected escaped dollar to become literal, got %s", config.Password)
+				}
+				if config.ExpSeconds != 600 {
+					t.Errorf("Expected exp_seconds 600 from environment, got %d", config.ExpSeconds)
+				}
+			},
+		},
+		{
+			name: "missing variable names the key",
+			yamlContent: `
+service_account_id: "test-id"
+clientSecret: "${PCTL_TEST_UNSET}"
+`,
+			wantErr: "PCTL_TEST_UNSET referenced by clientSecret is not set",
+		},
+		{
+			name:        "empty file",
+			yamlContent: "",
+			validate: func(t *testing.T, config *token.TokenConfig) {
+				if config.Type != token.TokenTypeServiceAccount {
+					t.Errorf("Expected default type, got %s", config.Type)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create temp config file: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.validate(t, config)
+		})
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	t.Setenv("PCTL_TEST_SECRET", "env-secret")
+
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		wantErr  string
+		validate func(t *testing.T, config *token.TokenConfig)
+	}{
+		{
+			name:     "json extension",
+			fileName: "config.json",
+			content: `{
+  "service_account_id": "test-id",
+  "jwk_json": "{\"kty\":\"RSA\"}",
+  "platform": "https://test.forgerock.com",
+  "scope": "fr:am:* fr:idm:*",
+  "exp_seconds": 900,
+  "retries": 5
+}`,
+			validate: func(t *testing.T, config *token.TokenConfig) {
+				if config.Type != token.TokenTypeServiceAccount {
+					t.Errorf("Expected default type, got %s", config.Type)
+				}
+				if config.JWKJson != `{"kty":"RSA"}` {
+					t.Errorf("Expected jwk_json to be decoded, got %s", config.JWKJson)
+				}
+				if config.BaseURL != "https://test.forgerock.com" {
+					t.Errorf("Expected baseURL to be set from platform, got %s", config.BaseURL)
+				}
+				if config.ExpiresIn != 900*time.Second {
+					t.Errorf("Expected ExpiresIn 900s, got %v", config.ExpiresIn)
+				}
+				if len(config.Scopes) != 2 {
+					t.Errorf("Expected 2 scopes, got %d", len(config.Scopes))
+				}
+				if config.Retries != 5 {
+					t.Errorf("Expected retries 5, got %d", config.Retries)
+				}
+			},
+		},
+		{
+			name:     "leading brace without json extension",
+			fileName: "config.conf",
+			content:  `  {"type": "custom", "clientId": "client", "clientSecret": "${PCTL_TEST_SECRET}", "baseUrl": "https://test.forgerock.com"}`,
+			validate: func(t *testing.T, config *token.TokenConfig) {
+				if config.Type != token.TokenTypeCustom {
+					t.Errorf("Expected type custom, got %s", config.Type)
+				}
+				if config.ClientSecret != "env-secret" {
+					t.Errorf("Expected clientSecret from environment, got %s", config.ClientSecret)
+				}
+				if config.ExpiresIn != 60*time.Minute {
+					t.Errorf("Expected default ExpiresIn, got %v", config.ExpiresIn)
+				}
+			},
+		},
+		{
+			name:     "missing variable names the key",
+			fileName: "config.json",
+			content:  `{"customClaims": {"tenant": "${PCTL_TEST_UNSET}"}}`,
+			wantErr:  "PCTL_TEST_UNSET referenced by customClaims.tenant is not set",
+		},
+		{
+			name:     "invalid json",
+			fileName: "config.json",
+			content:  `{"service_account_id": }`,
+			wantErr:  "failed to parse config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create temp config file: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.validate(t, config)
+		})
+	}
+}
+
+func TestLoadConfigExpiresIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		expected time.Duration
+		wantErr  string
+	}{
+		{name: "hours", fileName: "config.yaml", content: "expiresIn: 1h", expected: time.Hour},
+		{name: "quoted minutes", fileName: "config.yaml", content: `expiresIn: "30m"`, expected: 30 * time.Minute},
+		{name: "seconds suffix", fileName: "config.yaml", content: "expiresIn: 900s", expected: 900 * time.Second},
+		{name: "bare seconds", fileName: "config.yaml", content: "expiresIn: 900", expected: 900 * time.Second},
+		{name: "json duration", fileName: "config.json", content: `{"expiresIn": "45m"}`, expected: 45 * time.Minute},
+		{name: "json seconds", fileName: "config.json", content: `{"expiresIn": 600}`, expected: 10 * time.Minute},
+		{name: "exp_seconds alternative", fileName: "config.yaml", content: "exp_seconds: 120", expected: 2 * time.Minute},
+		{name: "invalid duration", fileName: "config.yaml", content: "expiresIn: soon", wantErr: `invalid expiresIn "soon"`},
+		{name: "negative duration", fileName: "config.json", content: `{"expiresIn": "-5m"}`, wantErr: "must not be negative"},
+		{name: "json nanoseconds", fileName: "config.json", content: `{"expiresIn": 3600000000000}`, wantErr: `invalid expiresIn "3600000000000": integers are seconds`},
+		{name: "yaml nanoseconds", fileName: "config.yaml", content: "expiresIn: 3600000000000", wantErr: "integers are seconds"},
+		{name: "largest seconds", fileName: "config.yaml", content: "expiresIn: 9223372036", expected: 9223372036 * time.Second},
+		{name: "empty", fileName: "config.yaml", content: "expiresIn:", expected: 60 * time.Minute},
+		{name: "null", fileName: "config.yaml", content: "expiresIn: ~", expected: 60 * time.Minute},
+		{name: "json null", fileName: "config.json", content: `{"expiresIn": null}`, expected: 60 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create temp config file: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.ExpiresIn != tt.expected {
+				t.Errorf("Expected ExpiresIn %v, got %v", tt.expected, config.ExpiresIn)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMerge(t *testing.T) {
+	dir := t.TempDir()
+	keysDir := filepath.Join(dir, "keys")
+	if err := os.Mkdir(keysDir, 0700); err != nil {
+		t.Fatalf("Failed to create keys directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(keysDir, "key.json"), []byte(`{"kty":"RSA"}`), 0600); err != nil {
+		t.Fatalf("Failed to write JWK file: %v", err)
+	}
+
+	base := filepath.Join(dir, "base.yaml")
+	if err := os.WriteFile(base, []byte(`
+platform: "https://test.forgerock.com"
+scope: "fr:am:*"
+exp_seconds: 900
+customClaims:
+  tenant: "alpha"
+  env: "staging"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write base config: %v", err)
+	}
+
+	account := filepath.Join(keysDir, "account.json")
+	if err := os.WriteFile(account, []byte(`{
+  "service_account_id": "account-id",
+  "jwk_file": "key.json",
+  "scope": "fr:idm:*",
+  "customClaims": {"env": "prod"}
+}`), 0644); err != nil {
+		t.Fatalf("Failed to write account config: %v", err)
+	}
+
+	config, err := LoadConfig(base, account)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.BaseURL != "https://test.forgerock.com" {
+		t.Errorf("Expected platform from base config, got %s", config.BaseURL)
+	}
+	if config.ServiceAccountID != "account-id" {
+		t.Errorf("Expected service account from override, got %s", config.ServiceAccountID)
+	}
+	if config.Scope != "fr:idm:*" || len(config.Scopes) != 1 {
+		t.Errorf("Expected scope from override, got %s (%v)", config.Scope, config.Scopes)
+	}
+	if config.ExpiresIn != 900*time.Second {
+		t.Errorf("Expected ExpiresIn from base config, got %v", config.ExpiresIn)
+	}
+	if config.JWKJson != `{"kty":"RSA"}` {
+		t.Errorf("Expected jwk_file resolved against the override's directory, got %q", config.JWKJson)
+	}
+	if config.CustomClaims["tenant"] != "alpha" || config.CustomClaims["env"] != "prod" {
+		t.Errorf("Expected custom claims merged key by key, got %v", config.CustomClaims)
+	}
+}
+
 func TestValidate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -149,7 +496,29 @@ func TestValidate(t *testing.T) {
 				Platform:        "https://test.forgerock.com",
 			},
 			wantErr: true,
-			errMsg:  "jwk_json or privateKey is required",
+			errMsg:  "jwk_json, privateKey or jwks_url is required",
+		},
+		{
+			name: "valid JWKS config",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKSURL:          "https://keys.example.com/jwks.json",
+				KeyID:            "signing-key",
+				Platform:         "https://test.forgerock.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "JWKS config missing key ID",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKSURL:          "https://keys.example.com/jwks.json",
+				Platform:         "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "keyId is required with jwks_url",
 		},
 		{
 			name: "missing platform",
@@ -161,6 +530,61 @@ func TestValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "baseUrl or platform is required",
 		},
+		{
+			name: "platform URL with typo in scheme",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "htps://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "scheme must be https",
+		},
+		{
+			name: "bare hostname platform",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "invalid platform URL",
+		},
+		{
+			name: "platform URL without host",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "https:///am",
+			},
+			wantErr: true,
+			errMsg:  "host is required",
+		},
+		{
+			name: "plain http platform",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "http://localhost:8080",
+			},
+			wantErr: true,
+			errMsg:  "plain http is not allowed",
+		},
+		{
+			name: "plain http platform allowed",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "http://localhost:8080",
+				AllowInsecureURL: true,
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid user config",
 			config: &token.TokenConfig{
@@ -181,6 +605,283 @@ func TestValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "username is required",
 		},
+		{
+			name: "user config with auth tree missing redirect_uri",
+			config: &token.TokenConfig{
+				Type:     token.TokenTypeUser,
+				Username: "testuser",
+				Password: "testpass",
+				AuthTree: "Login2FA",
+				Platform: "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "redirect_uri is required for user tokens",
+		},
+		{
+			name: "custom config without scope",
+			config: &token.TokenConfig{
+				Type:         token.TokenTypeCustom,
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+				Platform:     "https://test.forgerock.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "custom config without required scope",
+			config: &token.TokenConfig{
+				Type:         token.TokenTypeCustom,
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+				Platform:     "https://test.forgerock.com",
+				RequireScope: true,
+			},
+			wantErr: true,
+			errMsg:  "scope is required for custom tokens",
+		},
+		{
+			name: "user config with required scope",
+			config: &token.TokenConfig{
+				Type:         token.TokenTypeUser,
+				Username:     "testuser",
+				Password:     "testpass",
+				Scopes:       []string{"openid"},
+				Platform:     "https://test.forgerock.com",
+				RequireScope: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "client_secret_basic auth method",
+			config: &token.TokenConfig{
+				Type:                    token.TokenTypeCustom,
+				ClientID:                "test-client",
+				ClientSecret:            "test-secret",
+				TokenEndpointAuthMethod: token.ClientAuthSecretBasic,
+				Platform:                "https://test.forgerock.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid authorization-code config",
+			config: &token.TokenConfig{
+				Type:        token.TokenTypeAuthorizationCode,
+				ClientID:    "test-client",
+				RedirectURI: "http://127.0.0.1:8085/callback",
+				Platform:    "https://test.forgerock.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "authorization-code with remote redirect URI",
+			config: &token.TokenConfig{
+				Type:        token.TokenTypeAuthorizationCode,
+				ClientID:    "test-client",
+				RedirectURI: "https://app.example.com/callback",
+				Platform:    "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "must be an http loopback URL with a port",
+		},
+		{
+			name: "private_key_jwt without secret",
+			config: &token.TokenConfig{
+				Type:                    token.TokenTypeCustom,
+				ClientID:                "test-client",
+				TokenEndpointAuthMethod: token.ClientAuthPrivateKeyJWT,
+				JWKJson:                 `{"kty":"RSA"}`,
+				Platform:                "https://test.forgerock.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "private_key_jwt without key",
+			config: &token.TokenConfig{
+				Type:                    token.TokenTypeCustom,
+				ClientID:                "test-client",
+				ClientSecret:            "test-secret",
+				TokenEndpointAuthMethod: token.ClientAuthPrivateKeyJWT,
+				Platform:                "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "jwk_json, privateKey or jwks_url is required with private_key_jwt",
+		},
+		{
+			name: "unsupported auth method",
+			config: &token.TokenConfig{
+				Type:                    token.TokenTypeCustom,
+				ClientID:                "test-client",
+				ClientSecret:            "test-secret",
+				TokenEndpointAuthMethod: "tls_client_auth",
+				Platform:                "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "invalid token_endpoint_auth_method",
+		},
+		{
+			name: "valid token exchange",
+			config: &token.TokenConfig{
+				Type:         token.TokenTypeTokenExchange,
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+				SubjectToken: "subject-token",
+				Platform:     "https://test.forgerock.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "token exchange without subject token",
+			config: &token.TokenConfig{
+				Type:         token.TokenTypeTokenExchange,
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+				Platform:     "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "subject_token is required for token-exchange tokens",
+		},
+		{
+			name: "token exchange actor type without actor token",
+			config: &token.TokenConfig{
+				Type:           token.TokenTypeTokenExchange,
+				ClientID:       "test-client",
+				SubjectToken:   "subject-token",
+				ActorTokenType: token.TokenTypeURIJWT,
+				Platform:       "https://test.forgerock.com",
+			},
+			wantErr: true,
+			errMsg:  "actor_token_type is set without actor_token",
+		},
+		{
+			name: "valid realm",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				Realm:            "root/alpha",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid realm",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				Realm:            "root/../alpha",
+			},
+			wantErr: true,
+			errMsg:  "invalid realm",
+		},
+		{
+			name: "valid token endpoint",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				TokenEndpoint:    "https://gateway.example.com/paic/token",
+			},
+			wantErr: false,
+		},
+		{
+			name: "plain http token endpoint",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				TokenEndpoint:    "http://gateway.example.com/paic/token",
+			},
+			wantErr: true,
+			errMsg:  "invalid token_endpoint",
+		},
+		{
+			name: "valid signing_alg",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				SigningAlg:       "RS384",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid signing_alg",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				SigningAlg:       "HS256",
+			},
+			wantErr: true,
+			errMsg:  "invalid signing_alg \"HS256\"",
+		},
+		{
+			name: "jti with include_jti disabled",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          "{}",
+				Platform:         "https://test.forgerock.com",
+				JTI:              "fixed-jti",
+				IncludeJTI:       new(bool),
+			},
+			wantErr: true,
+			errMsg:  "jti is set but include_jti is false",
+		},
+		{
+			name: "client certificate without key",
+			config: &token.TokenConfig{
+				Type:           token.TokenTypeCustom,
+				ClientID:       "test-client",
+				ClientSecret:   "test-secret",
+				Platform:       "https://test.forgerock.com",
+				ClientCertFile: "client.crt",
+			},
+			wantErr: true,
+			errMsg:  "client_cert_file and client_key_file must be set together",
+		},
+		{
+			name: "assertion_only on custom token",
+			config: &token.TokenConfig{
+				Type:          token.TokenTypeCustom,
+				ClientID:      "test-client",
+				ClientSecret:  "test-secret",
+				Platform:      "https://test.forgerock.com",
+				AssertionOnly: true,
+			},
+			wantErr: true,
+			errMsg:  "assertion_only is only supported for service account tokens",
+		},
+		{
+			name: "mismatched audience with strict_audience",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "https://test.forgerock.com",
+				Audience:         "https://other.forgerock.com/am/oauth2/access_token",
+				StrictAudience:   true,
+			},
+			wantErr: true,
+			errMsg:  "does not match the token endpoint",
+		},
+		{
+			name: "mismatched audience without strict_audience",
+			config: &token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"}`,
+				Platform:         "https://test.forgerock.com",
+				Audience:         "https://other.forgerock.com/am/oauth2/access_token",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -200,6 +901,33 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateReportsAllErrors(t *testing.T) {
+	err := Validate(&token.TokenConfig{
+		Type:                    token.TokenTypeServiceAccount,
+		TokenEndpointAuthMethod: "tls_client_auth",
+	})
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected error to match ErrValidation, got %v", err)
+	}
+
+	for _, want := range []string{
+		"baseUrl or platform is required",
+		"invalid token_endpoint_auth_method",
+		"service_account_id is required for service account tokens",
+		"jwk_json, privateKey or jwks_url is required for service account tokens",
+	} {
+		if !containsString(err.Error(), want) {
+			t.Errorf("Expected error message to contain '%s', got '%s'", want, err.Error())
+		}
+	}
+	if containsString(err.Error(), "keyId is required") {
+		t.Errorf("Expected no keyId error without jwks_url, got '%s'", err.Error())
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	config := DefaultConfig()
 	
@@ -0,0 +1,109 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPattern matches ${VAR}, ${VAR:-default} and the $$ escape for a literal dollar sign
+var envPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv expands ${VAR} and ${VAR:-default} references in value using the
+// process environment. A literal "$" must be written as "$$".
+func expandEnv(value, key string) (string, error) {
+	var missing string
+	expanded := envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := envPattern.FindStringSubmatch(match)
+		if env, ok := os.LookupEnv(groups[1]); ok {
+			return env
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		if missing == "" {
+			missing = groups[1]
+		}
+		return ""
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("environment variable %s referenced by %s is not set", missing, key)
+	}
+	return expanded, nil
+}
+
+// expandEnvNode expands environment variable references in every scalar value of the YAML tree
+func expandEnvNode(node *yaml.Node, key string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := expandEnvNode(child, key); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			if key != "" {
+				childKey = key + "." + childKey
+			}
+			if err := expandEnvNode(node.Content[i+1], childKey); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := expandEnvNode(child, fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		expanded, err := expandEnv(node.Value, key)
+		if err != nil {
+			return err
+		}
+		if expanded != node.Value {
+			node.Value = expanded
+			// Let unquoted values resolve to their natural type after expansion
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+	return nil
+}
+
+// expandEnvValue expands environment variable references in every string of a decoded JSON value
+func expandEnvValue(value interface{}, key string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			childKey := name
+			if key != "" {
+				childKey = key + "." + name
+			}
+			expanded, err := expandEnvValue(child, childKey)
+			if err != nil {
+				return nil, err
+			}
+			v[name] = expanded
+		}
+	case []interface{}:
+		for i, child := range v {
+			expanded, err := expandEnvValue(child, fmt.Sprintf("%s[%d]", key, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	case string:
+		return expandEnv(v, key)
+	}
+	return value, nil
+}
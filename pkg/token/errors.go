@@ -0,0 +1,73 @@
+package token
+
+import (
+	"errors"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// Errors returned by Client methods can be matched with errors.Is.
+// ErrValidation is defined alongside Validate.
+var (
+	// ErrTokenEndpoint matches failures requesting a token from the PAIC token endpoint
+	ErrTokenEndpoint = token.ErrTokenEndpoint
+	// ErrKeyParse matches failures loading or parsing the service account signing key
+	ErrKeyParse = token.ErrKeyParse
+	// ErrMinValidity matches generated tokens that expire sooner than GeneratorOptions.MinValidity
+	ErrMinValidity = errors.New("token expires too soon")
+)
+
+// TokenEndpointError is returned when the token endpoint rejects a request,
+// exposing the HTTP status code, response body and endpoint URL.
+// Unwrap it from errors returned by Client methods with errors.As:
+//
+//	var endpointErr *token.TokenEndpointError
+//	if errors.As(err, &endpointErr) {
+//		fmt.Println(endpointErr.StatusCode, endpointErr.Body)
+//	}
+type TokenEndpointError = token.TokenEndpointError
+
+// ClockSkewError is returned when PAIC rejects a service account assertion as
+// expired or not yet valid, usually because the local clock is wrong. It wraps
+// the TokenEndpointError, so it also matches ErrTokenEndpoint.
+type ClockSkewError = token.ClockSkewError
+
+// Transient reports whether err may succeed when retried, such as when PAIC
+// could not be reached or answered 429 or 5xx. Certificate verification
+// failures are not transient.
+func Transient(err error) bool {
+	return token.Transient(err)
+}
+
+// ErrorDetails returns the messages of the individual failures combined in
+// err, such as every problem found by Validate, or nil when err is a single
+// failure. The sentinel errors of this package are not listed.
+func ErrorDetails(err error) []string {
+	for err != nil {
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			var parts []error
+			for _, part := range e.Unwrap() {
+				if !isSentinel(part) {
+					parts = append(parts, part)
+				}
+			}
+			if len(parts) != 1 {
+				var details []string
+				for _, part := range parts {
+					details = append(details, part.Error())
+				}
+				return details
+			}
+			err = parts[0]
+		default:
+			err = errors.Unwrap(err)
+		}
+	}
+	return nil
+}
+
+// isSentinel reports whether err is one of the sentinel errors of this package
+func isSentinel(err error) bool {
+	return err == ErrValidation || err == ErrTokenEndpoint || err == ErrKeyParse || err == ErrMinValidity
+}
@@ -0,0 +1,128 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestGenerateErrorTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		config token.TokenConfig
+		target error
+	}{
+		{
+			name:   "validation",
+			config: token.TokenConfig{Type: token.TokenTypeCustom},
+			target: ErrValidation,
+		},
+		{
+			name: "key parse",
+			config: token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				ServiceAccountID: "test-id",
+				JWKJson:          `{"kty":"RSA"`,
+				Platform:         "https://test.forgerock.com",
+			},
+			target: ErrKeyParse,
+		},
+		{
+			name: "token endpoint",
+			config: token.TokenConfig{
+				Type:             token.TokenTypeCustom,
+				ClientID:         "test-client",
+				ClientSecret:     "wrong-secret",
+				Platform:         server.URL,
+				AllowInsecureURL: true,
+			},
+			target: ErrTokenEndpoint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(GeneratorOptions{Config: tt.config}).GenerateContext(context.Background())
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Expected error matching %v, got %v", tt.target, err)
+			}
+		})
+	}
+}
+
+func TestTokenEndpointErrorAs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"temporarily_unavailable"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(GeneratorOptions{Config: token.TokenConfig{
+		Type:             token.TokenTypeCustom,
+		ClientID:         "test-client",
+		ClientSecret:     "test-secret",
+		Platform:         server.URL,
+		AllowInsecureURL: true,
+		Retries:          -1,
+	}})
+
+	_, err := client.Generate()
+	var endpointErr *TokenEndpointError
+	if !errors.As(err, &endpointErr) {
+		t.Fatalf("Expected TokenEndpointError, got %v", err)
+	}
+	if endpointErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", endpointErr.StatusCode)
+	}
+	if endpointErr.Body != `{"error":"temporarily_unavailable"}` {
+		t.Errorf("Expected response body, got %s", endpointErr.Body)
+	}
+	if endpointErr.URL != server.URL+"/am/oauth2/access_token" {
+		t.Errorf("Expected token endpoint URL, got %s", endpointErr.URL)
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{name: "nil", err: nil, want: nil},
+		{name: "single failure", err: fmt.Errorf("token generation failed: %w", errors.New("boom")), want: nil},
+		{
+			name: "validation problems",
+			err: fmt.Errorf("token generation failed: %w", Validate(&token.TokenConfig{
+				Type:     token.TokenTypeCustom,
+				Platform: "https://openam.example.com",
+			})),
+			want: []string{"clientId is required for custom tokens", "clientSecret is required for custom tokens"},
+		},
+		{
+			name: "joined failures",
+			err:  fmt.Errorf("%w: %w", ErrValidation, errors.Join(errors.New("first"), errors.New("second"))),
+			want: []string{"first", "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorDetails(tt.err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
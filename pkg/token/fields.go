@@ -0,0 +1,59 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// ResultFields returns the top-level TokenResult field names that can be selected with Fields
+func ResultFields() []string {
+	resultType := reflect.TypeOf(token.TokenResult{})
+	fields := make([]string, 0, resultType.NumField())
+	for i := 0; i < resultType.NumField(); i++ {
+		name, _, _ := strings.Cut(resultType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// ValidateFields checks that every field name is a top-level TokenResult field
+func ValidateFields(fields []string) error {
+	known := ResultFields()
+	for _, field := range fields {
+		if !slices.Contains(known, field) {
+			return fmt.Errorf("unknown field %q: must be one of %s", field, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// projectFields returns the named fields of the result, omitting empty optional fields
+func projectFields(result *token.TokenResult, fields []string) (map[string]interface{}, error) {
+	if err := ValidateFields(fields); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
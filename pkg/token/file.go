@@ -0,0 +1,45 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OutputExtPlaceholder is replaced with the output format name in output file templates
+const OutputExtPlaceholder = "{ext}"
+
+// OutputPath returns the output file path for the format, replacing
+// OutputExtPlaceholder in the template with the format name
+func OutputPath(template string, format OutputFormat) string {
+	return strings.ReplaceAll(template, OutputExtPlaceholder, string(format))
+}
+
+// WriteFileAtomic writes data to path by writing a temporary file in the same
+// directory and renaming it into place, so readers never observe a partial file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.txt")
+
+	// Existing content with looser permissions must be replaced entirely
+	if err := os.WriteFile(path, []byte("old content that is longer"), 0644); err != nil {
+		t.Fatalf("Failed to create existing file: %v", err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("new-token\n"), 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "new-token\n" {
+		t.Errorf("Expected file content 'new-token', got %q", string(data))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "token.txt")
+	if err := WriteFileAtomic(path, []byte("token"), 0600); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	tests := []struct {
+		template string
+		format   OutputFormat
+		want     string
+	}{
+		{"token.{ext}", OutputFormatJSON, "token.json"},
+		{"out/{ext}/token.{ext}", OutputFormatRaw, "out/raw/token.raw"},
+		{"token.json", OutputFormatJSON, "token.json"},
+	}
+
+	for _, tt := range tests {
+		if got := OutputPath(tt.template, tt.format); got != tt.want {
+			t.Errorf("OutputPath(%q, %s) = %q, want %q", tt.template, tt.format, got, tt.want)
+		}
+	}
+}
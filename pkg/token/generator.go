@@ -1,17 +1,31 @@
 package token
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	"github.com/aaronwang/pctl/internal/logger"
 	"github.com/aaronwang/pctl/internal/token"
 )
 
 // Generator is the main token generator interface
 type Generator interface {
 	Generate() (*token.TokenResult, error)
+	GenerateContext(ctx context.Context) (*token.TokenResult, error)
+}
+
+// Metrics records token generation outcomes, e.g. to feed Prometheus.
+// ObserveGenerate is called after each token request to PAIC; cached tokens are not observed.
+type Metrics interface {
+	ObserveGenerate(duration time.Duration, success bool)
 }
 
 // GeneratorOptions represents options for token generation
@@ -19,6 +33,37 @@ type GeneratorOptions struct {
 	Config       token.TokenConfig
 	OutputFormat OutputFormat
 	Verbose      bool
+	Cache        *FileCache   // Optional; when set, valid cached tokens are reused
+	ExportPrefix string       // Variable prefix for the export format, defaults to DefaultExportPrefix
+	Logger       *slog.Logger // Optional; defaults to debug output on stderr when Verbose
+	Metrics      Metrics      // Optional; when set, observes each token request to PAIC
+	Fields       []string     // Optional; limits JSON and YAML output to these top-level result fields
+	TimeFormat   TimeFormat   // Timestamp format for text output, defaults to TimeFormatHuman
+	Decode       bool         // Add the decoded access token claims to the result
+	ShowToken    bool         // Print tokens in full in text output instead of masking them
+
+	// FormatTemplate is a Go text/template the result is rendered through,
+	// overriding OutputFormat and Fields. Optional.
+	FormatTemplate string
+
+	// MinValidity is how long a token must remain valid. Cached tokens closer
+	// to expiry are regenerated, and a generated token expiring sooner is an
+	// error matching ErrMinValidity. Optional.
+	MinValidity time.Duration
+
+	// OTPPrompt is called for a one-time password when user authentication
+	// requires one and the configuration has no otp. Optional.
+	OTPPrompt func(prompt string) (string, error)
+
+	// OpenBrowser opens the authorization-code login URL. When nil, the URL
+	// is printed to stderr instead. Optional.
+	OpenBrowser func(authorizationURL string) error
+
+	// HTTPClient is shared by every request to PAIC so connections are
+	// reused, e.g. by long-running servers. Its transport replaces the
+	// configured TLS and proxy settings. When nil, each request creates a
+	// client from the configuration. Optional.
+	HTTPClient *http.Client
 }
 
 // Client is the main entry point for token operations
@@ -28,67 +73,290 @@ type Client struct {
 
 // NewClient creates a new token client
 func NewClient(options GeneratorOptions) *Client {
+	if options.HTTPClient != nil {
+		options.Config.HTTPClient = options.HTTPClient
+	}
 	return &Client{
 		options: options,
 	}
 }
 
+// NewHTTPClient creates an HTTP client with the configured TLS, proxy and
+// timeout settings, for sharing through GeneratorOptions.HTTPClient
+func NewHTTPClient(config token.TokenConfig) (*http.Client, error) {
+	return token.NewHTTPClient(config)
+}
+
 // Generate generates a token based on the configuration
 func (c *Client) Generate() (*token.TokenResult, error) {
+	return c.GenerateContext(context.Background())
+}
+
+// GenerateContext generates a token based on the configuration, aborting
+// any in-flight request to PAIC when ctx is cancelled or its deadline passes
+func (c *Client) GenerateContext(ctx context.Context) (*token.TokenResult, error) {
 	// Validate configuration
 	if err := Validate(&c.options.Config); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+		return nil, err
+	}
+	// Only user and custom tokens honour require_scope; other types, such as
+	// token exchange, may rightly keep the original grant without a scope
+	scoped := c.options.Config.Type == token.TokenTypeUser || c.options.Config.Type == token.TokenTypeCustom
+	if c.options.Verbose && scoped && !hasScope(&c.options.Config) {
+		c.logger().Warn("no scope requested; the token may have no usable permissions (set require_scope to make this an error)",
+			"type", c.options.Config.Type)
+	}
+	if !c.options.Config.StrictExpiry {
+		if err := checkExpiry(&c.options.Config); err != nil {
+			c.logger().Warn("exp_seconds and expiresIn conflict; using expiresIn (set strict_expiry to make this an error)", "error", err)
+		}
+	}
+	if !c.options.Config.StrictAudience {
+		if err := token.CheckAudience(c.options.Config); err != nil {
+			c.logger().Warn("service account audience check failed; PAIC may reject the assertion with invalid_jwt (set strict_audience to make this an error)",
+				"error", err)
+		}
+	}
+
+	// Reuse a cached token when it is still valid. Assertions are never cached,
+	// as they are not access tokens and are meant for a single exchange.
+	cache := c.options.Cache
+	if c.options.Config.AssertionOnly {
+		cache = nil
+	}
+	if cache != nil && c.options.MinValidity > cache.Buffer {
+		cache = &FileCache{Dir: cache.Dir, Buffer: c.options.MinValidity}
+	}
+	if cache != nil {
+		if result, ok := cache.Get(&c.options.Config); ok {
+			c.logger().Debug("using cached token", "expires_at", result.ExpiresAt)
+			if c.options.Config.Fingerprint && result.Metadata["fingerprint"] == nil {
+				if result.Metadata == nil {
+					result.Metadata = make(map[string]interface{})
+				}
+				result.Metadata["fingerprint"] = token.Fingerprint(result.AccessToken)
+			}
+			c.decode(result)
+			return result, nil
+		}
 	}
 
 	// Create appropriate generator based on token type
 	var generator Generator
 	switch c.options.Config.Type {
 	case token.TokenTypeServiceAccount:
-		generator = &token.ServiceAccountGenerator{Config: c.options.Config, Verbose: c.options.Verbose}
+		generator = &token.ServiceAccountGenerator{Config: c.options.Config, Logger: c.logger()}
 	case token.TokenTypeUser:
-		generator = &token.UserTokenGenerator{Config: c.options.Config, Verbose: c.options.Verbose}
+		generator = &token.UserTokenGenerator{Config: c.options.Config, Logger: c.logger(), OTPPrompt: c.options.OTPPrompt}
 	case token.TokenTypeCustom:
-		generator = &token.CustomTokenGenerator{Config: c.options.Config, Verbose: c.options.Verbose}
+		generator = &token.CustomTokenGenerator{Config: c.options.Config, Logger: c.logger()}
+	case token.TokenTypeAuthorizationCode:
+		generator = &token.AuthorizationCodeGenerator{Config: c.options.Config, Logger: c.logger(), OpenBrowser: c.options.OpenBrowser}
+	case token.TokenTypeTokenExchange:
+		generator = &token.TokenExchangeGenerator{Config: c.options.Config, Logger: c.logger()}
+	case token.TokenTypeDeviceCode:
+		generator = &token.DeviceCodeGenerator{Config: c.options.Config, Logger: c.logger()}
 	default:
 		return nil, fmt.Errorf("unsupported token type: %s", c.options.Config.Type)
 	}
 
-	return generator.Generate()
+	start := time.Now()
+	result, err := generator.GenerateContext(ctx)
+	if c.options.Metrics != nil {
+		c.options.Metrics.ObserveGenerate(time.Since(start), err == nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkMinValidity(result); err != nil {
+		return nil, err
+	}
+
+	if cache != nil {
+		if err := cache.Put(&c.options.Config, result); err != nil {
+			c.logger().Warn("failed to cache token", "error", err)
+		}
+	}
+
+	c.decode(result)
+	return result, nil
+}
+
+// checkMinValidity returns an error matching ErrMinValidity when the token
+// expires within the minimum validity, or has an unknown expiry
+func (c *Client) checkMinValidity(result *token.TokenResult) error {
+	if c.options.MinValidity <= 0 || !result.ExpiresWithin(c.options.MinValidity) {
+		return nil
+	}
+	if result.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: expiry is unknown, but a minimum validity of %s is required", ErrMinValidity, c.options.MinValidity)
+	}
+	return fmt.Errorf("%w: it is valid for %s, less than the minimum validity of %s",
+		ErrMinValidity, time.Until(result.ExpiresAt).Round(time.Second), c.options.MinValidity)
+}
+
+// decode adds the decoded access token claims to the result when requested
+func (c *Client) decode(result *token.TokenResult) {
+	if c.options.Decode {
+		result.Decoded = token.DecodeJWT(result.AccessToken)
+	}
+}
+
+// logger returns the configured logger, falling back to the default for the verbosity
+func (c *Client) logger() *slog.Logger {
+	if c.options.Logger != nil {
+		return c.options.Logger
+	}
+	return logger.Default(c.options.Verbose)
 }
 
 // FormatOutput formats the token result according to the specified format
 func (c *Client) FormatOutput(result *token.TokenResult) (string, error) {
-	switch c.options.OutputFormat {
-	case OutputFormatJSON:
-		data, err := json.MarshalIndent(result, "", "  ")
+	if c.options.FormatTemplate != "" {
+		return c.formatTemplate(result)
+	}
+	if len(c.options.Fields) > 0 {
+		if c.options.OutputFormat != OutputFormatJSON && c.options.OutputFormat != OutputFormatYAML && c.options.OutputFormat != OutputFormatJSONL {
+			return "", fmt.Errorf("fields require json, jsonl or yaml output, got %s", c.options.OutputFormat)
+		}
+		projected, err := projectFields(result, c.options.Fields)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal JSON: %w", err)
+			return "", err
 		}
-		return string(data), nil
+		output, _, err := c.formatStructured(projected)
+		return output, err
+	}
 
-	case OutputFormatYAML:
-		data, err := yaml.Marshal(result)
-		if err != nil {
-			return "", fmt.Errorf("failed to marshal YAML: %w", err)
+	if output, ok, err := c.formatStructured(result); ok {
+		return output, err
+	}
+
+	switch c.options.OutputFormat {
+	case OutputFormatRaw:
+		return result.AccessToken + "\n", nil
+
+	case OutputFormatExport:
+		prefix := c.options.ExportPrefix
+		if prefix == "" {
+			prefix = DefaultExportPrefix
+		}
+		var output strings.Builder
+		output.WriteString(fmt.Sprintf("export %s_ACCESS_TOKEN=%s\n", prefix, shellQuote(result.AccessToken)))
+		output.WriteString(fmt.Sprintf("export %s_TOKEN_TYPE=%s\n", prefix, shellQuote(result.TokenType)))
+		output.WriteString(fmt.Sprintf("export %s_EXPIRES_AT=%s\n", prefix, shellQuote(result.ExpiresAt.Format(time.RFC3339))))
+		if result.IDToken != "" {
+			output.WriteString(fmt.Sprintf("export %s_ID_TOKEN=%s\n", prefix, shellQuote(result.IDToken)))
 		}
-		return string(data), nil
+		return output.String(), nil
 
-	case OutputFormatText:
-		fallthrough
-	default:
+	case OutputFormatText, "":
 		var output strings.Builder
 		output.WriteString("Token Generation Result:\n")
 		output.WriteString("=======================\n")
-		output.WriteString(fmt.Sprintf("Access Token: %s\n", result.AccessToken))
+		output.WriteString(fmt.Sprintf("Access Token: %s\n", c.displayToken(result.AccessToken)))
 		output.WriteString(fmt.Sprintf("Token Type: %s\n", result.TokenType))
 		output.WriteString(fmt.Sprintf("Expires In: %d seconds\n", result.ExpiresIn))
-		output.WriteString(fmt.Sprintf("Expires At: %s\n", result.ExpiresAt.Format("2006-01-02 15:04:05 MST")))
+		output.WriteString(fmt.Sprintf("Expires At: %s\n", c.formatTime(result.ExpiresAt)))
 		if result.Scope != "" {
 			output.WriteString(fmt.Sprintf("Scope: %s\n", result.Scope))
 		}
 		if result.RefreshToken != "" {
-			output.WriteString(fmt.Sprintf("Refresh Token: %s\n", result.RefreshToken))
+			output.WriteString(fmt.Sprintf("Refresh Token: %s\n", c.displayToken(result.RefreshToken)))
+		}
+		if result.IDToken != "" {
+			output.WriteString(fmt.Sprintf("ID Token: %s\n", c.displayToken(result.IDToken)))
+		}
+		if result.Decoded != nil {
+			output.WriteString(formatDecoded(result.Decoded))
 		}
 		return output.String(), nil
+
+	default:
+		return "", ValidateOutputFormat(c.options.OutputFormat)
+	}
+}
+
+// maskedTokenChars is how many characters of a token are kept at each end when masking it
+const maskedTokenChars = 3
+
+// displayToken returns the token for text output: in full when ShowToken is
+// set, otherwise masked to its first and last few characters, such as
+// eyJ...abc, so it does not end up in terminal scrollback. Tokens too short
+// to mask that way are hidden entirely.
+func (c *Client) displayToken(t string) string {
+	if c.options.ShowToken {
+		return t
+	}
+	if len(t) < 4*maskedTokenChars {
+		return "..."
+	}
+	return t[:maskedTokenChars] + "..." + t[len(t)-maskedTokenChars:]
+}
+
+// formatDecoded renders the decoded access token claims as an extra text block
+func formatDecoded(decoded *token.DecodedToken) string {
+	var output strings.Builder
+	output.WriteString("\nDecoded Claims:\n")
+	output.WriteString("===============\n")
+	if decoded.Note != "" {
+		output.WriteString(decoded.Note + "\n")
+		return output.String()
+	}
+
+	names := make([]string, 0, len(decoded.Claims))
+	for name := range decoded.Claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		output.WriteString(fmt.Sprintf("%s: %v\n", name, decoded.Claims[name]))
 	}
-}
\ No newline at end of file
+	return output.String()
+}
+
+// formatTime renders a timestamp for text output in the configured time format
+func (c *Client) formatTime(t time.Time) string {
+	switch c.options.TimeFormat {
+	case TimeFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	default:
+		return t.Format("2006-01-02 15:04:05 MST")
+	}
+}
+
+// formatStructured marshals v when the output format is JSON, JSON Lines or YAML.
+// It reports false for other formats so callers can render their own text.
+func (c *Client) formatStructured(v interface{}) (string, bool, error) {
+	switch c.options.OutputFormat {
+	case OutputFormatJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return "", true, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return string(data), true, nil
+
+	case OutputFormatJSONL:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", true, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return string(data) + "\n", true, nil
+
+	case OutputFormatYAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return "", true, fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		return string(data), true, nil
+
+	default:
+		return "", false, nil
+	}
+}
+
+// shellQuote single-quotes a value so it is safe to eval in a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
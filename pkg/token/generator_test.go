@@ -1,7 +1,11 @@
 package token
 
 import (
+	"bytes"
+	"errors"
+	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/aaronwang/pctl/internal/token"
 )
@@ -45,7 +49,7 @@ func TestFormatOutput(t *testing.T) {
 		{
 			name:         "text format",
 			outputFormat: OutputFormatText,
-			wantContains: []string{"Token Generation Result", "Access Token: test-token", "Token Type: Bearer"},
+			wantContains: []string{"Token Generation Result", "Access Token: ...\n", "Token Type: Bearer"},
 			wantErr:      false,
 		},
 		{
@@ -60,6 +64,12 @@ func TestFormatOutput(t *testing.T) {
 			wantContains: []string{"access_token: test-token", "token_type: Bearer"},
 			wantErr:      false,
 		},
+		{
+			name:         "raw format",
+			outputFormat: OutputFormatRaw,
+			wantContains: []string{"test-token"},
+			wantErr:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -86,6 +96,61 @@ func TestFormatOutput(t *testing.T) {
 	}
 }
 
+func TestFormatOutputRaw(t *testing.T) {
+	client := NewClient(GeneratorOptions{OutputFormat: OutputFormatRaw})
+
+	output, err := client.FormatOutput(&token.TokenResult{AccessToken: "raw-token", TokenType: "Bearer"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output != "raw-token\n" {
+		t.Errorf("Expected only the access token, got %q", output)
+	}
+}
+
+func TestFormatOutputExport(t *testing.T) {
+	result := &token.TokenResult{
+		AccessToken: "abc'$(rm -rf)`x`",
+		TokenType:   "Bearer",
+		ExpiresAt:   time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{
+			name:   "default prefix",
+			prefix: "",
+			want: "export PCTL_ACCESS_TOKEN='abc'\\''$(rm -rf)`x`'\n" +
+				"export PCTL_TOKEN_TYPE='Bearer'\n" +
+				"export PCTL_EXPIRES_AT='2030-01-02T03:04:05Z'\n",
+		},
+		{
+			name:   "custom prefix",
+			prefix: "PAIC",
+			want: "export PAIC_ACCESS_TOKEN='abc'\\''$(rm -rf)`x`'\n" +
+				"export PAIC_TOKEN_TYPE='Bearer'\n" +
+				"export PAIC_EXPIRES_AT='2030-01-02T03:04:05Z'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(GeneratorOptions{OutputFormat: OutputFormatExport, ExportPrefix: tt.prefix})
+
+			output, err := client.FormatOutput(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output != tt.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, output)
+			}
+		})
+	}
+}
+
 func TestGenerateValidationErrors(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -145,4 +210,475 @@ func TestGenerateValidationErrors(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// recordingMetrics records the outcomes observed by the client
+type recordingMetrics struct {
+	successes []bool
+}
+
+func (m *recordingMetrics) ObserveGenerate(duration time.Duration, success bool) {
+	m.successes = append(m.successes, success)
+}
+
+func TestGenerateMetrics(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 3600, &requests)
+	metrics := &recordingMetrics{}
+
+	client := NewClient(GeneratorOptions{Config: customClientConfig(server), Metrics: metrics})
+	if _, err := client.Generate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	client.options.Config.Platform = "https://127.0.0.1:1"
+	client.options.Config.Retries = -1
+	if _, err := client.Generate(); err == nil {
+		t.Fatal("Expected error for unreachable platform")
+	}
+
+	// Validation failures never reach PAIC and are not observed
+	client.options.Config.ClientID = ""
+	client.Generate()
+
+	if len(metrics.successes) != 2 || !metrics.successes[0] || metrics.successes[1] {
+		t.Errorf("Expected observations [true false], got %v", metrics.successes)
+	}
+}
+
+func TestFormatOutputFields(t *testing.T) {
+	result := &token.TokenResult{
+		AccessToken: "test-token",
+		TokenType:   "Bearer",
+		ExpiresIn:   3600,
+		ExpiresAt:   time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name    string
+		format  OutputFormat
+		fields  []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "json projection",
+			format: OutputFormatJSON,
+			fields: []string{"access_token", "expires_at"},
+			want:   "{\n  \"access_token\": \"test-token\",\n  \"expires_at\": \"2030-01-02T03:04:05Z\"\n}",
+		},
+		{
+			name:   "yaml projection",
+			format: OutputFormatYAML,
+			fields: []string{"expires_in"},
+			want:   "expires_in: 3600\n",
+		},
+		{
+			name:   "empty optional field omitted",
+			format: OutputFormatJSON,
+			fields: []string{"access_token", "scope"},
+			want:   "{\n  \"access_token\": \"test-token\"\n}",
+		},
+		{name: "unknown field", format: OutputFormatJSON, fields: []string{"acess_token"}, wantErr: `unknown field "acess_token"`},
+		{name: "text output", format: OutputFormatText, fields: []string{"access_token"}, wantErr: "fields require json, jsonl or yaml output"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(GeneratorOptions{OutputFormat: tt.format, Fields: tt.fields})
+
+			output, err := client.FormatOutput(result)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output != tt.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, output)
+			}
+		})
+	}
+}
+
+func TestParseOutputFormats(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []OutputFormat
+		wantErr string
+	}{
+		{value: "json", want: []OutputFormat{OutputFormatJSON}},
+		{value: "json,raw", want: []OutputFormat{OutputFormatJSON, OutputFormatRaw}},
+		{value: "yaml, export", want: []OutputFormat{OutputFormatYAML, OutputFormatExport}},
+		{value: "json,xml", wantErr: `invalid output format "xml"`},
+		{value: "json,json", wantErr: `output format "json" is listed more than once`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOutputFormats(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	for _, format := range OutputFormats {
+		if err := ValidateOutputFormat(format); err != nil {
+			t.Errorf("Unexpected error for %s: %v", format, err)
+		}
+	}
+
+	want := `invalid output format "josn": must be one of text, json, yaml, raw, export, jsonl`
+	if err := ValidateOutputFormat("josn"); err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+	if _, err := NewClient(GeneratorOptions{OutputFormat: "josn"}).FormatOutput(&token.TokenResult{}); err == nil || err.Error() != want {
+		t.Errorf("Expected FormatOutput to reject unknown format, got %v", err)
+	}
+}
+
+func TestFormatOutputTimeFormat(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := &token.TokenResult{AccessToken: "test-token", TokenType: "Bearer", ExpiresAt: expiresAt}
+
+	tests := []struct {
+		format TimeFormat
+		want   string
+	}{
+		{"", "Expires At: 2030-01-02 03:04:05 UTC\n"},
+		{TimeFormatHuman, "Expires At: 2030-01-02 03:04:05 UTC\n"},
+		{TimeFormatRFC3339, "Expires At: 2030-01-02T03:04:05Z\n"},
+		{TimeFormatUnix, "Expires At: 1893553445\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			client := NewClient(GeneratorOptions{OutputFormat: OutputFormatText, TimeFormat: tt.format})
+			output, err := client.FormatOutput(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !containsString(output, tt.want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.want, output)
+			}
+		})
+	}
+
+	if err := ValidateTimeFormat("iso"); err == nil || !containsString(err.Error(), "must be one of human, rfc3339, unix") {
+		t.Errorf("Expected invalid time format error, got %v", err)
+	}
+}
+
+func TestFormatOutputMaskToken(t *testing.T) {
+	result := &token.TokenResult{
+		AccessToken:  "eyJhbGciOiJSUzI1NiJ9.payload.signature-abc",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-token-xyz",
+		IDToken:      "short",
+	}
+
+	tests := []struct {
+		name      string
+		format    OutputFormat
+		showToken bool
+		want      []string
+		notWant   []string
+	}{
+		{
+			name:    "text masked",
+			format:  OutputFormatText,
+			want:    []string{"Access Token: eyJ...abc\n", "Refresh Token: ref...xyz\n", "ID Token: ...\n"},
+			notWant: []string{"payload", "refresh-token-xyz", "short"},
+		},
+		{
+			name:      "text shown",
+			format:    OutputFormatText,
+			showToken: true,
+			want:      []string{"Access Token: eyJhbGciOiJSUzI1NiJ9.payload.signature-abc\n", "Refresh Token: refresh-token-xyz\n", "ID Token: short\n"},
+		},
+		{
+			name:   "json unmasked",
+			format: OutputFormatJSON,
+			want:   []string{`"access_token": "eyJhbGciOiJSUzI1NiJ9.payload.signature-abc"`},
+		},
+		{
+			name:   "yaml unmasked",
+			format: OutputFormatYAML,
+			want:   []string{"access_token: eyJhbGciOiJSUzI1NiJ9.payload.signature-abc"},
+		},
+		{
+			name:   "raw unmasked",
+			format: OutputFormatRaw,
+			want:   []string{"eyJhbGciOiJSUzI1NiJ9.payload.signature-abc\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := NewClient(GeneratorOptions{OutputFormat: tt.format, ShowToken: tt.showToken}).FormatOutput(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !containsString(output, want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if containsString(output, notWant) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", notWant, output)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatOutputIDToken(t *testing.T) {
+	result := &token.TokenResult{AccessToken: "test-token", TokenType: "Bearer", IDToken: "test-id-token"}
+
+	tests := []struct {
+		format OutputFormat
+		want   string
+	}{
+		{OutputFormatText, "ID Token: tes...ken\n"},
+		{OutputFormatJSON, `"id_token": "test-id-token"`},
+		{OutputFormatYAML, "id_token: test-id-token\n"},
+		{OutputFormatExport, "export PCTL_ID_TOKEN='test-id-token'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			output, err := NewClient(GeneratorOptions{OutputFormat: tt.format}).FormatOutput(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !containsString(output, tt.want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.want, output)
+			}
+		})
+	}
+}
+
+func TestFormatOutputDecoded(t *testing.T) {
+	result := &token.TokenResult{
+		AccessToken: "test-token",
+		TokenType:   "Bearer",
+		Decoded:     &token.DecodedToken{Claims: map[string]interface{}{"sub": "test-subject", "scope": "fr:idm:*"}},
+	}
+
+	tests := []struct {
+		format OutputFormat
+		want   string
+	}{
+		{OutputFormatText, "Decoded Claims:\n===============\nscope: fr:idm:*\nsub: test-subject\n"},
+		{OutputFormatJSON, `"sub": "test-subject"`},
+		{OutputFormatYAML, "decoded:\n    claims:\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			output, err := NewClient(GeneratorOptions{OutputFormat: tt.format}).FormatOutput(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !containsString(output, tt.want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.want, output)
+			}
+		})
+	}
+
+	opaque := &token.TokenResult{AccessToken: "test-token", Decoded: &token.DecodedToken{Note: "access token is not a JWT; decoding skipped"}}
+	output, err := NewClient(GeneratorOptions{OutputFormat: OutputFormatText}).FormatOutput(opaque)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !containsString(output, "Decoded Claims:\n===============\naccess token is not a JWT") {
+		t.Errorf("Expected decoding note in output, got:\n%s", output)
+	}
+}
+
+func TestGenerateDecode(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 3600, &requests)
+
+	for _, decode := range []bool{false, true} {
+		result, err := NewClient(GeneratorOptions{Config: customClientConfig(server), Decode: decode}).Generate()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if (result.Decoded != nil) != decode {
+			t.Errorf("Expected decoded %v, got %+v", decode, result.Decoded)
+		}
+		if decode && result.Decoded.Note == "" {
+			t.Errorf("Expected opaque token to be noted, got %+v", result.Decoded)
+		}
+	}
+}
+
+func TestGenerateMissingScopeWarning(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 3600, &requests)
+
+	tests := []struct {
+		name      string
+		tokenType token.TokenType
+		scope     string
+		verbose   bool
+		want      bool
+	}{
+		{name: "verbose without scope", verbose: true, want: true},
+		{name: "verbose with scope", scope: "fr:idm:*", verbose: true, want: false},
+		{name: "quiet without scope", verbose: false, want: false},
+		{name: "token exchange without scope", tokenType: token.TokenTypeTokenExchange, verbose: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			config := customClientConfig(server)
+			config.Scope = tt.scope
+			if tt.tokenType != "" {
+				config.Type = tt.tokenType
+				config.SubjectToken = "subject-token"
+			}
+			client := NewClient(GeneratorOptions{
+				Config:  config,
+				Verbose: tt.verbose,
+				Logger:  slog.New(slog.NewTextHandler(&stderr, nil)),
+			})
+
+			if _, err := client.Generate(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := containsString(stderr.String(), "no scope requested"); got != tt.want {
+				t.Errorf("Expected warning %t, got log:\n%s", tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestGenerateExpiryConflict(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 3600, &requests)
+
+	tests := []struct {
+		name       string
+		expSeconds int
+		expiresIn  time.Duration
+		strict     bool
+		wantWarn   bool
+		wantErr    bool
+	}{
+		{name: "expiresIn only", expiresIn: 30 * time.Minute},
+		{name: "exp_seconds only", expSeconds: 900},
+		{name: "exp_seconds only with strict_expiry", expSeconds: 900, strict: true},
+		{name: "same lifetime", expSeconds: 1800, expiresIn: 30 * time.Minute},
+		{name: "conflict", expSeconds: 900, expiresIn: 30 * time.Minute, wantWarn: true},
+		{name: "conflict with strict_expiry", expSeconds: 900, expiresIn: 30 * time.Minute, strict: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			config := customClientConfig(server)
+			config.ExpSeconds = tt.expSeconds
+			config.ExpiresIn = tt.expiresIn
+			config.StrictExpiry = tt.strict
+			client := NewClient(GeneratorOptions{
+				Config: config,
+				Logger: slog.New(slog.NewTextHandler(&stderr, nil)),
+			})
+
+			_, err := client.Generate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) || !containsString(err.Error(), "exp_seconds 900 (15m0s) conflicts with expiresIn 30m0s") {
+					t.Fatalf("Expected expiry conflict validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := containsString(stderr.String(), "exp_seconds and expiresIn conflict"); got != tt.wantWarn {
+				t.Errorf("Expected warning %t, got log:\n%s", tt.wantWarn, stderr.String())
+			}
+		})
+	}
+}
+
+func TestGenerateMinValidity(t *testing.T) {
+	tests := []struct {
+		name        string
+		expiresIn   int
+		minValidity time.Duration
+		wantErr     bool
+	}{
+		{name: "unset", expiresIn: 60},
+		{name: "valid long enough", expiresIn: 3600, minValidity: 10 * time.Minute},
+		{name: "expires too soon", expiresIn: 300, minValidity: 10 * time.Minute, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := newCountingTokenServer(t, tt.expiresIn, &requests)
+			client := NewClient(GeneratorOptions{Config: customClientConfig(server), MinValidity: tt.minValidity})
+
+			_, err := client.Generate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrMinValidity) {
+					t.Fatalf("Expected ErrMinValidity, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateMinValidityRegeneratesCachedToken(t *testing.T) {
+	var requests int32
+	server := newCountingTokenServer(t, 3600, &requests)
+	config := customClientConfig(server)
+
+	cache, err := NewFileCache(t.TempDir(), DefaultCacheBuffer)
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	cached := &token.TokenResult{AccessToken: "cached-token", ExpiresAt: time.Now().Add(5 * time.Minute)}
+	if err := cache.Put(&config, cached); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// The cached token is still outside the cache buffer, so it is reused by default
+	result, err := NewClient(GeneratorOptions{Config: config, Cache: cache}).Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.AccessToken != "cached-token" {
+		t.Errorf("Expected cached token, got %s", result.AccessToken)
+	}
+
+	result, err = NewClient(GeneratorOptions{Config: config, Cache: cache, MinValidity: 10 * time.Minute}).Generate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.AccessToken != "token-1" || requests != 1 {
+		t.Errorf("Expected a regenerated token, got %s after %d requests", result.AccessToken, requests)
+	}
+}
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// TokenPath is the path NewTokenHandler serves the access token at
+const TokenPath = "/token"
+
+// tokenResponse is the body served at TokenPath
+type tokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// NewTokenHandler returns an HTTP handler serving the client's current access
+// token as JSON at GET /token, in the style of a cloud metadata server.
+// Tokens are generated on first request and regenerated as they near expiry.
+func NewTokenHandler(client *CachingClient) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+TokenPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		result, err := client.Result(r.Context())
+		if err != nil {
+			client.client.logger().Error("token generation failed", "error", err)
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken: result.AccessToken,
+			TokenType:   result.TokenType,
+			ExpiresAt:   result.ExpiresAt,
+		})
+	})
+	return mux
+}
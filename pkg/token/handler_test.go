@@ -0,0 +1,64 @@
+package token
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestTokenHandler(t *testing.T) {
+	var requests int32
+	paic := newCountingTokenServer(t, 3600, &requests)
+	server := httptest.NewServer(NewTokenHandler(NewCachingClient(NewClient(GeneratorOptions{Config: customClientConfig(paic)}), 0)))
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL + TokenPath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if body["access_token"] != "token-1" {
+			t.Errorf("Expected cached token-1, got %v", body["access_token"])
+		}
+		expiresAt, err := time.Parse(time.RFC3339, body["expires_at"].(string))
+		if err != nil || time.Until(expiresAt) < 59*time.Minute {
+			t.Errorf("Expected expires_at about an hour away, got %v", body["expires_at"])
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single token request, got %d", requests)
+	}
+
+	resp, err := http.Post(server.URL+TokenPath, "application/json", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", resp.StatusCode)
+	}
+}
+
+func TestTokenHandlerError(t *testing.T) {
+	client := NewCachingClient(NewClient(GeneratorOptions{Config: token.TokenConfig{Type: token.TokenTypeCustom}}), 0)
+	recorder := httptest.NewRecorder()
+	NewTokenHandler(client).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, TokenPath, nil))
+
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", recorder.Code)
+	}
+	if !containsString(recorder.Body.String(), "baseUrl or platform is required") {
+		t.Errorf("Expected error in body, got %s", recorder.Body.String())
+	}
+}
@@ -0,0 +1,51 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// IntrospectionResult represents the response from the PAIC token introspection endpoint
+type IntrospectionResult = token.IntrospectionResult
+
+// Introspect checks whether an access token is still active, authenticating
+// with the client credentials from the configuration
+func (c *Client) Introspect(ctx context.Context, accessToken string) (*IntrospectionResult, error) {
+	if accessToken == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+	if err := validateClientConfig(&c.options.Config); err != nil {
+		return nil, err
+	}
+
+	return token.Introspect(ctx, c.options.Config, accessToken, c.logger())
+}
+
+// FormatIntrospection formats the introspection result according to the specified format
+func (c *Client) FormatIntrospection(result *IntrospectionResult) (string, error) {
+	if output, ok, err := c.formatStructured(result); ok {
+		return output, err
+	}
+
+	var output strings.Builder
+	output.WriteString("Token Introspection Result:\n")
+	output.WriteString("===========================\n")
+	output.WriteString(fmt.Sprintf("Active: %t\n", result.Active))
+	if result.Scope != "" {
+		output.WriteString(fmt.Sprintf("Scope: %s\n", result.Scope))
+	}
+	if result.Sub != "" {
+		output.WriteString(fmt.Sprintf("Subject: %s\n", result.Sub))
+	}
+	if result.ClientID != "" {
+		output.WriteString(fmt.Sprintf("Client ID: %s\n", result.ClientID))
+	}
+	if result.Exp != 0 {
+		output.WriteString(fmt.Sprintf("Expires At: %s\n", c.formatTime(time.Unix(result.Exp, 0))))
+	}
+	return output.String(), nil
+}
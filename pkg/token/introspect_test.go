@@ -0,0 +1,88 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestIntrospect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/am/oauth2/introspect" {
+			t.Errorf("Expected introspection endpoint path, got %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("token") != "test-token" {
+			t.Errorf("Expected token 'test-token', got %s", r.PostForm.Get("token"))
+		}
+		if r.PostForm.Get("client_id") != "test-client" {
+			t.Errorf("Expected client_id 'test-client', got %s", r.PostForm.Get("client_id"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"active": true,
+			"scope":  "fr:am:*",
+			"exp":    1893456000,
+			"sub":    "test-subject",
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(GeneratorOptions{
+		Config: token.TokenConfig{
+			Platform:         server.URL,
+			ClientID:         "test-client",
+			ClientSecret:     "test-secret",
+			AllowInsecureURL: true,
+		},
+	})
+
+	result, err := client.Introspect(context.Background(), "test-token")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Active || result.Sub != "test-subject" || result.Exp != 1893456000 {
+		t.Errorf("Unexpected introspection result: %+v", result)
+	}
+
+	tests := []struct {
+		format OutputFormat
+		want   []string
+	}{
+		{OutputFormatText, []string{"Active: true", "Scope: fr:am:*", "Subject: test-subject"}},
+		{OutputFormatJSON, []string{`"active": true`, `"sub": "test-subject"`}},
+		{OutputFormatYAML, []string{"active: true", "sub: test-subject"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			client.options.OutputFormat = tt.format
+			output, err := client.FormatIntrospection(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !containsString(output, want) {
+					t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestIntrospectValidation(t *testing.T) {
+	client := NewClient(GeneratorOptions{Config: token.TokenConfig{Platform: "https://test.forgerock.com"}})
+
+	if _, err := client.Introspect(context.Background(), ""); err == nil {
+		t.Error("Expected error for empty token")
+	}
+	_, err := client.Introspect(context.Background(), "test-token")
+	if err == nil || !containsString(err.Error(), "clientId is required") {
+		t.Errorf("Expected clientId validation error, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// keychainPrefix marks a configuration value that references an OS keychain entry
+const keychainPrefix = "keychain:"
+
+// secretStore looks up secrets by service name
+type secretStore interface {
+	Lookup(service string) (string, error)
+}
+
+// keychain is the secret store keychain references are resolved from
+var keychain secretStore = systemKeychain{}
+
+// resolveSecret returns the secret a keychain:<service> reference points to,
+// or the value unchanged when it is not a reference
+func resolveSecret(value, key string) (string, error) {
+	service, ok := strings.CutPrefix(value, keychainPrefix)
+	if !ok {
+		return value, nil
+	}
+	if service == "" {
+		return "", fmt.Errorf("keychain reference for %s has no service name", key)
+	}
+
+	secret, err := keychain.Lookup(service)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from keychain entry %q: %w", key, service, err)
+	}
+	return secret, nil
+}
+
+// resolveSecrets replaces keychain references in the credential fields of the configuration
+func resolveSecrets(config *token.TokenConfig) error {
+	fields := []struct {
+		key   string
+		value *string
+	}{
+		{"password", &config.Password},
+		{"clientSecret", &config.ClientSecret},
+		{"refreshToken", &config.RefreshToken},
+		{"privateKey", &config.PrivateKey},
+		{"jwk_json", &config.JWKJson},
+	}
+
+	for _, field := range fields {
+		secret, err := resolveSecret(*field.value, field.key)
+		if err != nil {
+			return err
+		}
+		*field.value = secret
+	}
+	return nil
+}
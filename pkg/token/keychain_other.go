@@ -0,0 +1,42 @@
+//go:build !windows
+
+package token
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// systemKeychain reads generic passwords from the macOS Keychain with the
+// security tool, or from the Secret Service with secret-tool elsewhere
+type systemKeychain struct{}
+
+// Lookup returns the secret stored for the service
+func (systemKeychain) Lookup(service string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", service)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if message := strings.TrimSpace(stderr.String()); message != "" {
+				return "", fmt.Errorf("%s: %s", cmd.Args[0], message)
+			}
+			return "", fmt.Errorf("%s: entry not found", cmd.Args[0])
+		}
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
@@ -0,0 +1,79 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeKeychain is an in-memory secret store for tests
+type fakeKeychain map[string]string
+
+func (k fakeKeychain) Lookup(service string) (string, error) {
+	secret, ok := k[service]
+	if !ok {
+		return "", fmt.Errorf("entry not found")
+	}
+	return secret, nil
+}
+
+func TestLoadConfigKeychain(t *testing.T) {
+	original := keychain
+	keychain = fakeKeychain{"pctl-password": "keychain-password", "pctl-secret": "keychain-secret"}
+	t.Cleanup(func() { keychain = original })
+
+	tests := []struct {
+		name             string
+		yamlContent      string
+		wantPassword     string
+		wantClientSecret string
+		wantErr          string
+	}{
+		{
+			name:             "resolves references",
+			yamlContent:      "password: keychain:pctl-password\nclientSecret: keychain:pctl-secret\n",
+			wantPassword:     "keychain-password",
+			wantClientSecret: "keychain-secret",
+		},
+		{
+			name:             "plaintext values unchanged",
+			yamlContent:      "password: plain-password\nclientSecret: plain-secret\n",
+			wantPassword:     "plain-password",
+			wantClientSecret: "plain-secret",
+		},
+		{
+			name:        "missing entry",
+			yamlContent: "clientSecret: keychain:pctl-missing\n",
+			wantErr:     `failed to read clientSecret from keychain entry "pctl-missing": entry not found`,
+		},
+		{
+			name:        "empty service name",
+			yamlContent: "password: 'keychain:'\n",
+			wantErr:     "keychain reference for password has no service name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create temp config file: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.Password != tt.wantPassword || config.ClientSecret != tt.wantClientSecret {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.wantPassword, tt.wantClientSecret, config.Password, config.ClientSecret)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// ConfigAPIVersion is the current configuration layout. Configurations
+// without an apiVersion use the older authflow-compatible layout and are
+// migrated when loaded.
+const ConfigAPIVersion = token.ConfigAPIVersion
+
+// defaultExpiresIn is the access token lifetime requested when neither expiresIn nor exp_seconds is set
+const defaultExpiresIn = 60 * time.Minute
+
+// migrateConfig normalizes older configuration layouts to the current one
+// and applies defaults. Unversioned authflow-compatible configurations are
+// migrated silently; a configuration declaring the current apiVersion gets a
+// warning for each deprecated field it still uses.
+func migrateConfig(config *token.TokenConfig) error {
+	var deprecated func(field, replacement string)
+	switch config.APIVersion {
+	case "":
+		deprecated = func(string, string) {}
+	case ConfigAPIVersion:
+		deprecated = func(field, replacement string) {
+			config.Warnings = append(config.Warnings, fmt.Sprintf("%s is deprecated in apiVersion %s; use %s", field, ConfigAPIVersion, replacement))
+		}
+	default:
+		return fmt.Errorf("unsupported apiVersion %q: must be %s", config.APIVersion, ConfigAPIVersion)
+	}
+
+	if config.Type == "" {
+		config.Type = token.TokenTypeServiceAccount
+	}
+
+	// authflow named the platform URL platform; it stays set for metadata
+	if config.Platform != "" {
+		deprecated("platform", "baseUrl")
+		if config.BaseURL == "" {
+			config.BaseURL = config.Platform
+		}
+	}
+
+	// authflow set the access token lifetime in seconds with exp_seconds
+	if config.ExpSeconds > 0 {
+		deprecated("exp_seconds", "expiresIn")
+		if config.ExpiresIn == 0 {
+			config.ExpiresIn = time.Duration(config.ExpSeconds) * time.Second
+		}
+	}
+	if config.ExpiresIn == 0 {
+		config.ExpiresIn = defaultExpiresIn
+	}
+
+	// Keep scopes in step with a single space-delimited scope
+	if config.Scope != "" && len(config.Scopes) == 0 {
+		config.Scopes = strings.Split(config.Scope, " ")
+	}
+
+	config.APIVersion = ConfigAPIVersion
+	return nil
+}
+
+// checkExpiry reports exp_seconds and expiresIn both set to different
+// lifetimes, as exp_seconds is then ignored. exp_seconds alone is not a
+// conflict, as in a configuration built without LoadConfig.
+func checkExpiry(config *token.TokenConfig) error {
+	expSeconds := time.Duration(config.ExpSeconds) * time.Second
+	if config.ExpSeconds > 0 && config.ExpiresIn != 0 && config.ExpiresIn != expSeconds {
+		return fmt.Errorf("exp_seconds %d (%s) conflicts with expiresIn %s", config.ExpSeconds, expSeconds, config.ExpiresIn)
+	}
+	return nil
+}
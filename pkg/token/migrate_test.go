@@ -0,0 +1,103 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigMigration(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantErr       string
+		wantBaseURL   string
+		wantExpiresIn time.Duration
+		wantWarnings  []string
+	}{
+		{
+			name: "unversioned authflow layout migrates silently",
+			content: `
+service_account_id: "test-id"
+platform: "https://test.forgerock.com"
+exp_seconds: 900
+`,
+			wantBaseURL:   "https://test.forgerock.com",
+			wantExpiresIn: 900 * time.Second,
+		},
+		{
+			name: "current layout",
+			content: `
+apiVersion: pctl/v1
+service_account_id: "test-id"
+baseUrl: "https://test.forgerock.com"
+expiresIn: 15m
+`,
+			wantBaseURL:   "https://test.forgerock.com",
+			wantExpiresIn: 15 * time.Minute,
+		},
+		{
+			name: "deprecated fields in current layout warn",
+			content: `
+apiVersion: pctl/v1
+service_account_id: "test-id"
+platform: "https://test.forgerock.com"
+exp_seconds: 900
+`,
+			wantBaseURL:   "https://test.forgerock.com",
+			wantExpiresIn: 900 * time.Second,
+			wantWarnings: []string{
+				"platform is deprecated in apiVersion pctl/v1; use baseUrl",
+				"exp_seconds is deprecated in apiVersion pctl/v1; use expiresIn",
+			},
+		},
+		{
+			name: "unsupported version",
+			content: `
+apiVersion: pctl/v9
+service_account_id: "test-id"
+baseUrl: "https://test.forgerock.com"
+`,
+			wantErr: `unsupported apiVersion "pctl/v9"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create temp config file: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if config.APIVersion != ConfigAPIVersion {
+				t.Errorf("Expected apiVersion %s after migration, got %q", ConfigAPIVersion, config.APIVersion)
+			}
+			if config.BaseURL != tt.wantBaseURL {
+				t.Errorf("Expected baseUrl %s, got %s", tt.wantBaseURL, config.BaseURL)
+			}
+			if config.ExpiresIn != tt.wantExpiresIn {
+				t.Errorf("Expected expiresIn %v, got %v", tt.wantExpiresIn, config.ExpiresIn)
+			}
+			if len(config.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("Expected warnings %v, got %v", tt.wantWarnings, config.Warnings)
+			}
+			for i, want := range tt.wantWarnings {
+				if config.Warnings[i] != want {
+					t.Errorf("Expected warning %q, got %q", want, config.Warnings[i])
+				}
+			}
+		})
+	}
+}
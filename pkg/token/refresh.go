@@ -0,0 +1,24 @@
+package token
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// Refresh exchanges a refresh token for a new access token without
+// re-sending credentials. An empty refreshToken uses refresh_token from the configuration.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*token.TokenResult, error) {
+	if refreshToken == "" {
+		refreshToken = c.options.Config.RefreshToken
+	}
+	if refreshToken == "" {
+		return nil, fmt.Errorf("refresh token is required")
+	}
+	if err := validateClientConfig(&c.options.Config); err != nil {
+		return nil, err
+	}
+
+	return token.Refresh(ctx, c.options.Config, refreshToken, c.logger())
+}
@@ -0,0 +1,58 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("refresh_token") != "config-refresh-token" {
+			t.Errorf("Expected refresh token from config, got %s", r.PostForm.Get("refresh_token"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "refreshed-access-token",
+			"refresh_token": "rotated-refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3599,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(GeneratorOptions{
+		Config: token.TokenConfig{
+			Platform:         server.URL,
+			ClientID:         "test-client",
+			RefreshToken:     "config-refresh-token",
+			AllowInsecureURL: true,
+		},
+	})
+
+	result, err := client.Refresh(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.AccessToken != "refreshed-access-token" || result.RefreshToken != "rotated-refresh-token" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestRefreshValidation(t *testing.T) {
+	client := NewClient(GeneratorOptions{Config: token.TokenConfig{Platform: "https://test.forgerock.com"}})
+
+	if _, err := client.Refresh(context.Background(), ""); err == nil || !containsString(err.Error(), "refresh token is required") {
+		t.Errorf("Expected missing refresh token error, got %v", err)
+	}
+	if _, err := client.Refresh(context.Background(), "refresh-token"); err == nil || !containsString(err.Error(), "clientId is required") {
+		t.Errorf("Expected missing clientId error, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package token
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// Token type hints for revocation requests
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// Revoke revokes an access or refresh token, authenticating with the client
+// credentials from the configuration. tokenTypeHint may be empty.
+func (c *Client) Revoke(ctx context.Context, tok, tokenTypeHint string) error {
+	if tok == "" {
+		return fmt.Errorf("token is required")
+	}
+	if err := validateClientConfig(&c.options.Config); err != nil {
+		return err
+	}
+
+	return token.Revoke(ctx, c.options.Config, tok, tokenTypeHint, c.logger())
+}
@@ -0,0 +1,49 @@
+package token
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestRevoke(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/am/oauth2/token/revoke" {
+			t.Errorf("Expected revocation endpoint path, got %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("token_type_hint") != TokenTypeHintRefreshToken {
+			t.Errorf("Expected refresh token hint, got %s", r.PostForm.Get("token_type_hint"))
+		}
+		if r.PostForm.Get("token") == "unknown-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(GeneratorOptions{
+		Config: token.TokenConfig{
+			Platform:         server.URL,
+			ClientID:         "test-client",
+			ClientSecret:     "test-secret",
+			AllowInsecureURL: true,
+		},
+	})
+
+	if err := client.Revoke(context.Background(), "refresh-token", TokenTypeHintRefreshToken); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err := client.Revoke(context.Background(), "unknown-token", TokenTypeHintRefreshToken)
+	if err == nil || !containsString(err.Error(), "invalid_request") {
+		t.Errorf("Expected server error to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// Self-test step statuses
+const (
+	SelfTestPass = "pass"
+	SelfTestFail = "fail"
+	SelfTestSkip = "skip"
+)
+
+// Self-test step names, in the order they run
+const (
+	SelfTestStepConfig        = "config"
+	SelfTestStepKeyParse      = "key parse"
+	SelfTestStepAssertionSign = "assertion sign"
+	SelfTestStepTokenExchange = "token exchange"
+	SelfTestStepTokenActive   = "token active"
+)
+
+// SelfTestStep is the outcome of one self-test step
+type SelfTestStep struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"` // SelfTestPass, SelfTestFail or SelfTestSkip
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// SelfTestResult lists the outcome of each self-test step
+type SelfTestResult struct {
+	Passed bool           `json:"passed" yaml:"passed"`
+	Steps  []SelfTestStep `json:"steps" yaml:"steps"`
+}
+
+// selfTest records step outcomes, skipping the remaining steps after a failure
+type selfTest struct {
+	result SelfTestResult
+}
+
+// run runs the step unless an earlier step failed. The step returns the
+// detail to report, with skip set when it does not apply to the configuration.
+func (s *selfTest) run(name string, step func() (detail string, skip bool, err error)) {
+	if !s.result.Passed {
+		s.result.Steps = append(s.result.Steps, SelfTestStep{Name: name, Status: SelfTestSkip, Detail: "skipped after an earlier failure"})
+		return
+	}
+
+	detail, skip, err := step()
+	switch {
+	case err != nil:
+		s.result.Passed = false
+		s.result.Steps = append(s.result.Steps, SelfTestStep{Name: name, Status: SelfTestFail, Detail: err.Error()})
+	case skip:
+		s.result.Steps = append(s.result.Steps, SelfTestStep{Name: name, Status: SelfTestSkip, Detail: detail})
+	default:
+		s.result.Steps = append(s.result.Steps, SelfTestStep{Name: name, Status: SelfTestPass, Detail: detail})
+	}
+}
+
+// SelfTest checks the configuration end to end: it validates the
+// configuration, parses the signing key, signs an assertion, generates a new
+// token without using the cache and confirms the token is active, by token
+// introspection when a client secret is configured and otherwise by
+// userinfo. Steps after the first failure are skipped.
+func (c *Client) SelfTest(ctx context.Context) *SelfTestResult {
+	config := c.options.Config
+	config.AssertionOnly = false
+	usesKey := config.Type == token.TokenTypeServiceAccount || config.TokenEndpointAuthMethod == token.ClientAuthPrivateKeyJWT
+
+	s := &selfTest{result: SelfTestResult{Passed: true}}
+
+	s.run(SelfTestStepConfig, func() (string, bool, error) {
+		if err := Validate(&config); err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("%s token configuration is valid", config.Type), false, nil
+	})
+
+	s.run(SelfTestStepKeyParse, func() (string, bool, error) {
+		switch {
+		case !usesKey:
+			return fmt.Sprintf("%s tokens do not use a signing key", config.Type), true, nil
+		case config.JWKJson == "" && config.PrivateKey == "":
+			return "key is fetched from jwks_url when signing", true, nil
+		}
+		if err := token.CheckSigningKey(config); err != nil {
+			return "", false, err
+		}
+		return "signing key parsed", false, nil
+	})
+
+	s.run(SelfTestStepAssertionSign, func() (string, bool, error) {
+		if config.Type != token.TokenTypeServiceAccount {
+			return fmt.Sprintf("%s tokens do not use a service account assertion", config.Type), true, nil
+		}
+		assertionConfig := config
+		assertionConfig.AssertionOnly = true
+		generator := &token.ServiceAccountGenerator{Config: assertionConfig, Logger: c.logger()}
+		if _, err := generator.GenerateContext(ctx); err != nil {
+			return "", false, err
+		}
+		return "service account assertion signed", false, nil
+	})
+
+	var result *token.TokenResult
+	s.run(SelfTestStepTokenExchange, func() (string, bool, error) {
+		options := c.options
+		options.Config = config
+		options.Cache = nil
+		options.Decode = false
+		var err error
+		if result, err = NewClient(options).GenerateContext(ctx); err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("%s token issued, expires in %ds", result.TokenType, result.ExpiresIn), false, nil
+	})
+
+	s.run(SelfTestStepTokenActive, func() (string, bool, error) {
+		if config.ClientID != "" && config.ClientSecret != "" {
+			introspection, err := c.Introspect(ctx, result.AccessToken)
+			if err != nil {
+				return "", false, err
+			}
+			if !introspection.Active {
+				return "", false, fmt.Errorf("token introspection reports the token is not active")
+			}
+			return "token introspection reports the token is active", false, nil
+		}
+
+		if _, err := c.UserInfo(ctx, result.AccessToken); err != nil {
+			return "", false, err
+		}
+		return "userinfo accepted the token", false, nil
+	})
+
+	return &s.result
+}
+
+// ANSI colors for self-test statuses in text output
+var selfTestColors = map[string]string{
+	SelfTestPass: "\033[32m",
+	SelfTestFail: "\033[31m",
+	SelfTestSkip: "\033[33m",
+}
+
+// FormatSelfTest formats the self-test result according to the specified
+// format, coloring the step statuses in text output when color is set
+func (c *Client) FormatSelfTest(result *SelfTestResult, color bool) (string, error) {
+	if output, ok, err := c.formatStructured(result); ok {
+		return output, err
+	}
+
+	var output strings.Builder
+	for _, step := range result.Steps {
+		status := fmt.Sprintf("[%s]", strings.ToUpper(step.Status))
+		if color {
+			status = selfTestColors[step.Status] + status + "\033[0m"
+		}
+		output.WriteString(fmt.Sprintf("%s %s", status, step.Name))
+		if step.Detail != "" {
+			output.WriteString(": " + step.Detail)
+		}
+		output.WriteString("\n")
+	}
+
+	if result.Passed {
+		output.WriteString("Self-test passed\n")
+	} else {
+		output.WriteString("Self-test failed\n")
+	}
+	return output.String(), nil
+}
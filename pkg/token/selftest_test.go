@@ -0,0 +1,145 @@
+package token
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// newSelfTestServer serves the token endpoint and introspection, reporting tokens as active when active is set
+func newSelfTestServer(t *testing.T, active bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/access_token"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "selftest-token",
+				"token_type":   "Bearer",
+				"expires_in":   300,
+			})
+		case strings.HasSuffix(r.URL.Path, "/introspect"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"active": active})
+		case strings.HasSuffix(r.URL.Path, "/userinfo"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"sub": "test-service-account"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSelfTest(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	activeServer := newSelfTestServer(t, true)
+	inactiveServer := newSelfTestServer(t, false)
+
+	tests := []struct {
+		name     string
+		config   token.TokenConfig
+		passed   bool
+		statuses []string
+	}{
+		{
+			name: "service account with userinfo",
+			config: token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				Platform:         activeServer.URL,
+				ServiceAccountID: "test-service-account",
+				PrivateKey:       pemKey,
+				AllowInsecureURL: true,
+			},
+			passed:   true,
+			statuses: []string{SelfTestPass, SelfTestPass, SelfTestPass, SelfTestPass, SelfTestPass},
+		},
+		{
+			name:     "custom client with introspection",
+			config:   customClientConfig(activeServer),
+			passed:   true,
+			statuses: []string{SelfTestPass, SelfTestSkip, SelfTestSkip, SelfTestPass, SelfTestPass},
+		},
+		{
+			name:     "inactive token",
+			config:   customClientConfig(inactiveServer),
+			passed:   false,
+			statuses: []string{SelfTestPass, SelfTestSkip, SelfTestSkip, SelfTestPass, SelfTestFail},
+		},
+		{
+			name: "unparseable key skips later steps",
+			config: token.TokenConfig{
+				Type:             token.TokenTypeServiceAccount,
+				Platform:         activeServer.URL,
+				ServiceAccountID: "test-service-account",
+				JWKJson:          "{not json",
+				AllowInsecureURL: true,
+			},
+			passed:   false,
+			statuses: []string{SelfTestPass, SelfTestFail, SelfTestSkip, SelfTestSkip, SelfTestSkip},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewClient(GeneratorOptions{Config: tt.config}).SelfTest(context.Background())
+			if result.Passed != tt.passed {
+				t.Errorf("Expected passed %v, got %v (%+v)", tt.passed, result.Passed, result.Steps)
+			}
+			if len(result.Steps) != len(tt.statuses) {
+				t.Fatalf("Expected %d steps, got %+v", len(tt.statuses), result.Steps)
+			}
+			for i, status := range tt.statuses {
+				if result.Steps[i].Status != status {
+					t.Errorf("Expected step %q to be %s, got %s: %s", result.Steps[i].Name, status, result.Steps[i].Status, result.Steps[i].Detail)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatSelfTest(t *testing.T) {
+	result := &SelfTestResult{
+		Passed: false,
+		Steps: []SelfTestStep{
+			{Name: SelfTestStepConfig, Status: SelfTestPass, Detail: "custom token configuration is valid"},
+			{Name: SelfTestStepTokenExchange, Status: SelfTestFail, Detail: "token request failed"},
+		},
+	}
+	client := NewClient(GeneratorOptions{OutputFormat: OutputFormatText})
+
+	output, err := client.FormatSelfTest(result, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "[PASS] config: custom token configuration is valid\n[FAIL] token exchange: token request failed\nSelf-test failed\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	colored, err := client.FormatSelfTest(result, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(colored, "\033[32m[PASS]\033[0m") || !strings.Contains(colored, "\033[31m[FAIL]\033[0m") {
+		t.Errorf("Expected colored statuses, got %q", colored)
+	}
+}
@@ -0,0 +1,51 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// templateFuncs are the functions available to format templates in addition
+// to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	},
+}
+
+// ParseFormatTemplate parses a Go text/template for rendering a TokenResult,
+// such as "{{.AccessToken}} expires {{.ExpiresAt}}". Referencing a missing
+// map key, e.g. in Metadata, is an error when the template is executed.
+func ParseFormatTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// formatTemplate renders the result through the FormatTemplate, ending the
+// output with a newline as the other formats do
+func (c *Client) formatTemplate(result *token.TokenResult) (string, error) {
+	tmpl, err := ParseFormatTemplate(c.options.FormatTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	if err := tmpl.Execute(&output, result); err != nil {
+		return "", fmt.Errorf("failed to render format template: %w", err)
+	}
+	if !strings.HasSuffix(output.String(), "\n") {
+		output.WriteString("\n")
+	}
+	return output.String(), nil
+}
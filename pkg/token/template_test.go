@@ -0,0 +1,86 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestFormatOutputTemplate(t *testing.T) {
+	result := &token.TokenResult{
+		AccessToken: "template-token",
+		TokenType:   "Bearer",
+		ExpiresAt:   time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		Metadata:    map[string]interface{}{"client_id": "test-client"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "fields",
+			template: "{{.AccessToken}} expires {{.ExpiresAt.Format \"2006-01-02\"}}",
+			want:     "template-token expires 2030-01-02\n",
+		},
+		{
+			name:     "trailing newline kept",
+			template: "{{.TokenType}}\n",
+			want:     "Bearer\n",
+		},
+		{
+			name:     "json function",
+			template: "{{json .Metadata}}",
+			want:     "{\"client_id\":\"test-client\"}\n",
+		},
+		{
+			name:     "metadata key",
+			template: "{{.Metadata.client_id}}",
+			want:     "test-client\n",
+		},
+		{
+			name:     "missing metadata key",
+			template: "{{.Metadata.service_account_id}}",
+			wantErr:  "failed to render format template",
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Token}}",
+			wantErr:  "failed to render format template",
+		},
+		{
+			name:     "parse error",
+			template: "{{.AccessToken",
+			wantErr:  "invalid format template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The template overrides the output format and fields
+			client := NewClient(GeneratorOptions{
+				OutputFormat:   OutputFormatJSON,
+				Fields:         []string{"access_token"},
+				FormatTemplate: tt.template,
+			})
+
+			output, err := client.FormatOutput(result)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, output)
+			}
+		})
+	}
+}
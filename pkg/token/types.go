@@ -1,64 +1,115 @@
 package token
 
 import (
-	"time"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
 )
 
 // TokenType represents the type of token to generate
-type TokenType string
+type TokenType = token.TokenType
+
+const (
+	TokenTypeServiceAccount    = token.TokenTypeServiceAccount
+	TokenTypeUser              = token.TokenTypeUser
+	TokenTypeCustom            = token.TokenTypeCustom
+	TokenTypeAuthorizationCode = token.TokenTypeAuthorizationCode
+	TokenTypeTokenExchange     = token.TokenTypeTokenExchange
+	TokenTypeDeviceCode        = token.TokenTypeDeviceCode
+)
 
+// RFC 8693 token type identifiers for subject_token_type, actor_token_type and requested_token_type
 const (
-	TokenTypeServiceAccount TokenType = "service-account"
-	TokenTypeUser           TokenType = "user"
-	TokenTypeCustom         TokenType = "custom"
+	TokenTypeURIAccessToken  = token.TokenTypeURIAccessToken
+	TokenTypeURIRefreshToken = token.TokenTypeURIRefreshToken
+	TokenTypeURIIDToken      = token.TokenTypeURIIDToken
+	TokenTypeURIJWT          = token.TokenTypeURIJWT
 )
 
 // OutputFormat represents the output format for tokens
 type OutputFormat string
 
 const (
-	OutputFormatText OutputFormat = "text"
-	OutputFormatJSON OutputFormat = "json"
-	OutputFormatYAML OutputFormat = "yaml"
+	OutputFormatText   OutputFormat = "text"
+	OutputFormatJSON   OutputFormat = "json"
+	OutputFormatYAML   OutputFormat = "yaml"
+	OutputFormatRaw    OutputFormat = "raw"    // Access token only, for shell scripting
+	OutputFormatExport OutputFormat = "export" // Shell export statements, for eval
+	OutputFormatJSONL  OutputFormat = "jsonl"  // JSON Lines, one compact JSON object per line; batches print each result as it completes
 )
 
-// TokenConfig represents the configuration for token generation
-type TokenConfig struct {
-	// Token type
-	Type TokenType `yaml:"type" json:"type"`
-	
-	// PAIC connection details
-	BaseURL      string `yaml:"baseUrl" json:"baseUrl"`
-	Username     string `yaml:"username" json:"username"`
-	Password     string `yaml:"password" json:"password"`
-	ClientID     string `yaml:"clientId" json:"clientId"`
-	ClientSecret string `yaml:"clientSecret" json:"clientSecret"`
-	
-	// Service Account specific
-	ServiceAccountID   string `yaml:"serviceAccountId" json:"serviceAccountId"`
-	ServiceAccountName string `yaml:"serviceAccountName" json:"serviceAccountName"`
-	PrivateKey         string `yaml:"privateKey" json:"privateKey"`
-	KeyID              string `yaml:"keyId" json:"keyId"`
-	
-	// Token properties
-	Audience  string        `yaml:"audience" json:"audience"`
-	Issuer    string        `yaml:"issuer" json:"issuer"`
-	Subject   string        `yaml:"subject" json:"subject"`
-	ExpiresIn time.Duration `yaml:"expiresIn" json:"expiresIn"`
-	Scopes    []string      `yaml:"scopes" json:"scopes"`
-	
-	// Custom claims
-	CustomClaims map[string]interface{} `yaml:"customClaims" json:"customClaims"`
+// OutputFormats lists the supported output formats
+var OutputFormats = []OutputFormat{OutputFormatText, OutputFormatJSON, OutputFormatYAML, OutputFormatRaw, OutputFormatExport, OutputFormatJSONL}
+
+// ValidateOutputFormat checks that format is one of OutputFormats
+func ValidateOutputFormat(format OutputFormat) error {
+	if slices.Contains(OutputFormats, format) {
+		return nil
+	}
+
+	names := make([]string, len(OutputFormats))
+	for i, f := range OutputFormats {
+		names[i] = string(f)
+	}
+	return fmt.Errorf("invalid output format %q: must be one of %s", format, strings.Join(names, ", "))
 }
 
-// TokenResult represents the result of token generation
-type TokenResult struct {
-	AccessToken  string                 `json:"access_token" yaml:"access_token"`
-	TokenType    string                 `json:"token_type" yaml:"token_type"`
-	ExpiresIn    int64                  `json:"expires_in" yaml:"expires_in"`
-	ExpiresAt    time.Time              `json:"expires_at" yaml:"expires_at"`
-	Scope        string                 `json:"scope,omitempty" yaml:"scope,omitempty"`
-	RefreshToken string                 `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
-	Metadata     map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+// ParseOutputFormats parses a comma-separated list of output formats,
+// such as "json,raw", validating each one
+func ParseOutputFormats(value string) ([]OutputFormat, error) {
+	var formats []OutputFormat
+	for _, name := range strings.Split(value, ",") {
+		format := OutputFormat(strings.TrimSpace(name))
+		if err := ValidateOutputFormat(format); err != nil {
+			return nil, err
+		}
+		if slices.Contains(formats, format) {
+			return nil, fmt.Errorf("output format %q is listed more than once", format)
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// TimeFormat represents how timestamps are rendered in text output
+type TimeFormat string
+
+const (
+	TimeFormatHuman   TimeFormat = "human"   // 2006-01-02 15:04:05 MST
+	TimeFormatRFC3339 TimeFormat = "rfc3339" // 2006-01-02T15:04:05Z07:00
+	TimeFormatUnix    TimeFormat = "unix"    // Seconds since the Unix epoch
+)
+
+// TimeFormats lists the supported time formats
+var TimeFormats = []TimeFormat{TimeFormatHuman, TimeFormatRFC3339, TimeFormatUnix}
+
+// ValidateTimeFormat checks that format is one of TimeFormats
+func ValidateTimeFormat(format TimeFormat) error {
+	if slices.Contains(TimeFormats, format) {
+		return nil
+	}
+
+	names := make([]string, len(TimeFormats))
+	for i, f := range TimeFormats {
+		names[i] = string(f)
+	}
+	return fmt.Errorf("invalid time format %q: must be one of %s", format, strings.Join(names, ", "))
 }
 
+// AssertionTokenType is the token type of results holding a service account
+// JWT assertion, returned when assertion_only is set
+const AssertionTokenType = token.AssertionTokenType
+
+// DefaultExportPrefix is the environment variable prefix used by the export output format
+const DefaultExportPrefix = "PCTL"
+
+// TokenConfig represents the configuration for token generation
+type TokenConfig = token.TokenConfig
+
+// TokenResult represents the result of token generation
+type TokenResult = token.TokenResult
+
+// DecodedToken holds the unverified header and claims of a JWT access token
+type DecodedToken = token.DecodedToken
@@ -0,0 +1,50 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// UserInfo holds the claims returned by the PAIC userinfo endpoint
+type UserInfo = token.UserInfo
+
+// UserInfo returns the claims about the identity an access token represents.
+// An empty accessToken generates a new token from the configuration first.
+func (c *Client) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	if accessToken == "" {
+		result, err := c.GenerateContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("token generation failed: %w", err)
+		}
+		accessToken = result.AccessToken
+	} else if err := validatePlatformURL(&c.options.Config); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	return token.GetUserInfo(ctx, c.options.Config, accessToken, c.logger())
+}
+
+// FormatUserInfo formats the userinfo claims according to the specified format
+func (c *Client) FormatUserInfo(info UserInfo) (string, error) {
+	if output, ok, err := c.formatStructured(info); ok {
+		return output, err
+	}
+
+	names := make([]string, 0, len(info))
+	for name := range info {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var output strings.Builder
+	output.WriteString("User Info:\n")
+	output.WriteString("==========\n")
+	for _, name := range names {
+		output.WriteString(fmt.Sprintf("%s: %v\n", name, info[name]))
+	}
+	return output.String(), nil
+}
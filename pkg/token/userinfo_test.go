@@ -0,0 +1,105 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestUserInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/am/oauth2/access_token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "generated-token",
+				"token_type":   "Bearer",
+				"expires_in":   3599,
+			})
+		case "/am/oauth2/userinfo":
+			if r.Method != http.MethodGet {
+				t.Errorf("Expected GET request, got %s", r.Method)
+			}
+			switch r.Header.Get("Authorization") {
+			case "Bearer supplied-token":
+				json.NewEncoder(w).Encode(map[string]interface{}{"sub": "supplied-subject", "name": "Test User"})
+			case "Bearer generated-token":
+				json.NewEncoder(w).Encode(map[string]interface{}{"sub": "generated-subject"})
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_token"}`))
+			}
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(GeneratorOptions{
+		Config: token.TokenConfig{
+			Type:             token.TokenTypeCustom,
+			Platform:         server.URL,
+			ClientID:         "test-client",
+			ClientSecret:     "test-secret",
+			AllowInsecureURL: true,
+		},
+	})
+
+	tests := []struct {
+		name        string
+		accessToken string
+		wantSub     string
+		wantErr     string
+	}{
+		{name: "supplied token", accessToken: "supplied-token", wantSub: "supplied-subject"},
+		{name: "generated token", wantSub: "generated-subject"},
+		{name: "rejected token", accessToken: "bad-token", wantErr: "userinfo request failed with status 401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := client.UserInfo(context.Background(), tt.accessToken)
+			if tt.wantErr != "" {
+				if err == nil || !containsString(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if info["sub"] != tt.wantSub {
+				t.Errorf("Expected sub %q, got %v", tt.wantSub, info["sub"])
+			}
+		})
+	}
+}
+
+func TestFormatUserInfo(t *testing.T) {
+	info := UserInfo{"sub": "test-subject", "name": "Test User"}
+
+	tests := []struct {
+		format OutputFormat
+		want   []string
+	}{
+		{OutputFormatText, []string{"name: Test User\nsub: test-subject"}},
+		{OutputFormatJSON, []string{`"sub": "test-subject"`}},
+		{OutputFormatYAML, []string{"sub: test-subject"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			client := NewClient(GeneratorOptions{OutputFormat: tt.format})
+			output, err := client.FormatUserInfo(info)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !containsString(output, want) {
+					t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
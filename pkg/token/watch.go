@@ -0,0 +1,57 @@
+package token
+
+import (
+	"context"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+// DefaultRefreshBefore is how long before expiry Watch regenerates a token
+const DefaultRefreshBefore = time.Minute
+
+// minWatchInterval bounds how often Watch regenerates tokens and retries failures
+var minWatchInterval = 10 * time.Second
+
+// Watch generates a token, passes it to handle, and regenerates it refreshBefore
+// its expiry until ctx is cancelled, when it returns nil. Failures to generate the
+// first token are returned; later failures are logged and retried while the
+// previous token may still be valid.
+func (c *Client) Watch(ctx context.Context, refreshBefore time.Duration, handle func(*token.TokenResult) error) error {
+	if refreshBefore <= 0 {
+		refreshBefore = DefaultRefreshBefore
+	}
+
+	log := c.logger()
+	first := true
+	for {
+		wait := minWatchInterval
+		result, err := c.GenerateContext(ctx)
+		if err == nil {
+			err = handle(result)
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil && first:
+			return err
+		case err != nil:
+			log.Error("token refresh failed", "error", err, "retry_in", wait)
+		default:
+			first = false
+			if untilRefresh := time.Until(result.ExpiresAt.Add(-refreshBefore)); untilRefresh > wait {
+				wait = untilRefresh
+			}
+			log.Info("token refreshed", "expires_at", result.ExpiresAt, "next_refresh", time.Now().Add(wait).Round(time.Second))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
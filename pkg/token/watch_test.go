@@ -0,0 +1,49 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aaronwang/pctl/internal/token"
+)
+
+func TestWatchRegeneratesBeforeExpiry(t *testing.T) {
+	original := minWatchInterval
+	minWatchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { minWatchInterval = original })
+
+	var requests int32
+	server := newCountingTokenServer(t, 1, &requests)
+	client := NewClient(GeneratorOptions{Config: customClientConfig(server)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var tokens []string
+	err := client.Watch(ctx, time.Second, func(result *token.TokenResult) error {
+		tokens = append(tokens, result.AccessToken)
+		if len(tokens) == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tokens) != 3 || tokens[0] != "token-1" || tokens[2] != "token-3" {
+		t.Errorf("Expected three regenerated tokens, got %v", tokens)
+	}
+}
+
+func TestWatchFirstFailure(t *testing.T) {
+	client := NewClient(GeneratorOptions{Config: token.TokenConfig{Type: token.TokenTypeCustom}})
+
+	err := client.Watch(context.Background(), time.Minute, func(*token.TokenResult) error {
+		t.Error("Expected handle not to be called")
+		return nil
+	})
+	if err == nil || !containsString(err.Error(), "baseUrl or platform is required") {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+}